@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gopolicy/internal/policy"
+)
+
+// HandleTemplateDiagnostics reports the load failures and the loaded files
+// of the current bundle
+func (h *PolicyHandler) HandleTemplateDiagnostics(w http.ResponseWriter, r *http.Request) {
+	workspace := h.current().workspace
+
+	failures := workspace.LoadFailures()
+	response := TemplateDiagnosticsResponse{
+		FailureCount: len(failures),
+		Failures:     make([]LoadFailureInfo, 0, len(failures)),
+		Files:        []TemplateFileSummary{},
+	}
+	for _, fail := range failures {
+		response.Failures = append(response.Failures, LoadFailureInfo{
+			Type:    fail.FailType.String(),
+			File:    fail.AdmxPath,
+			Info:    fail.Info,
+			Message: fail.Error(),
+		})
+	}
+
+	for _, file := range workspace.TemplateInventory() {
+		response.Files = append(response.Files, TemplateFileSummary{
+			Namespace:     file.Namespace,
+			File:          file.File,
+			Root:          file.Root,
+			Legacy:        file.Legacy,
+			Revision:      file.Revision.String(),
+			Prefixes:      file.Prefixes,
+			AdmlFile:      file.AdmlFile,
+			Locale:        file.Locale,
+			Locales:       file.Locales,
+			PolicyCount:   file.PolicyCount,
+			CategoryCount: file.CategoryCount,
+		})
+	}
+
+	response.Stats = loadStatsInfo(workspace.LastLoadStats())
+	respondSuccess(w, response)
+}
+
+func loadStatsInfo(stats policy.AdmxLoadStats) LoadStatsInfo {
+	return LoadStatsInfo{
+		Roots:      stats.Folder,
+		Files:      stats.Files,
+		Failures:   stats.Failures,
+		CacheHit:   stats.CacheHit,
+		DurationMs: stats.TotalTime.Milliseconds(),
+	}
+}
+
+// HandleRegistryConflicts lists the registry values that more than one
+// policy writes, so that setting one policy changes the state of another.
+// crossFile=true keeps the conflicts between different template files.
+func (h *PolicyHandler) HandleRegistryConflicts(w http.ResponseWriter, r *http.Request) {
+	ws := h.localized(w, r)
+	crossFileOnly := r.URL.Query().Get("crossFile") == "true"
+
+	response := RegistryConflictsResponse{Conflicts: []RegistryConflictInfo{}}
+	for _, conflict := range ws.workspace.RegistryConflicts() {
+		if crossFileOnly && !conflict.CrossFile {
+			continue
+		}
+		info := RegistryConflictInfo{
+			Key:       conflict.Key,
+			ValueName: conflict.ValueName,
+			CrossFile: conflict.CrossFile,
+			Policies:  make([]RegistryLookupMatch, 0, len(conflict.Matches)),
+		}
+		for _, match := range conflict.Matches {
+			info.Policies = append(info.Policies, registryLookupMatch(match))
+		}
+		if conflict.CrossFile {
+			response.CrossFileCount++
+		}
+		response.Conflicts = append(response.Conflicts, info)
+	}
+	response.Count = len(response.Conflicts)
+	respondSuccess(w, response)
+}
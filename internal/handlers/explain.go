@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gopolicy/internal/policy"
+)
+
+// explainPolicy traces how the state of a policy is decided in each of
+// its sections, reading both Registry.pol and the live registry
+func (h *PolicyHandler) explainPolicy(w http.ResponseWriter, pol *policy.PolicyPlusPolicy) {
+	response := PolicyExplanation{
+		ID:       pol.UniqueID,
+		State:    policy.PolicyStateNotConfigured.String(),
+		Sections: []SectionExplanation{},
+	}
+
+	decided := false
+	for _, section := range h.sectionsToCheck(pol.RawPolicy.Section) {
+		source, err := h.getOrCreateSource(section)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Registry source creation failed")
+			return
+		}
+
+		x := policy.ExplainPolicyState(source, pol.RawPolicy)
+		info := SectionExplanation{
+			Section:   sectionName(section),
+			State:     x.State.String(),
+			DecidedBy: x.DecidedBy,
+			Rule:      x.Rule,
+			Disagree:  x.Disagree(),
+			Sources:   make([]StateTraceInfo, 0, len(x.Traces)),
+		}
+		for _, trace := range x.Traces {
+			info.Sources = append(info.Sources, stateTraceInfo(trace))
+		}
+		response.Sections = append(response.Sections, info)
+
+		if !decided && x.State != policy.PolicyStateNotConfigured {
+			response.State, response.Section = info.State, info.Section
+			decided = true
+		}
+	}
+	if !decided && len(response.Sections) > 0 {
+		response.Section = response.Sections[len(response.Sections)-1].Section
+	}
+
+	respondSuccess(w, response)
+}
+
+func stateTraceInfo(trace *policy.StateTrace) StateTraceInfo {
+	info := StateTraceInfo{
+		Source:     trace.Source,
+		Path:       trace.Path,
+		State:      trace.State.String(),
+		Rule:       trace.Rule,
+		Checks:     make([]StateCheckInfo, 0, len(trace.Checks)),
+		Mismatches: mismatchInfos(trace.Mismatches),
+	}
+	if trace.Err != nil {
+		info.Error = trace.Err.Error()
+	}
+	for _, check := range trace.Checks {
+		info.Checks = append(info.Checks, StateCheckInfo{
+			Key:       check.Key,
+			ValueName: check.ValueName,
+			Expected:  check.Expected,
+			Actual:    check.Actual,
+			Outcome:   check.Outcome,
+			Note:      check.Note,
+		})
+	}
+	return info
+}
+
+func mismatchInfos(mismatches []policy.StateMismatch) []StateMismatchInfo {
+	var result []StateMismatchInfo
+	for _, m := range mismatches {
+		result = append(result, StateMismatchInfo{
+			Key:       m.Key,
+			ValueName: m.ValueName,
+			Expected:  m.Expected,
+			Actual:    m.Actual,
+		})
+	}
+	return result
+}
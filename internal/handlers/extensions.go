@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// buildClientExtensions describes the client-side extensions of a policy
+func buildClientExtensions(pol *policy.PolicyPlusPolicy) []ClientExtensionInfo {
+	guids := pol.RawPolicy.ClientExtensions()
+	result := make([]ClientExtensionInfo, 0, len(guids))
+	for _, guid := range guids {
+		name, _ := policy.ClientExtensionName(guid)
+		result = append(result, ClientExtensionInfo{GUID: guid, Name: name})
+	}
+	return result
+}
+
+// extensionFilter builds a filter from the cse query parameter, a comma
+// separated list of extension GUIDs or well-known names. It returns nil when
+// the parameter is missing.
+func extensionFilter(r *http.Request) (policyFilter, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("cse"))
+	if value == "" {
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, ref := range strings.Split(value, ",") {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		guid, err := resolveExtension(ref)
+		if err != nil {
+			return nil, err
+		}
+		wanted[guid] = true
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	return func(pol *policy.PolicyPlusPolicy) bool {
+		for _, guid := range pol.RawPolicy.ClientExtensions() {
+			if wanted[guid] {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// resolveExtension turns a GUID or a well-known extension name into a GUID
+func resolveExtension(ref string) (string, error) {
+	guid := policy.NormalizeExtensionGUID(ref)
+	if strings.HasPrefix(guid, "{") {
+		return guid, nil
+	}
+	for _, known := range policy.KnownClientExtensions() {
+		if name, _ := policy.ClientExtensionName(known); strings.EqualFold(name, ref) {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("unknown client-side extension: %s", ref)
+}
+
+// HandleClientExtensions groups the loaded policies by the client-side
+// extensions that process them
+func (h *PolicyHandler) HandleClientExtensions(w http.ResponseWriter, r *http.Request) {
+	ws := h.localized(w, r)
+	filter, err := applicabilityFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groups := make(map[string]*ClientExtensionGroup)
+	for _, pol := range ws.workspace.Policies {
+		if !filter.match(pol) {
+			continue
+		}
+		for _, guid := range pol.RawPolicy.ClientExtensions() {
+			group, ok := groups[guid]
+			if !ok {
+				name, known := policy.ClientExtensionName(guid)
+				group = &ClientExtensionGroup{GUID: guid, Name: name, Known: known, Policies: []string{}}
+				groups[guid] = group
+			}
+			group.Policies = append(group.Policies, pol.UniqueID)
+		}
+	}
+
+	result := make([]*ClientExtensionGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Policies)
+		group.PolicyCount = len(group.Policies)
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].PolicyCount != result[j].PolicyCount {
+			return result[i].PolicyCount > result[j].PolicyCount
+		}
+		return result[i].GUID < result[j].GUID
+	})
+
+	respondSuccess(w, result)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopolicy/internal/policy"
+)
+
+// extraRegistryCategory prefixes the IDs of the virtual Extra Registry
+// Settings categories, one per section
+const extraRegistryCategory = "ExtraRegistrySettings"
+
+// extraRegistryScanAge is how long a count that includes the registry below
+// Software\Policies is reused, since registry changes do not touch
+// Registry.pol
+const extraRegistryScanAge = 30 * time.Second
+
+// extraRegistryCount the number of extra registry values of a section, as
+// counted for a workspace and a version of Registry.pol
+type extraRegistryCount struct {
+	workspace *policy.AdmxBundle
+	modified  time.Time
+	size      int64
+	counted   time.Time
+	count     int
+}
+
+// SetPolicyKeyScan makes Extra Registry Settings include the values below
+// Software\Policies in the registry, not only those of Registry.pol.
+func (h *PolicyHandler) SetPolicyKeyScan(enabled bool) {
+	h.policyKeyScan = enabled
+}
+
+// HandleExtraRegistry lists (GET), writes (POST) and deletes (DELETE) the
+// registry values that no loaded template describes
+func (h *PolicyHandler) HandleExtraRegistry(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listExtraRegistry(w, r)
+	case http.MethodPost:
+		h.setExtraRegistry(w, r)
+	case http.MethodDelete:
+		h.deleteExtraRegistry(w, r)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *PolicyHandler) listExtraRegistry(w http.ResponseWriter, r *http.Request) {
+	sections := []policy.AdmxPolicySection{policy.Machine, policy.User}
+	if requested := r.URL.Query().Get("section"); requested != "" {
+		section, err := resolveSection(requested, policy.Both)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sections = []policy.AdmxPolicySection{section}
+	}
+
+	workspace := h.current().workspace
+	response := ExtraRegistryResponse{Values: []ExtraRegistryInfo{}}
+	for _, section := range sections {
+		values, err := workspace.ReadExtraRegistry(section, h.policyKeyScan)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, fmt.Sprintf("Extra registry settings could not be read: %v", err))
+			return
+		}
+		for _, value := range values {
+			response.Values = append(response.Values, extraRegistryInfo(value))
+		}
+	}
+	response.Count = len(response.Values)
+	respondSuccess(w, response)
+}
+
+func (h *PolicyHandler) setExtraRegistry(w http.ResponseWriter, r *http.Request) {
+	var req setRegistryValueRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	section, ok := h.extraRegistryTarget(w, req.Section, req.Key, req.ValueName)
+	if !ok {
+		return
+	}
+
+	kind, data, err := policy.ParseRegistryData(req.Type, req.Data)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, err := h.getOrCreateSource(section)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Registry source creation failed")
+		return
+	}
+	if err := policy.SetRegistryValue(source, req.Key, req.ValueName, kind, data); err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Registry value could not be written: %v", err))
+		return
+	}
+
+	respondSuccess(w, map[string]interface{}{
+		"success": true,
+		"message": "Registry value written",
+	})
+}
+
+func (h *PolicyHandler) deleteExtraRegistry(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	section, ok := h.extraRegistryTarget(w, query.Get("section"), query.Get("key"), query.Get("valueName"))
+	if !ok {
+		return
+	}
+
+	source, err := h.getOrCreateSource(section)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Registry source creation failed")
+		return
+	}
+	if err := policy.DeleteRegistryValue(source, query.Get("key"), query.Get("valueName")); err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Registry value could not be deleted: %v", err))
+		return
+	}
+
+	respondSuccess(w, map[string]interface{}{
+		"success": true,
+		"message": "Registry value deleted",
+	})
+}
+
+// extraRegistryTarget checks the section and path of a raw value. Only
+// values in the policy trees, or values that Extra Registry Settings lists,
+// can be changed; values that a loaded policy writes are refused, since
+// they belong to the policy.
+func (h *PolicyHandler) extraRegistryTarget(w http.ResponseWriter, requested, key, valueName string) (policy.AdmxPolicySection, bool) {
+	section, err := resolveSection(requested, policy.Both)
+	if err != nil || requested == "" {
+		respondError(w, http.StatusBadRequest, "invalid section: machine or user")
+		return section, false
+	}
+	if strings.Trim(key, "\\") == "" {
+		respondError(w, http.StatusBadRequest, "Registry key required")
+		return section, false
+	}
+	if !policy.IsPolicyKey(key) {
+		listed, err := h.isExtraRegistryValue(section, key, valueName)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, fmt.Sprintf("Extra registry settings could not be read: %v", err))
+			return section, false
+		}
+		if !listed {
+			respondError(w, http.StatusForbidden, fmt.Sprintf("%s is outside the policy trees and not an extra registry setting", key))
+			return section, false
+		}
+	}
+
+	if !strings.HasPrefix(valueName, "**") {
+		for _, match := range h.current().workspace.LookupRegistry(section, key+"\\"+valueName) {
+			if !match.KeyOnly {
+				respondError(w, http.StatusConflict, fmt.Sprintf("%s\\%s is written by the policy %s; set the policy instead", key, valueName, match.Policy.UniqueID))
+				return section, false
+			}
+		}
+	}
+	return section, true
+}
+
+// isExtraRegistryValue reports whether Extra Registry Settings lists a
+// value of a section
+func (h *PolicyHandler) isExtraRegistryValue(section policy.AdmxPolicySection, key, valueName string) (bool, error) {
+	values, err := h.current().workspace.ReadExtraRegistry(section, h.policyKeyScan)
+	if err != nil {
+		return false, err
+	}
+	key = strings.Trim(key, "\\")
+	for _, value := range values {
+		if strings.EqualFold(strings.Trim(value.Key, "\\"), key) && strings.EqualFold(value.ValueName, valueName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// extraRegistryCategories returns the virtual category of each section
+// that has extra registry values, or whose values cannot be read; the node
+// then carries the error.
+func (h *PolicyHandler) extraRegistryCategories() (user, computer *CategoryNode) {
+	node := func(section policy.AdmxPolicySection) *CategoryNode {
+		count, err := h.countExtraRegistry(section)
+		if err == nil && count == 0 {
+			return nil
+		}
+		node := &CategoryNode{
+			ID:          extraRegistryCategory + ":" + sectionName(section),
+			Name:        "Extra Registry Settings",
+			Description: "Registry values that no loaded template describes",
+			Children:    []*CategoryNode{},
+			PolicyCount: count,
+			Virtual:     true,
+			Section:     sectionName(section),
+		}
+		if err != nil {
+			node.Error = fmt.Sprintf("Extra registry settings could not be read: %v", err)
+		}
+		return node
+	}
+	return node(policy.User), node(policy.Machine)
+}
+
+// countExtraRegistry returns the number of extra registry values of a
+// section. The count is kept until the workspace is reloaded or Registry.pol
+// changes, and with the policy key scan for extraRegistryScanAge at most.
+func (h *PolicyHandler) countExtraRegistry(section policy.AdmxPolicySection) (int, error) {
+	workspace := h.current().workspace
+	polPath, err := policy.GetPolPath(section)
+	if err != nil {
+		return 0, err
+	}
+	current := extraRegistryCount{workspace: workspace, counted: time.Now()}
+	info, err := os.Stat(polPath)
+	switch {
+	case err == nil:
+		current.modified, current.size = info.ModTime(), info.Size()
+	case !os.IsNotExist(err):
+		return 0, err
+	}
+
+	h.extraMu.Lock()
+	cached, ok := h.extraCounts[section]
+	h.extraMu.Unlock()
+	if ok && cached.workspace == workspace && cached.modified.Equal(current.modified) && cached.size == current.size &&
+		(!h.policyKeyScan || current.counted.Sub(cached.counted) < extraRegistryScanAge) {
+		return cached.count, nil
+	}
+
+	values, err := workspace.ReadExtraRegistry(section, h.policyKeyScan)
+	if err != nil {
+		return 0, err
+	}
+	current.count = len(values)
+
+	h.extraMu.Lock()
+	if h.extraCounts == nil {
+		h.extraCounts = make(map[policy.AdmxPolicySection]extraRegistryCount)
+	}
+	h.extraCounts[section] = current
+	h.extraMu.Unlock()
+	return current.count, nil
+}
+
+func extraRegistryInfo(value *policy.ExtraRegistryValue) ExtraRegistryInfo {
+	info := ExtraRegistryInfo{
+		Section:   sectionName(value.Section),
+		Source:    value.Source,
+		Key:       value.Key,
+		ValueName: value.ValueName,
+		Type:      value.Type,
+		Data:      value.Data,
+		Deleted:   value.Deleted,
+	}
+	switch value.Type {
+	case policy.RegTypeString, policy.RegTypeExpandSz, policy.RegTypeDWord, policy.RegTypeMultiSz:
+		info.Editable = !value.Deleted && !strings.HasPrefix(value.ValueName, "**")
+	}
+	if s := value.Suggestion; s != nil {
+		info.Suggestion = &NamespaceSuggestionInfo{
+			Namespace: s.Namespace,
+			Template:  s.Template,
+			Loaded:    s.Loaded,
+			Reason:    s.Reason,
+		}
+	}
+	return info
+}
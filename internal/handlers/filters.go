@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// policyFilter selects the policies a request is interested in. A nil
+// filter selects all policies.
+type policyFilter func(*policy.PolicyPlusPolicy) bool
+
+func (f policyFilter) match(pol *policy.PolicyPlusPolicy) bool {
+	return f == nil || f(pol)
+}
+
+// requestFilter builds the policy filter of a request from its applicableTo
+// and cse query parameters. It returns nil when neither is given.
+func requestFilter(workspace *policy.AdmxBundle, r *http.Request) (policyFilter, error) {
+	applicability, err := applicabilityFilter(workspace, r)
+	if err != nil {
+		return nil, err
+	}
+	extension, err := extensionFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case applicability == nil:
+		return extension, nil
+	case extension == nil:
+		return applicability, nil
+	}
+	return func(pol *policy.PolicyPlusPolicy) bool {
+		return applicability(pol) && extension(pol)
+	}, nil
+}
+
+// stateFilter selects policies by their current state. A nil filter
+// selects all states.
+type stateFilter map[policy.PolicyState]bool
+
+func (f stateFilter) match(state policy.PolicyState) bool {
+	return f == nil || f[state]
+}
+
+// requestStateFilter builds the state filter of a request from its
+// comma-separated state query parameter, such as state=unknown. It returns
+// nil when none is given.
+func requestStateFilter(r *http.Request) (stateFilter, error) {
+	filter := make(stateFilter)
+	for _, ref := range strings.Split(r.URL.Query().Get("state"), ",") {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		if strings.EqualFold(ref, "unknown") {
+			filter[policy.PolicyStateUnknown] = true
+			continue
+		}
+		state, err := resolvePolicyState(ref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state filter: enabled, disabled, notconfigured or unknown")
+		}
+		filter[state] = true
+	}
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// HandleFootprint exports the registry footprint of every policy, sorted by
+// ID. It accepts the policy filters and format=csv for one row per entry.
+func (h *PolicyHandler) HandleFootprint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ws := h.localized(w, r)
+	filter, err := requestFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items := make([]PolicyFootprintInfo, 0, len(ws.workspace.Policies))
+	for _, pol := range ws.workspace.Policies {
+		if !filter.match(pol) {
+			continue
+		}
+		items = append(items, PolicyFootprintInfo{
+			ID:        pol.UniqueID,
+			Name:      pol.DisplayName,
+			Section:   sectionName(pol.RawPolicy.Section),
+			Namespace: pol.RawPolicy.DefinedIn.AdmxNamespace,
+			Footprint: buildFootprint(policy.PolicyFootprint(pol.RawPolicy)),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	if strings.EqualFold(r.URL.Query().Get("format"), "csv") {
+		writeFootprintCSV(w, items)
+		return
+	}
+	respondSuccess(w, items)
+}
+
+func writeFootprintCSV(w http.ResponseWriter, items []PolicyFootprintInfo) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="policy-footprint.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"policy", "section", "state", "key", "valueName", "action", "type", "data", "source", "condition"})
+	for _, item := range items {
+		for _, state := range []struct {
+			name    string
+			entries []FootprintEntryInfo
+		}{{"Enabled", item.Footprint.Enabled}, {"Disabled", item.Footprint.Disabled}} {
+			for _, e := range state.entries {
+				out.Write([]string{item.ID, item.Section, state.name, e.Key, e.ValueName, e.Action, e.Type, e.Data, e.Source, e.Condition})
+			}
+		}
+	}
+	out.Flush()
+}
+
+func buildFootprint(footprint *policy.RegistryFootprint) FootprintInfo {
+	return FootprintInfo{
+		Keys:          footprint.Keys(),
+		UnmanagedKeys: append([]string{}, footprint.UnmanagedKeys()...),
+		Enabled:       footprintEntries(footprint.Enabled),
+		Disabled:      footprintEntries(footprint.Disabled),
+	}
+}
+
+func footprintEntries(entries []policy.FootprintEntry) []FootprintEntryInfo {
+	result := make([]FootprintEntryInfo, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, FootprintEntryInfo{
+			Key:       e.Key,
+			ValueName: e.ValueName,
+			Action:    e.Action,
+			Type:      e.Type,
+			Data:      e.Data,
+			Source:    e.Source,
+			ElementID: e.ElementID,
+			Condition: e.Condition,
+		})
+	}
+	return result
+}
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopolicy/internal/policy"
@@ -14,30 +16,58 @@ import (
 type SourceFactory func(policy.AdmxPolicySection) (policy.PolicySource, error)
 
 type PolicyHandler struct {
-	workspace     *policy.AdmxBundle
+	state         atomic.Pointer[workspaceState]
 	renderer      pageRenderer
+	sourcesMu     sync.Mutex
 	sources       map[policy.AdmxPolicySection]policy.PolicySource
 	sourceFactory SourceFactory
+	loader        BundleLoader
+	reloadMu      sync.Mutex
+	packs         *policy.PackStore
+	policyKeyScan bool
+	tattoos       *policy.TattooStore
+	extraMu       sync.Mutex
+	extraCounts   map[policy.AdmxPolicySection]extraRegistryCount
+}
+
+// workspaceState is a loaded bundle together with the detail builder bound
+// to it. Both are replaced at once on reload, so a request that loaded the
+// state once keeps a consistent view.
+type workspaceState struct {
+	workspace     *policy.AdmxBundle
 	detailBuilder *PolicyDetailBuilder
 }
 
+func newWorkspaceState(workspace *policy.AdmxBundle) *workspaceState {
+	return &workspaceState{
+		workspace:     workspace,
+		detailBuilder: NewPolicyDetailBuilder(workspace),
+	}
+}
+
 func NewPolicyHandler(workspace *policy.AdmxBundle) (*PolicyHandler, error) {
 	machineSource, err := policy.NewRegistrySource(policy.Machine)
 	if err != nil {
 		return nil, fmt.Errorf("kayıt kaynağı oluşturulamadı: %w", err)
 	}
 
-	return &PolicyHandler{
-		workspace: workspace,
-		renderer:  newDefaultRenderer(),
+	h := &PolicyHandler{
+		renderer: newDefaultRenderer(),
 		sources: map[policy.AdmxPolicySection]policy.PolicySource{
 			policy.Machine: machineSource,
 		},
 		sourceFactory: func(section policy.AdmxPolicySection) (policy.PolicySource, error) {
 			return policy.NewRegistrySource(section)
 		},
-		detailBuilder: NewPolicyDetailBuilder(workspace),
-	}, nil
+	}
+	h.state.Store(newWorkspaceState(workspace))
+	return h, nil
+}
+
+// current returns the workspace state in use. Handlers load it once per
+// request.
+func (h *PolicyHandler) current() *workspaceState {
+	return h.state.Load()
 }
 
 func (h *PolicyHandler) HandleIndex(w http.ResponseWriter, r *http.Request) {
@@ -47,23 +77,39 @@ func (h *PolicyHandler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PolicyHandler) HandleCategories(w http.ResponseWriter, r *http.Request) {
+	ws := h.localized(w, r)
+	filter, err := requestFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var userRoots []*CategoryNode
 	var computerRoots []*CategoryNode
 
-	for _, cat := range h.workspace.Categories {
+	for _, cat := range ws.workspace.Categories {
 		// Check if category has user policies
-		if hasPoliciesInSection(cat, policy.User) {
-			userRoots = append(userRoots, buildCategoryTreeForSection(cat, policy.User))
+		if hasPoliciesInSection(cat, policy.User, filter) {
+			userRoots = append(userRoots, buildCategoryTreeForSection(cat, policy.User, filter))
 		}
 		// Check if category has computer policies
-		if hasPoliciesInSection(cat, policy.Machine) {
-			computerRoots = append(computerRoots, buildCategoryTreeForSection(cat, policy.Machine))
+		if hasPoliciesInSection(cat, policy.Machine, filter) {
+			computerRoots = append(computerRoots, buildCategoryTreeForSection(cat, policy.Machine, filter))
 		}
 	}
 
 	sortCategoryNodes(userRoots)
 	sortCategoryNodes(computerRoots)
 
+	// Extra Registry Settings come last, as in GPMC
+	userExtra, computerExtra := h.extraRegistryCategories()
+	if userExtra != nil {
+		userRoots = append(userRoots, userExtra)
+	}
+	if computerExtra != nil {
+		computerRoots = append(computerRoots, computerExtra)
+	}
+
 	respondSuccess(w, CategoriesResponse{
 		User:     userRoots,
 		Computer: computerRoots,
@@ -77,19 +123,37 @@ func (h *PolicyHandler) HandlePolicies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cat, ok := h.workspace.FlatCategories[categoryID]
+	ws := h.localized(w, r)
+	cat, ok := ws.workspace.FlatCategories[categoryID]
 	if !ok {
 		respondError(w, http.StatusNotFound, "Category not found")
 		return
 	}
 
+	filter, err := requestFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	states, err := requestStateFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	items := make([]PolicyListItem, 0, len(cat.Policies))
 	for _, pol := range cat.Policies {
+		if !filter.match(pol) {
+			continue
+		}
 		state, _, err := h.readPolicyState(pol)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "Policy state okunamadı")
 			return
 		}
+		if !states.match(state) {
+			continue
+		}
 
 		items = append(items, PolicyListItem{
 			ID:          pol.UniqueID,
@@ -97,6 +161,7 @@ func (h *PolicyHandler) HandlePolicies(w http.ResponseWriter, r *http.Request) {
 			Description: pol.DisplayExplanation,
 			State:       state.String(),
 			Section:     sectionName(pol.RawPolicy.Section),
+			Tattooing:   len(ws.workspace.UnmanagedKeys(pol)) > 0,
 		})
 	}
 
@@ -104,20 +169,40 @@ func (h *PolicyHandler) HandlePolicies(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PolicyHandler) HandlePolicy(w http.ResponseWriter, r *http.Request) {
+	ws := h.localized(w, r)
 	policyID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/policy/"), "/")
-	pol, ok := h.workspace.Policies[policyID]
+	policyID, explain := strings.CutSuffix(policyID, "/explain")
+	pol, ok := ws.workspace.Policies[policyID]
 	if !ok {
 		respondError(w, http.StatusNotFound, "Policy not found")
 		return
 	}
+	if explain {
+		h.explainPolicy(w, pol)
+		return
+	}
 
-	state, options, err := h.readPolicyState(pol)
+	eval, err := h.evaluatePolicy(pol)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Policy state okunamadı")
 		return
 	}
 
-	detail := h.detailBuilder.Build(pol, state, options)
+	filter, err := requestFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	detail := ws.detailBuilder.Build(pol, eval.State, eval.Options)
+	detail.Mismatches = mismatchInfos(eval.Mismatches)
+	if detail.Tattooing {
+		detail.TattooBackups = h.tattooBackups(pol)
+	}
+	if filter != nil {
+		applicable := filter.match(pol)
+		detail.Applicable = &applicable
+	}
 	respondSuccess(w, detail)
 }
 
@@ -133,7 +218,8 @@ func (h *PolicyHandler) HandleSetPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	pol, ok := h.workspace.Policies[req.PolicyID]
+	workspace := h.current().workspace
+	pol, ok := workspace.Policies[req.PolicyID]
 	if !ok {
 		respondError(w, http.StatusNotFound, "Policy not found")
 		return
@@ -151,17 +237,46 @@ func (h *PolicyHandler) HandleSetPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	options := req.Options
+	defaultsApplied := []AppliedDefaultInfo{}
+	if state == policy.PolicyStateEnabled {
+		if options, defaultsApplied, err = h.prepareOptions(w, r, pol, req.Options); err != nil {
+			respondOptionErrors(w, err)
+			return
+		}
+	}
+
 	source, err := h.sourceFactory(section)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Registry source creation failed")
 		return
 	}
 
-	if err := policy.SetPolicyState(source, pol.RawPolicy, state, req.Options); err != nil {
-		respondError(w, http.StatusInternalServerError, "Policy update failed")
+	// policies sharing registry values with this one may change with it
+	others := snapshotPolicies(source, workspace.ConflictingPolicies(pol), section)
+
+	// values outside the policy trees stay when the policy is removed, so
+	// the ones it overwrites are kept to be put back
+	tattoo, err := h.backupTattoo(source, workspace, pol, section, state)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Tattoo backup failed: %v", err))
+		return
+	}
+
+	if err := policy.SetPolicyState(source, pol.RawPolicy, state, options); err != nil {
+		if !respondOptionErrors(w, err) {
+			respondError(w, http.StatusInternalServerError, "Policy update failed")
+		}
 		return
 	}
 
+	if state == policy.PolicyStateNotConfigured {
+		if err := h.restoreTattoo(source, pol, section, tattoo); err != nil {
+			respondError(w, http.StatusInternalServerError, fmt.Sprintf("Policy is not configured, but the values it overwrote could not be restored: %v", err))
+			return
+		}
+	}
+
 	verifyState, _, err := policy.GetPolicyState(source, pol.RawPolicy)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Policy verify failed")
@@ -169,12 +284,59 @@ func (h *PolicyHandler) HandleSetPolicy(w http.ResponseWriter, r *http.Request)
 	}
 
 	respondSuccess(w, map[string]interface{}{
-		"success":       true,
-		"message":       "Policy updated successfully",
-		"verifiedState": verifyState.String(),
+		"success":          true,
+		"message":          "Policy updated successfully",
+		"verifiedState":    verifyState.String(),
+		"defaultsApplied":  defaultsApplied,
+		"affectedPolicies": others.changed(source),
+		"tattoo":           tattoo,
 	})
 }
 
+// policySnapshot the state of a policy before another one is set
+type policySnapshot struct {
+	pol   *policy.PolicyPlusPolicy
+	state policy.PolicyState
+}
+
+type policySnapshots []policySnapshot
+
+// snapshotPolicies reads the state of the policies of section in source.
+// Policies that cannot be read are left out.
+func snapshotPolicies(source policy.PolicySource, policies []*policy.PolicyPlusPolicy, section policy.AdmxPolicySection) policySnapshots {
+	var snapshots policySnapshots
+	for _, pol := range policies {
+		if pol.RawPolicy.Section != policy.Both && pol.RawPolicy.Section != section {
+			continue
+		}
+		state, _, err := policy.GetPolicyState(source, pol.RawPolicy)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, policySnapshot{pol: pol, state: state})
+	}
+	return snapshots
+}
+
+// changed returns the policies whose state in source differs from the
+// snapshot
+func (s policySnapshots) changed(source policy.PolicySource) []AffectedPolicyInfo {
+	affected := []AffectedPolicyInfo{}
+	for _, snap := range s {
+		state, _, err := policy.GetPolicyState(source, snap.pol.RawPolicy)
+		if err != nil || state == snap.state {
+			continue
+		}
+		affected = append(affected, AffectedPolicyInfo{
+			PolicyID:   snap.pol.UniqueID,
+			PolicyName: snap.pol.DisplayName,
+			Before:     snap.state.String(),
+			After:      state.String(),
+		})
+	}
+	return affected
+}
+
 func (h *PolicyHandler) HandleSources(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, []map[string]interface{}{
 		{
@@ -190,6 +352,52 @@ func (h *PolicyHandler) HandleSources(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleNamespaces lists the template file chosen for each namespace
+func (h *PolicyHandler) HandleNamespaces(w http.ResponseWriter, r *http.Request) {
+	workspace := h.current().workspace
+	sources := workspace.NamespaceSources
+
+	outdated := make(map[string][]OutdatedAdmlInfo)
+	for _, issue := range workspace.AdmlRevisionIssues() {
+		outdated[issue.Namespace] = append(outdated[issue.Namespace], OutdatedAdmlInfo{
+			File:     issue.AdmlPath,
+			Locale:   issue.Locale,
+			Revision: issue.Revision.String(),
+			Required: issue.Required.String(),
+		})
+	}
+
+	items := make([]NamespaceInfo, 0, len(sources))
+	for _, src := range sources {
+		overridden := src.Overridden
+		if overridden == nil {
+			overridden = []string{}
+		}
+		outdatedAdmls := outdated[src.Namespace]
+		if outdatedAdmls == nil {
+			outdatedAdmls = []OutdatedAdmlInfo{}
+		}
+		info := NamespaceInfo{
+			Namespace:     src.Namespace,
+			File:          src.File,
+			Root:          src.Root,
+			Revision:      src.Revision.String(),
+			SchemaVersion: src.SchemaVersion,
+			OutdatedAdmls: outdatedAdmls,
+			Overridden:    overridden,
+		}
+		if !src.MinAdmlRevision.IsZero() {
+			info.MinAdmlRevision = src.MinAdmlRevision.String()
+		}
+		items = append(items, info)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Namespace < items[j].Namespace
+	})
+
+	respondSuccess(w, items)
+}
+
 func (h *PolicyHandler) HandleSave(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -235,7 +443,12 @@ func (h *PolicyHandler) HandleRefreshExplorer(w http.ResponseWriter, r *http.Req
 // HandleSearch searches policies by name or description
 func (h *PolicyHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
-	if query == "" {
+	states, err := requestStateFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query == "" && states == nil {
 		respondError(w, http.StatusBadRequest, "Search query required")
 		return
 	}
@@ -246,6 +459,13 @@ func (h *PolicyHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		sectionFilter = "both"
 	}
 
+	ws := h.localized(w, r)
+	filter, err := requestFilter(ws.workspace, r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Normalize query for case-insensitive search
 	queryLower := strings.ToLower(query)
 
@@ -253,18 +473,23 @@ func (h *PolicyHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	var computerResults []SearchResultItem
 
 	// Search through all policies
-	for _, pol := range h.workspace.Policies {
-		// Check if query matches name or description (case-insensitive)
+	for _, pol := range ws.workspace.Policies {
+		if !filter.match(pol) {
+			continue
+		}
+
+		// Check if query matches name, description or keywords (case-insensitive)
 		nameLower := strings.ToLower(pol.DisplayName)
 		descLower := strings.ToLower(pol.DisplayExplanation)
 
-		if !strings.Contains(nameLower, queryLower) && !strings.Contains(descLower, queryLower) {
+		if !strings.Contains(nameLower, queryLower) && !strings.Contains(descLower, queryLower) &&
+			!keywordsContain(pol.Keywords, queryLower) {
 			continue
 		}
 
 		// Get policy state
 		state, _, err := h.readPolicyState(pol)
-		if err != nil {
+		if err != nil || !states.match(state) {
 			// Skip policies with errors
 			continue
 		}
@@ -286,6 +511,8 @@ func (h *PolicyHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 			Section:      sectionName(pol.RawPolicy.Section),
 			CategoryID:   categoryID,
 			CategoryName: categoryName,
+			Keywords:     pol.Keywords,
+			Tattooing:    len(ws.workspace.UnmanagedKeys(pol)) > 0,
 		}
 
 		// Add to appropriate section based on filter
@@ -329,16 +556,16 @@ func (h *PolicyHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 }
 
 // hasPoliciesInSection checks if a category (or any of its children) has policies in the given section
-func hasPoliciesInSection(cat *policy.PolicyPlusCategory, section policy.AdmxPolicySection) bool {
+func hasPoliciesInSection(cat *policy.PolicyPlusCategory, section policy.AdmxPolicySection, filter policyFilter) bool {
 	// Check direct policies
 	for _, pol := range cat.Policies {
-		if pol.RawPolicy.Section == section || pol.RawPolicy.Section == policy.Both {
+		if (pol.RawPolicy.Section == section || pol.RawPolicy.Section == policy.Both) && filter.match(pol) {
 			return true
 		}
 	}
 	// Check children
 	for _, child := range cat.Children {
-		if hasPoliciesInSection(child, section) {
+		if hasPoliciesInSection(child, section, filter) {
 			return true
 		}
 	}
@@ -346,11 +573,12 @@ func hasPoliciesInSection(cat *policy.PolicyPlusCategory, section policy.AdmxPol
 }
 
 // buildCategoryTreeForSection builds a category tree filtered by section
-func buildCategoryTreeForSection(cat *policy.PolicyPlusCategory, section policy.AdmxPolicySection) *CategoryNode {
+// and by the policy filter
+func buildCategoryTreeForSection(cat *policy.PolicyPlusCategory, section policy.AdmxPolicySection, filter policyFilter) *CategoryNode {
 	// Count policies in this section
 	policyCount := 0
 	for _, pol := range cat.Policies {
-		if pol.RawPolicy.Section == section || pol.RawPolicy.Section == policy.Both {
+		if (pol.RawPolicy.Section == section || pol.RawPolicy.Section == policy.Both) && filter.match(pol) {
 			policyCount++
 		}
 	}
@@ -362,11 +590,12 @@ func buildCategoryTreeForSection(cat *policy.PolicyPlusCategory, section policy.
 		Children:    []*CategoryNode{},
 		PolicyCount: policyCount,
 	}
+	node.DescriptionHTML, node.DescriptionMarkdown = formatDescription(cat.DisplayExplanation)
 
 	// Add children that have policies in this section
 	for _, child := range cat.Children {
-		if hasPoliciesInSection(child, section) {
-			node.Children = append(node.Children, buildCategoryTreeForSection(child, section))
+		if hasPoliciesInSection(child, section, filter) {
+			node.Children = append(node.Children, buildCategoryTreeForSection(child, section, filter))
 		}
 	}
 	sortCategoryNodes(node.Children)
@@ -382,6 +611,7 @@ func buildCategoryTree(cat *policy.PolicyPlusCategory) *CategoryNode {
 		Children:    []*CategoryNode{},
 		PolicyCount: len(cat.Policies),
 	}
+	node.DescriptionHTML, node.DescriptionMarkdown = formatDescription(cat.DisplayExplanation)
 	for _, child := range cat.Children {
 		node.Children = append(node.Children, buildCategoryTree(child))
 	}
@@ -389,6 +619,26 @@ func buildCategoryTree(cat *policy.PolicyPlusCategory) *CategoryNode {
 	return node
 }
 
+// keywordsContain reports whether one of the keywords contains the
+// lower-case query
+func keywordsContain(keywords []string, queryLower string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(strings.ToLower(keyword), queryLower) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDescription renders explanation text as sanitized HTML and Markdown
+func formatDescription(text string) (string, string) {
+	if text == "" {
+		return "", ""
+	}
+	explanation := policy.FormatExplanation(text)
+	return explanation.HTML(), explanation.Markdown()
+}
+
 func sortCategoryNodes(nodes []*CategoryNode) {
 	sort.Slice(nodes, func(i, j int) bool {
 		return nodes[i].Name < nodes[j].Name
@@ -396,31 +646,38 @@ func sortCategoryNodes(nodes []*CategoryNode) {
 }
 
 func (h *PolicyHandler) readPolicyState(pol *policy.PolicyPlusPolicy) (policy.PolicyState, map[string]interface{}, error) {
-	sections := h.sectionsToCheck(pol.RawPolicy.Section)
+	eval, err := h.evaluatePolicy(pol)
+	if err != nil {
+		return policy.PolicyStateNotConfigured, nil, err
+	}
+	return eval.State, eval.Options, nil
+}
 
-	for idx, section := range sections {
+// evaluatePolicy returns the state of the first section the policy is
+// configured in, with the values that make it inconsistent
+func (h *PolicyHandler) evaluatePolicy(pol *policy.PolicyPlusPolicy) (*policy.StateEvaluation, error) {
+	eval := &policy.StateEvaluation{State: policy.PolicyStateNotConfigured}
+	for _, section := range h.sectionsToCheck(pol.RawPolicy.Section) {
 		source, err := h.getOrCreateSource(section)
 		if err != nil {
-			return policy.PolicyStateNotConfigured, nil, err
+			return nil, err
 		}
 
-		state, options, err := policy.GetPolicyState(source, pol.RawPolicy)
+		eval, err = policy.EvaluatePolicyState(source, pol.RawPolicy)
 		if err != nil {
-			return policy.PolicyStateNotConfigured, nil, err
-		}
-
-		if state != policy.PolicyStateNotConfigured {
-			return state, options, nil
+			return nil, err
 		}
-		if idx == len(sections)-1 {
-			return state, options, nil
+		if eval.State != policy.PolicyStateNotConfigured {
+			break
 		}
 	}
-
-	return policy.PolicyStateNotConfigured, nil, nil
+	return eval, nil
 }
 
 func (h *PolicyHandler) getOrCreateSource(section policy.AdmxPolicySection) (policy.PolicySource, error) {
+	h.sourcesMu.Lock()
+	defer h.sourcesMu.Unlock()
+
 	if h.sources == nil {
 		h.sources = make(map[policy.AdmxPolicySection]policy.PolicySource)
 	}
@@ -456,6 +713,14 @@ type setPolicyRequest struct {
 	Options  map[string]interface{} `json:"options"`
 }
 
+type setRegistryValueRequest struct {
+	Section   string      `json:"section"`
+	Key       string      `json:"key"`
+	ValueName string      `json:"valueName"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+}
+
 func resolveSection(requested string, defaultSection policy.AdmxPolicySection) (policy.AdmxPolicySection, error) {
 	if requested == "" {
 		if defaultSection == policy.Both {
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// requestLanguages returns the languages asked for by a request, most
+// preferred first: the lang query parameter, then Accept-Language.
+func requestLanguages(r *http.Request) []string {
+	var languages []string
+	if lang := strings.TrimSpace(r.URL.Query().Get("lang")); lang != "" {
+		languages = append(languages, lang)
+	}
+
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			accepted = append(accepted, weighted{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+	for _, a := range accepted {
+		languages = append(languages, a.tag)
+	}
+	return languages
+}
+
+// localized returns the workspace state in the language of the request. It
+// falls back to the state as loaded when no requested language is available.
+func (h *PolicyHandler) localized(w http.ResponseWriter, r *http.Request) *workspaceState {
+	ws := h.current()
+	locale, ok := ws.workspace.MatchLocale(requestLanguages(r)...)
+	if !ok {
+		return ws
+	}
+
+	w.Header().Set("Content-Language", locale)
+	view := ws.workspace.Localized(locale)
+	if view == ws.workspace {
+		return ws
+	}
+	return &workspaceState{
+		workspace:     view,
+		detailBuilder: NewPolicyDetailBuilder(view),
+	}
+}
+
+// HandleLocales lists the ADML locales that are loaded
+func (h *PolicyHandler) HandleLocales(w http.ResponseWriter, r *http.Request) {
+	workspace := h.current().workspace
+
+	response := LocalesResponse{Locales: []LocaleInfo{}}
+	for _, locale := range workspace.Locales() {
+		response.Locales = append(response.Locales, LocaleInfo{
+			Code:  locale,
+			Files: workspace.LocaleFileCount(locale),
+		})
+	}
+	if locale, ok := workspace.MatchLocale(requestLanguages(r)...); ok {
+		response.Current = locale
+	}
+
+	respondSuccess(w, response)
+}
+
+// HandleLocalizationCoverage lists, per template, the strings that were not
+// found in the preferred ADML and where they were resolved from instead
+func (h *PolicyHandler) HandleLocalizationCoverage(w http.ResponseWriter, r *http.Request) {
+	workspace := h.localized(w, r).workspace
+
+	response := CoverageResponse{Files: []FileCoverage{}}
+	for _, entry := range workspace.Coverage() {
+		file := FileCoverage{
+			File:      entry.File,
+			Namespace: entry.Namespace,
+			Locale:    entry.Locale,
+			Total:     entry.Total,
+			Fallbacks: make([]StringFallbackInfo, 0, len(entry.Fallbacks)),
+		}
+		for _, fallback := range entry.Fallbacks {
+			file.Fallbacks = append(file.Fallbacks, StringFallbackInfo{
+				Code:   fallback.Code,
+				Locale: fallback.Locale,
+			})
+			if fallback.Locale == "" {
+				response.Missing++
+			} else {
+				response.FellBack++
+			}
+		}
+		response.Files = append(response.Files, file)
+	}
+	if locale, ok := workspace.MatchLocale(requestLanguages(r)...); ok {
+		response.Locale = locale
+	}
+
+	respondSuccess(w, response)
+}
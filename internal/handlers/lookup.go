@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// HandleRegistryLookup finds the policies that write a registry key or
+// value, given as path=HKLM\Software\...; paths without a hive match
+// policies of both sections
+func (h *PolicyHandler) HandleRegistryLookup(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSpace(r.URL.Query().Get("path"))
+	if path == "" {
+		respondError(w, http.StatusBadRequest, "Registry path required")
+		return
+	}
+
+	section, key := policy.ParseRegistryPath(path)
+	if key == "" {
+		respondError(w, http.StatusBadRequest, "Registry path needs a key below the hive")
+		return
+	}
+
+	ws := h.localized(w, r)
+	matches := ws.workspace.LookupRegistry(section, key)
+	response := RegistryLookupResponse{
+		Path:    path,
+		Section: sectionName(section),
+		Key:     key,
+		Matches: make([]RegistryLookupMatch, 0, len(matches)),
+	}
+	for _, match := range matches {
+		response.Matches = append(response.Matches, registryLookupMatch(match))
+	}
+	respondSuccess(w, response)
+}
+
+func registryLookupMatch(match *policy.RegistryMatch) RegistryLookupMatch {
+	pol := match.Policy
+	result := RegistryLookupMatch{
+		PolicyID:     pol.UniqueID,
+		PolicyName:   pol.DisplayName,
+		Section:      sectionName(pol.RawPolicy.Section),
+		CategoryPath: pol.CategoryPath(),
+		File:         pol.RawPolicy.DefinedIn.SourceFile,
+		ElementID:    match.ElementID,
+		Source:       match.Source,
+		Key:          match.Key,
+		ValueName:    match.ValueName,
+		KeyOnly:      match.KeyOnly,
+		States:       []string{},
+	}
+	if pol.Category != nil {
+		result.CategoryID = pol.Category.UniqueID
+	}
+	if result.CategoryPath == nil {
+		result.CategoryPath = []string{}
+	}
+	if match.Enabled {
+		result.States = append(result.States, policy.PolicyStateEnabled.String())
+	}
+	if match.Disabled {
+		result.States = append(result.States, policy.PolicyStateDisabled.String())
+	}
+	return result
+}
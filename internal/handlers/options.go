@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"gopolicy/internal/policy"
+)
+
+// prepareOptions completes the options of a set request with presentation
+// defaults, converts them to the types of the policy's elements and checks
+// their constraints. Enum items may be given by name in the language of the
+// request. The defaults that were used are returned as well.
+func (h *PolicyHandler) prepareOptions(w http.ResponseWriter, r *http.Request, pol *policy.PolicyPlusPolicy, options map[string]interface{}) (map[string]interface{}, []AppliedDefaultInfo, error) {
+	ws := h.localized(w, r)
+	if localized, ok := ws.workspace.Policies[pol.UniqueID]; ok {
+		pol = localized
+	}
+
+	defaults := ws.workspace.PresentationDefaults(pol)
+	options, used := policy.WithDefaults(options, defaults)
+	prepared, err := policy.PrepareOptions(ws.workspace.OptionSchemas(pol), options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied := make([]AppliedDefaultInfo, 0, len(used))
+	for _, id := range used {
+		applied = append(applied, AppliedDefaultInfo{ElementID: id, Value: prepared[id]})
+	}
+	return prepared, applied, nil
+}
+
+// respondOptionErrors reports option errors with one entry per element and
+// returns true; other errors are left to the caller.
+func respondOptionErrors(w http.ResponseWriter, err error) bool {
+	var optionErrors policy.OptionErrors
+	if !errors.As(err, &optionErrors) {
+		return false
+	}
+
+	items := make([]OptionErrorInfo, 0, len(optionErrors))
+	for _, e := range optionErrors {
+		items = append(items, OptionErrorInfo{ElementID: e.ElementID, Code: e.Code, Message: e.Message})
+	}
+	respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"success": false,
+		"error":   "Invalid policy options",
+		"errors":  items,
+	})
+	return true
+}
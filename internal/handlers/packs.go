@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// maxPackUploadSize limits the size of an uploaded template pack
+const maxPackUploadSize = 64 << 20
+
+// SetPackStore sets the store of custom template packs. Its folder must be
+// one of the roots of the bundle loader.
+func (h *PolicyHandler) SetPackStore(store *policy.PackStore) {
+	h.packs = store
+}
+
+// HandlePacks lists (GET), installs (POST) and removes (DELETE) custom
+// template packs
+func (h *PolicyHandler) HandlePacks(w http.ResponseWriter, r *http.Request) {
+	if h.packs == nil {
+		respondError(w, http.StatusNotImplemented, "Template packs are not configured")
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/packs"), "/")
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		h.listPacks(w)
+	case r.Method == http.MethodGet:
+		h.getPack(w, id)
+	case r.Method == http.MethodPost && id == "":
+		h.installPack(w, r)
+	case r.Method == http.MethodDelete && id != "":
+		h.removePack(w, r, id)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *PolicyHandler) listPacks(w http.ResponseWriter) {
+	packs, err := h.packs.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Template packs could not be listed: %v", err))
+		return
+	}
+
+	workspace := h.current().workspace
+	items := make([]TemplatePackInfo, 0, len(packs))
+	for _, pack := range packs {
+		items = append(items, h.packInfo(workspace, pack))
+	}
+	respondSuccess(w, items)
+}
+
+func (h *PolicyHandler) getPack(w http.ResponseWriter, id string) {
+	pack, err := h.packs.Get(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Template pack not found")
+		return
+	}
+	respondSuccess(w, h.packInfo(h.current().workspace, pack))
+}
+
+// packInfo describes a pack together with what the live bundle loaded
+// from it
+func (h *PolicyHandler) packInfo(workspace *policy.AdmxBundle, pack *policy.TemplatePack) TemplatePackInfo {
+	loaded := h.packNamespaces(workspace, pack)
+	count := 0
+	for _, pol := range workspace.Policies {
+		if loaded[pol.RawPolicy.DefinedIn.AdmxNamespace] {
+			count++
+		}
+	}
+	return TemplatePackInfo{
+		ID:          pack.ID,
+		Namespaces:  pack.Namespaces,
+		Files:       pack.Files,
+		Locales:     pack.Locales,
+		Installed:   pack.Installed,
+		PolicyCount: count,
+		Loaded:      len(loaded) > 0,
+	}
+}
+
+// packNamespaces returns the namespaces of a pack that the bundle loaded
+// from the pack's own files
+func (h *PolicyHandler) packNamespaces(workspace *policy.AdmxBundle, pack *policy.TemplatePack) map[string]bool {
+	result := make(map[string]bool)
+	for _, ns := range pack.Namespaces {
+		if src, ok := workspace.NamespaceSources[ns]; ok && h.packs.Contains(pack.ID, src.File) {
+			result[ns] = true
+		}
+	}
+	return result
+}
+
+// installPack accepts a multipart upload: either one zip archive or loose
+// .admx/.adml/.adm files in the files field. Loose ADML files are placed in
+// the folder of the locale field (default en-US). The pack ID comes from
+// the name field, the archive name or the first template. A pack that
+// defines a namespace of the templates outside the store is refused with
+// 409, unless replace=true is given.
+func (h *PolicyHandler) installPack(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPackUploadSize)
+	if err := r.ParseMultipartForm(maxPackUploadSize); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid upload: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	uploads := r.MultipartForm.File["files"]
+	if len(uploads) == 0 {
+		respondError(w, http.StatusBadRequest, "No files uploaded")
+		return
+	}
+
+	staging, err := os.MkdirTemp("", "gopolicy-upload-*")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Upload could not be staged")
+		return
+	}
+	defer os.RemoveAll(staging)
+
+	id := strings.TrimSpace(r.FormValue("name"))
+	var archive *zip.Reader
+	if len(uploads) == 1 && strings.EqualFold(filepath.Ext(uploads[0].Filename), ".zip") {
+		data, err := readUpload(uploads[0])
+		if err == nil {
+			archive, err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		}
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid zip archive: %v", err))
+			return
+		}
+		if id == "" {
+			id = uploads[0].Filename
+		}
+	} else {
+		locale := strings.TrimSpace(r.FormValue("locale"))
+		if locale == "" {
+			locale = "en-US"
+		}
+		if policy.SanitizePackID(locale) != locale {
+			respondError(w, http.StatusBadRequest, "Invalid locale")
+			return
+		}
+		for _, upload := range uploads {
+			name := filepath.Base(upload.Filename)
+			if strings.EqualFold(filepath.Ext(name), ".adml") {
+				name = filepath.Join(locale, name)
+			}
+			if id == "" && !strings.EqualFold(filepath.Ext(name), ".adml") {
+				id = name
+			}
+			if err := saveUpload(upload, filepath.Join(staging, name)); err != nil {
+				respondError(w, http.StatusInternalServerError, "Upload could not be staged")
+				return
+			}
+		}
+	}
+
+	id = policy.SanitizePackID(id)
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "Template pack needs a name")
+		return
+	}
+
+	system := h.systemNamespaces()
+	protected := system
+	if r.FormValue("replace") == "true" {
+		protected = nil
+	}
+
+	var pack *policy.TemplatePack
+	languages := h.current().workspace.LanguageCodes()
+	if archive != nil {
+		pack, err = h.packs.Install(id, archive, protected, languages...)
+	} else {
+		pack, err = h.packs.Install(id, os.DirFS(staging), protected, languages...)
+	}
+	var invalid *policy.PackError
+	var replacing *policy.PackReplaceError
+	if errors.As(err, &replacing) {
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"success":  false,
+			"error":    fmt.Sprintf("Template pack '%s' would replace %d namespace(s) of the system templates; use replace=true to install it anyway", replacing.Pack, len(replacing.Replaces)),
+			"replaces": replacing.Replaces,
+		})
+		return
+	}
+	if errors.As(err, &invalid) {
+		respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"success":  false,
+			"error":    fmt.Sprintf("Template pack '%s' is invalid", invalid.Pack),
+			"problems": invalid.Problems,
+		})
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Template pack could not be installed: %v", err))
+		return
+	}
+
+	h.respondPackChange(w, pack, fmt.Sprintf("Template pack '%s' installed", pack.ID), packReplaces(pack, system))
+}
+
+// systemNamespaces maps the namespaces that templates outside the pack
+// store define to their files, including templates a pack already replaced
+func (h *PolicyHandler) systemNamespaces() map[string]string {
+	result := make(map[string]string)
+	for ns, src := range h.current().workspace.NamespaceSources {
+		for _, file := range append([]string{src.File}, src.Overridden...) {
+			if !h.packs.Holds(file) {
+				result[ns] = file
+				break
+			}
+		}
+	}
+	return result
+}
+
+// packReplaces returns the system templates a pack replaces, by namespace
+func packReplaces(pack *policy.TemplatePack, system map[string]string) map[string]string {
+	replaces := make(map[string]string)
+	for _, ns := range pack.Namespaces {
+		if file, ok := system[ns]; ok {
+			replaces[ns] = file
+		}
+	}
+	return replaces
+}
+
+// removePack deletes a pack. When policies of its namespaces are configured
+// the removal is refused with 409, unless force=true is given.
+func (h *PolicyHandler) removePack(w http.ResponseWriter, r *http.Request, id string) {
+	pack, err := h.packs.Get(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Template pack not found")
+		return
+	}
+
+	if r.URL.Query().Get("force") != "true" {
+		orphaned := h.configuredPackPolicies(pack)
+		if len(orphaned) > 0 {
+			respondJSON(w, http.StatusConflict, map[string]interface{}{
+				"success":  false,
+				"error":    fmt.Sprintf("%d configured policies depend on template pack '%s'; use force=true to remove it anyway", len(orphaned), pack.ID),
+				"policies": orphaned,
+			})
+			return
+		}
+	}
+
+	if err := h.packs.Remove(pack.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Template pack could not be removed: %v", err))
+		return
+	}
+	h.respondPackChange(w, pack, fmt.Sprintf("Template pack '%s' removed", pack.ID), nil)
+}
+
+// configuredPackPolicies returns the IDs of configured policies that only
+// the pack defines, sorted
+func (h *PolicyHandler) configuredPackPolicies(pack *policy.TemplatePack) []string {
+	workspace := h.current().workspace
+	namespaces := h.packNamespaces(workspace, pack)
+
+	result := []string{}
+	for id, pol := range workspace.Policies {
+		ns := pol.RawPolicy.DefinedIn.AdmxNamespace
+		if !namespaces[ns] || len(workspace.NamespaceSources[ns].Overridden) > 0 {
+			continue
+		}
+		state, _, err := h.readPolicyState(pol)
+		if err == nil && state != policy.PolicyStateNotConfigured {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// respondPackChange reloads the templates so the change takes effect and
+// reports both. A failed reload is reported with 500: the change is made,
+// but the templates in use do not reflect it.
+func (h *PolicyHandler) respondPackChange(w http.ResponseWriter, pack *policy.TemplatePack, message string, replaces map[string]string) {
+	response := PackChangeResponse{Success: true, Message: message}
+	if len(replaces) > 0 {
+		response.Replaces = replaces
+	}
+	result, err := h.Reload()
+	switch {
+	case err == nil:
+		response.Reload = result
+		info := h.packInfo(h.current().workspace, pack)
+		response.Pack = &info
+	case errors.Is(err, errReloadNotConfigured):
+		response.Message += "; restart to load it"
+	default:
+		response.Success = false
+		response.Error = fmt.Sprintf("Template reload failed: %v", err)
+		respondJSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	respondSuccess(w, response)
+}
+
+func readUpload(upload *multipart.FileHeader) ([]byte, error) {
+	file, err := upload.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func saveUpload(upload *multipart.FileHeader, target string) error {
+	data, err := readUpload(upload)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0644)
+}
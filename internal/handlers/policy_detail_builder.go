@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"net/url"
+
 	"gopolicy/internal/policy"
 )
 
@@ -14,14 +16,27 @@ func NewPolicyDetailBuilder(workspace *policy.AdmxBundle) *PolicyDetailBuilder {
 
 func (b *PolicyDetailBuilder) Build(pol *policy.PolicyPlusPolicy, state policy.PolicyState, options map[string]interface{}) PolicyDetail {
 	detail := PolicyDetail{
-		ID:          pol.UniqueID,
-		Name:        pol.DisplayName,
-		Description: pol.DisplayExplanation,
-		State:       state.String(),
-		Section:     sectionName(pol.RawPolicy.Section),
-		Elements:    []ElementInfo{},
-		RegistryKey: pol.RawPolicy.RegistryKey,
+		ID:               pol.UniqueID,
+		Name:             pol.DisplayName,
+		Description:      pol.DisplayExplanation,
+		State:            state.String(),
+		Section:          sectionName(pol.RawPolicy.Section),
+		Elements:         []ElementInfo{},
+		Layout:           []LayoutItem{},
+		RegistryKey:      pol.RawPolicy.RegistryKey,
+		Keywords:         pol.Keywords,
+		SeeAlso:          buildSeeAlso(pol.SeeAlso),
+		ClientExtensions: buildClientExtensions(pol),
+		Footprint:        buildFootprint(policy.PolicyFootprint(pol.RawPolicy)),
+	}
+	if pol.SupportedOn != nil {
+		detail.SupportedOn = pol.SupportedOn.DisplayName
 	}
+	if detail.Keywords == nil {
+		detail.Keywords = []string{}
+	}
+	detail.Tattooing = len(detail.Footprint.UnmanagedKeys) > 0
+	detail.DescriptionHTML, detail.DescriptionMarkdown = formatDescription(pol.DisplayExplanation)
 
 	if pol.RawPolicy.Elements == nil {
 		return detail
@@ -32,9 +47,43 @@ func (b *PolicyDetailBuilder) Build(pol *policy.PolicyPlusPolicy, state policy.P
 		detail.Elements = append(detail.Elements, elemInfo)
 	}
 
+	detail.Layout = b.buildLayout(pol)
 	return detail
 }
 
+// buildLayout lists labels and controls in presentation order. Elements the
+// presentation does not show are appended at the end.
+func (b *PolicyDetailBuilder) buildLayout(pol *policy.PolicyPlusPolicy) []LayoutItem {
+	layout := []LayoutItem{}
+	shown := make(map[string]bool)
+
+	if pol.Presentation != nil {
+		for _, presElem := range pol.Presentation.Elements {
+			if label, ok := presElem.(*policy.LabelPresentationElement); ok {
+				layout = append(layout, LayoutItem{Type: "label", Text: b.resolveString(label.Text, pol)})
+				continue
+			}
+			if shown[presElem.GetID()] {
+				continue
+			}
+			for _, elem := range pol.RawPolicy.Elements {
+				if elem.GetID() == presElem.GetID() {
+					layout = append(layout, LayoutItem{Type: "element", ElementID: elem.GetID()})
+					shown[elem.GetID()] = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, elem := range pol.RawPolicy.Elements {
+		if !shown[elem.GetID()] {
+			layout = append(layout, LayoutItem{Type: "element", ElementID: elem.GetID()})
+		}
+	}
+	return layout
+}
+
 func (b *PolicyDetailBuilder) buildElementInfo(pol *policy.PolicyPlusPolicy, elem policy.PolicyElement, options map[string]interface{}) ElementInfo {
 	elemInfo := ElementInfo{
 		ID:       elem.GetID(),
@@ -58,6 +107,7 @@ func (b *PolicyDetailBuilder) buildElementInfo(pol *policy.PolicyPlusPolicy, ele
 }
 
 func (b *PolicyDetailBuilder) applyPresentation(metadata map[string]interface{}, elemInfo *ElementInfo, pres policy.PresentationElement, pol *policy.PolicyPlusPolicy) {
+	elemInfo.Control = pres.GetElementType()
 	switch pe := pres.(type) {
 	case *policy.TextBoxPresentationElement:
 		elemInfo.Label = b.resolveString(pe.Label, pol)
@@ -69,6 +119,10 @@ func (b *PolicyDetailBuilder) applyPresentation(metadata map[string]interface{},
 		if pe.DefaultValue != 0 {
 			elemInfo.DefaultValue = pe.DefaultValue
 		}
+		elemInfo.Spinner = pe.HasSpinner
+		if pe.HasSpinner {
+			elemInfo.SpinnerStep = pe.SpinnerIncrement
+		}
 	case *policy.CheckBoxPresentationElement:
 		elemInfo.Label = b.resolveString(pe.Text, pol)
 		elemInfo.DefaultValue = pe.DefaultState
@@ -77,8 +131,14 @@ func (b *PolicyDetailBuilder) applyPresentation(metadata map[string]interface{},
 		if pe.DefaultText != "" {
 			elemInfo.DefaultValue = b.resolveString(pe.DefaultText, pol)
 		}
+		elemInfo.NoSort = pe.NoSort
+		for _, suggestion := range pe.Suggestions {
+			elemInfo.Suggestions = append(elemInfo.Suggestions, b.resolveString(suggestion, pol))
+		}
 	case *policy.DropDownPresentationElement:
 		elemInfo.Label = b.resolveString(pe.Label, pol)
+		elemInfo.NoSort = pe.NoSort
+		elemInfo.DefaultItem = pe.DefaultItemID
 	case *policy.ListPresentationElement:
 		elemInfo.Label = b.resolveString(pe.Label, pol)
 	case *policy.MultiTextPresentationElement:
@@ -170,3 +230,16 @@ func sectionName(section policy.AdmxPolicySection) string {
 		return "Both"
 	}
 }
+
+// buildSeeAlso turns seeAlso texts into links when they are http(s) URLs
+func buildSeeAlso(texts []string) []SeeAlsoInfo {
+	result := []SeeAlsoInfo{}
+	for _, text := range texts {
+		info := SeeAlsoInfo{Text: text}
+		if u, err := url.Parse(text); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			info.URL = u.String()
+		}
+		result = append(result, info)
+	}
+	return result
+}
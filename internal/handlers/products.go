@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopolicy/internal/policy"
+)
+
+// applicabilityFilter builds a filter from the applicableTo query parameter,
+// a comma separated list of product IDs or names. It returns nil when the
+// parameter is missing.
+func applicabilityFilter(workspace *policy.AdmxBundle, r *http.Request) (policyFilter, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("applicableTo"))
+	if value == "" {
+		return nil, nil
+	}
+
+	var products []*policy.PolicyPlusProduct
+	for _, ref := range strings.Split(value, ",") {
+		if strings.TrimSpace(ref) == "" {
+			continue
+		}
+		product, ok := workspace.FindProduct(ref)
+		if !ok {
+			return nil, fmt.Errorf("unknown product: %s", strings.TrimSpace(ref))
+		}
+		products = append(products, product)
+	}
+	if len(products) == 0 {
+		return nil, nil
+	}
+
+	return func(pol *policy.PolicyPlusPolicy) bool {
+		return pol.AppliesTo(products...)
+	}, nil
+}
+
+// HandleProducts returns the product tree used by supportedOn definitions
+func (h *PolicyHandler) HandleProducts(w http.ResponseWriter, r *http.Request) {
+	ws := h.localized(w, r)
+
+	roots := make([]*ProductNode, 0, len(ws.workspace.Products))
+	for _, product := range ws.workspace.Products {
+		roots = append(roots, buildProductNode(product))
+	}
+	sortProductNodes(roots)
+
+	respondSuccess(w, roots)
+}
+
+func buildProductNode(product *policy.PolicyPlusProduct) *ProductNode {
+	node := &ProductNode{
+		ID:       product.UniqueID,
+		Name:     product.DisplayName,
+		Type:     productTypeName(product.RawProduct.Type),
+		Version:  product.RawProduct.Version,
+		Children: []*ProductNode{},
+	}
+	for _, child := range product.Children {
+		node.Children = append(node.Children, buildProductNode(child))
+	}
+	sortProductNodes(node.Children)
+	return node
+}
+
+func productTypeName(productType policy.AdmxProductType) string {
+	switch productType {
+	case policy.MajorRevision:
+		return "majorVersion"
+	case policy.MinorRevision:
+		return "minorVersion"
+	default:
+		return "product"
+	}
+}
+
+// sortProductNodes orders versions by version index and products by name
+func sortProductNodes(nodes []*ProductNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Version != nodes[j].Version {
+			return nodes[i].Version < nodes[j].Version
+		}
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gopolicy/internal/policy"
+)
+
+// BundleLoader builds a fresh, fully loaded bundle.
+type BundleLoader func() (*policy.AdmxBundle, []*policy.AdmxLoadFailure, error)
+
+var errReloadNotConfigured = errors.New("template reload is not configured")
+
+// SetBundleLoader sets the loader used by Reload.
+func (h *PolicyHandler) SetBundleLoader(loader BundleLoader) {
+	h.loader = loader
+}
+
+// Reload builds a new bundle in the background and swaps it in once it is
+// complete. Requests that already started keep using the previous bundle.
+func (h *PolicyHandler) Reload() (*ReloadResponse, error) {
+	if h.loader == nil {
+		return nil, errReloadNotConfigured
+	}
+
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	start := time.Now()
+	bundle, failures, err := h.loader()
+	if err != nil {
+		return nil, err
+	}
+
+	previous := h.state.Swap(newWorkspaceState(bundle))
+	diff := policy.DiffBundles(previous.workspace, bundle)
+
+	return &ReloadResponse{
+		Success: true,
+		Message: fmt.Sprintf("Templates reloaded: %d added, %d removed, %d changed",
+			len(diff.Added), len(diff.Removed), len(diff.Changed)),
+		Added:       diff.Added,
+		Removed:     diff.Removed,
+		Changed:     diff.Changed,
+		PolicyCount: len(bundle.Policies),
+		Failures:    len(failures),
+		DurationMs:  time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// HandleReload reloads the ADMX templates
+func (h *PolicyHandler) HandleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	result, err := h.Reload()
+	if errors.Is(err, errReloadNotConfigured) {
+		respondError(w, http.StatusNotImplemented, "Template reload is not configured")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Template reload failed: %v", err))
+		return
+	}
+
+	respondSuccess(w, result)
+}
+
+// WatchTemplates polls the template folders and reloads when a template
+// file is added, removed or modified. It returns when stop is closed.
+func (h *PolicyHandler) WatchTemplates(folders []string, interval time.Duration, stop <-chan struct{}) {
+	fingerprint := func() string {
+		combined := ""
+		for _, folder := range folders {
+			fp, err := policy.FolderFingerprint(folder)
+			if err != nil {
+				continue
+			}
+			combined += fp
+		}
+		return combined
+	}
+
+	last := fingerprint()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		current := fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+
+		result, err := h.Reload()
+		if err != nil {
+			log.Printf("Template reload failed: %v\n", err)
+			continue
+		}
+		log.Println(result.Message)
+	}
+}
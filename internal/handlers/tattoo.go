@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopolicy/internal/policy"
+)
+
+// SetTattooStore sets the store that keeps the values tattooing policies
+// overwrite. Without one, no backups are made.
+func (h *PolicyHandler) SetTattooStore(store *policy.TattooStore) {
+	h.tattoos = store
+}
+
+// HandleTattoos lists the policies that write outside the policy trees,
+// with their stored backups
+func (h *PolicyHandler) HandleTattoos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	backups, err := h.storedTattoos()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Tattoo backups could not be read: %v", err))
+		return
+	}
+
+	ws := h.localized(w, r)
+	response := TattoosResponse{
+		BackupEnabled: h.tattoos != nil,
+		Policies:      []TattooingPolicyInfo{},
+	}
+	for _, pol := range ws.workspace.TattooingPolicies() {
+		response.Policies = append(response.Policies, TattooingPolicyInfo{
+			ID:            pol.UniqueID,
+			Name:          pol.DisplayName,
+			Section:       sectionName(pol.RawPolicy.Section),
+			UnmanagedKeys: ws.workspace.UnmanagedKeys(pol),
+			Backups:       tattooBackupInfos(backups, pol.UniqueID),
+		})
+	}
+	response.Count = len(response.Policies)
+	respondSuccess(w, response)
+}
+
+// backupTattoo records the values a tattooing policy is about to overwrite
+// in source, unless a backup of the policy already exists: the first one
+// holds the values from before the policy was set. It returns nil for
+// policies that do not tattoo.
+func (h *PolicyHandler) backupTattoo(source policy.PolicySource, workspace *policy.AdmxBundle, pol *policy.PolicyPlusPolicy, section policy.AdmxPolicySection, state policy.PolicyState) (*TattooResultInfo, error) {
+	keys := workspace.UnmanagedKeys(pol)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	result := &TattooResultInfo{UnmanagedKeys: keys, Backup: "none"}
+	if h.tattoos == nil || !policy.TattooBackupSupported(source) || state == policy.PolicyStateNotConfigured {
+		return result, nil
+	}
+
+	existing, err := h.tattoos.Get(section, pol.UniqueID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		result.Backup = "kept"
+		return result, nil
+	}
+
+	backup, err := policy.BackupTattooValues(source, pol.UniqueID, pol.RawPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.tattoos.Put(backup); err != nil {
+		return nil, err
+	}
+	result.Backup = "recorded"
+	return result, nil
+}
+
+// restoreTattoo puts back the values of the backup of a policy that was
+// set to Not Configured and drops the backup
+func (h *PolicyHandler) restoreTattoo(source policy.PolicySource, pol *policy.PolicyPlusPolicy, section policy.AdmxPolicySection, result *TattooResultInfo) error {
+	if result == nil || h.tattoos == nil || !policy.TattooBackupSupported(source) {
+		return nil
+	}
+	backup, err := h.tattoos.Get(section, pol.UniqueID)
+	if err != nil || backup == nil {
+		return err
+	}
+
+	if err := policy.RestoreTattooValues(source, backup); err != nil {
+		return err
+	}
+	if err := h.tattoos.Delete(section, pol.UniqueID); err != nil {
+		return err
+	}
+	result.Backup = "restored"
+	for _, value := range backup.Values {
+		if value.Exists {
+			result.Restored++
+		}
+	}
+	return nil
+}
+
+// tattooBackups returns the stored backups of a policy; none when they
+// cannot be read
+func (h *PolicyHandler) tattooBackups(pol *policy.PolicyPlusPolicy) []TattooBackupInfo {
+	backups, err := h.storedTattoos()
+	if err != nil {
+		return nil
+	}
+	return tattooBackupInfos(backups, pol.UniqueID)
+}
+
+func (h *PolicyHandler) storedTattoos() ([]*policy.TattooBackup, error) {
+	if h.tattoos == nil {
+		return nil, nil
+	}
+	return h.tattoos.List()
+}
+
+func tattooBackupInfos(backups []*policy.TattooBackup, policyID string) []TattooBackupInfo {
+	infos := []TattooBackupInfo{}
+	for _, backup := range backups {
+		if backup.PolicyID != policyID {
+			continue
+		}
+		infos = append(infos, TattooBackupInfo{
+			Section:  backup.Section,
+			Recorded: backup.Recorded,
+			Values:   len(backup.Values),
+		})
+	}
+	return infos
+}
@@ -1,15 +1,22 @@
 package handlers
 
+import "time"
+
 // The CategoryNode, PolicyListItem, PolicyDetail, and ElementInfo structs represent HTTP
 // responses and are shared across multiple handlers, so they're defined in a separate file.
 
 // CategoryNode represents a category tree node.
 type CategoryNode struct {
-	ID          string          `json:"id"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Children    []*CategoryNode `json:"children"`
-	PolicyCount int             `json:"policyCount"`
+	ID                  string          `json:"id"`
+	Name                string          `json:"name"`
+	Description         string          `json:"description"`
+	DescriptionHTML     string          `json:"descriptionHtml,omitempty"`
+	DescriptionMarkdown string          `json:"descriptionMarkdown,omitempty"`
+	Children            []*CategoryNode `json:"children"`
+	PolicyCount         int             `json:"policyCount"`
+	Virtual             bool            `json:"virtual,omitempty"`
+	Section             string          `json:"section,omitempty"`
+	Error               string          `json:"error,omitempty"`
 }
 
 // PolicyListItem represents a summary of a policy under a category.
@@ -19,17 +26,246 @@ type PolicyListItem struct {
 	Description string `json:"description"`
 	State       string `json:"state"`
 	Section     string `json:"section"`
+	Tattooing   bool   `json:"tattooing,omitempty"`
 }
 
 // PolicyDetail contains details for a single policy.
 type PolicyDetail struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	Section     string        `json:"section"`
-	State       string        `json:"state"`
-	Elements    []ElementInfo `json:"elements"`
-	RegistryKey string        `json:"registryKey"`
+	ID                  string                `json:"id"`
+	Name                string                `json:"name"`
+	Description         string                `json:"description"`
+	DescriptionHTML     string                `json:"descriptionHtml"`
+	DescriptionMarkdown string                `json:"descriptionMarkdown"`
+	Section             string                `json:"section"`
+	State               string                `json:"state"`
+	Elements            []ElementInfo         `json:"elements"`
+	Layout              []LayoutItem          `json:"layout"`
+	RegistryKey         string                `json:"registryKey"`
+	SupportedOn         string                `json:"supportedOn"`
+	Applicable          *bool                 `json:"applicable,omitempty"`
+	ClientExtensions    []ClientExtensionInfo `json:"clientExtensions"`
+	Keywords            []string              `json:"keywords"`
+	SeeAlso             []SeeAlsoInfo         `json:"seeAlso"`
+	Mismatches          []StateMismatchInfo   `json:"mismatches,omitempty"`
+	Footprint           FootprintInfo         `json:"footprint"`
+	Tattooing           bool                  `json:"tattooing"`
+	TattooBackups       []TattooBackupInfo    `json:"tattooBackups,omitempty"`
+}
+
+// FootprintInfo lists the registry values a policy writes or deletes when
+// it is enabled or disabled, and the keys involved. UnmanagedKeys are the
+// keys outside the policy trees, whose values stay after the policy is
+// removed.
+type FootprintInfo struct {
+	Keys          []string             `json:"keys"`
+	UnmanagedKeys []string             `json:"unmanagedKeys"`
+	Enabled       []FootprintEntryInfo `json:"enabled"`
+	Disabled      []FootprintEntryInfo `json:"disabled"`
+}
+
+// FootprintEntryInfo is one registry change. Value names in braces stand
+// for names taken from the options; data is omitted when it comes from the
+// options.
+type FootprintEntryInfo struct {
+	Key       string `json:"key"`
+	ValueName string `json:"valueName,omitempty"`
+	Action    string `json:"action"`
+	Type      string `json:"type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Source    string `json:"source"`
+	ElementID string `json:"elementId,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// RegistryLookupResponse lists the policies that write a registry path.
+// Section is the one of the hive in the path, Both without a hive.
+type RegistryLookupResponse struct {
+	Path    string                `json:"path"`
+	Section string                `json:"section"`
+	Key     string                `json:"key"`
+	Matches []RegistryLookupMatch `json:"matches"`
+}
+
+// RegistryLookupMatch is a policy, or one of its elements, that writes or
+// deletes the path. States are those in which it does; keyOnly is set when
+// the path named a key that holds the value.
+type RegistryLookupMatch struct {
+	PolicyID     string   `json:"policyId"`
+	PolicyName   string   `json:"policyName"`
+	Section      string   `json:"section"`
+	CategoryID   string   `json:"categoryId,omitempty"`
+	CategoryPath []string `json:"categoryPath"`
+	File         string   `json:"file,omitempty"`
+	ElementID    string   `json:"elementId,omitempty"`
+	Source       string   `json:"source"`
+	Key          string   `json:"key"`
+	ValueName    string   `json:"valueName,omitempty"`
+	KeyOnly      bool     `json:"keyOnly"`
+	States       []string `json:"states"`
+}
+
+// ExtraRegistryResponse lists the registry values that no loaded template
+// describes.
+type ExtraRegistryResponse struct {
+	Count  int                 `json:"count"`
+	Values []ExtraRegistryInfo `json:"values"`
+}
+
+// ExtraRegistryInfo is a raw registry value of Registry.pol or the
+// registry. Deleted marks a **del. entry; editable is false for markers and
+// types that cannot be written.
+type ExtraRegistryInfo struct {
+	Section    string                   `json:"section"`
+	Source     string                   `json:"source"`
+	Key        string                   `json:"key"`
+	ValueName  string                   `json:"valueName"`
+	Type       string                   `json:"type"`
+	Data       interface{}              `json:"data"`
+	Deleted    bool                     `json:"deleted"`
+	Editable   bool                     `json:"editable"`
+	Suggestion *NamespaceSuggestionInfo `json:"suggestion,omitempty"`
+}
+
+// NamespaceSuggestionInfo is the template namespace that probably
+// describes an extra registry value.
+type NamespaceSuggestionInfo struct {
+	Namespace string `json:"namespace"`
+	Template  string `json:"template,omitempty"`
+	Loaded    bool   `json:"loaded"`
+	Reason    string `json:"reason"`
+}
+
+// RegistryConflictsResponse lists the registry values that more than one
+// policy writes.
+type RegistryConflictsResponse struct {
+	Count          int                    `json:"count"`
+	CrossFileCount int                    `json:"crossFileCount"`
+	Conflicts      []RegistryConflictInfo `json:"conflicts"`
+}
+
+// RegistryConflictInfo is a registry value written by several policies. An
+// empty valueName is a key that one of them clears.
+type RegistryConflictInfo struct {
+	Key       string                `json:"key"`
+	ValueName string                `json:"valueName,omitempty"`
+	CrossFile bool                  `json:"crossFile"`
+	Policies  []RegistryLookupMatch `json:"policies"`
+}
+
+// AffectedPolicyInfo is another policy whose state changed because it
+// shares registry values with the policy that was set.
+type AffectedPolicyInfo struct {
+	PolicyID   string `json:"policyId"`
+	PolicyName string `json:"policyName"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+}
+
+// TattooBackupInfo is a stored backup of the values a tattooing policy
+// overwrote in one section.
+type TattooBackupInfo struct {
+	Section  string    `json:"section"`
+	Recorded time.Time `json:"recorded"`
+	Values   int       `json:"values"`
+}
+
+// TattooResultInfo reports what setting a tattooing policy did with its
+// backup: recorded, kept (one already existed), restored or none.
+type TattooResultInfo struct {
+	UnmanagedKeys []string `json:"unmanagedKeys"`
+	Backup        string   `json:"backup"`
+	Restored      int      `json:"restored"`
+}
+
+// TattooingPolicyInfo is a policy that writes outside the policy trees.
+type TattooingPolicyInfo struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Section       string             `json:"section"`
+	UnmanagedKeys []string           `json:"unmanagedKeys"`
+	Backups       []TattooBackupInfo `json:"backups"`
+}
+
+// TattoosResponse lists the tattooing policies. BackupEnabled is false
+// when no backup file is configured.
+type TattoosResponse struct {
+	Count         int                   `json:"count"`
+	BackupEnabled bool                  `json:"backupEnabled"`
+	Policies      []TattooingPolicyInfo `json:"policies"`
+}
+
+// PolicyFootprintInfo is the footprint of one policy in the catalog export
+type PolicyFootprintInfo struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Section   string        `json:"section"`
+	Namespace string        `json:"namespace"`
+	Footprint FootprintInfo `json:"footprint"`
+}
+
+// PolicyExplanation traces how the state of a policy is decided. State
+// and Section are those of the first section that configures the policy.
+type PolicyExplanation struct {
+	ID       string               `json:"id"`
+	State    string               `json:"state"`
+	Section  string               `json:"section"`
+	Sections []SectionExplanation `json:"sections"`
+}
+
+// SectionExplanation is the verdict of one section, the source it came
+// from and the traces of every source read
+type SectionExplanation struct {
+	Section   string           `json:"section"`
+	State     string           `json:"state"`
+	DecidedBy string           `json:"decidedBy"`
+	Rule      string           `json:"rule"`
+	Disagree  bool             `json:"disagree"`
+	Sources   []StateTraceInfo `json:"sources"`
+}
+
+// StateTraceInfo lists the checks made against one source
+type StateTraceInfo struct {
+	Source     string              `json:"source"`
+	Path       string              `json:"path,omitempty"`
+	State      string              `json:"state"`
+	Rule       string              `json:"rule,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Checks     []StateCheckInfo    `json:"checks"`
+	Mismatches []StateMismatchInfo `json:"mismatches,omitempty"`
+}
+
+// StateCheckInfo is one registry value the evaluation looked at
+type StateCheckInfo struct {
+	Key       string `json:"key"`
+	ValueName string `json:"valueName"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Outcome   string `json:"outcome"`
+	Note      string `json:"note,omitempty"`
+}
+
+// StateMismatchInfo is a registry value that makes the state of a policy
+// Unknown. Actual is empty when the value is missing.
+type StateMismatchInfo struct {
+	Key       string `json:"key"`
+	ValueName string `json:"valueName"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual,omitempty"`
+}
+
+// SeeAlsoInfo is a related topic of a policy. URL is set when the text is
+// a web link.
+type SeeAlsoInfo struct {
+	Text string `json:"text"`
+	URL  string `json:"url,omitempty"`
+}
+
+// LayoutItem is one row of the policy dialog, in presentation order: either
+// a text label or the control of an element.
+type LayoutItem struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	ElementID string `json:"elementId,omitempty"`
 }
 
 // ElementInfo contains metadata for elements within a policy.
@@ -45,6 +281,39 @@ type ElementInfo struct {
 	MaxLength    *int                   `json:"maxLength,omitempty"`
 	Description  string                 `json:"description,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Control      string                 `json:"control,omitempty"`
+	Spinner      bool                   `json:"spinner,omitempty"`
+	SpinnerStep  uint32                 `json:"spinnerStep,omitempty"`
+	Suggestions  []string               `json:"suggestions,omitempty"`
+	DefaultItem  *int                   `json:"defaultItem,omitempty"`
+	NoSort       bool                   `json:"noSort,omitempty"`
+}
+
+// ClientExtensionInfo names a client-side extension. Name is empty for
+// extensions that are not well known.
+type ClientExtensionInfo struct {
+	GUID string `json:"guid"`
+	Name string `json:"name,omitempty"`
+}
+
+// ClientExtensionGroup lists the policies processed by a client-side
+// extension.
+type ClientExtensionGroup struct {
+	GUID        string   `json:"guid"`
+	Name        string   `json:"name,omitempty"`
+	Known       bool     `json:"known"`
+	PolicyCount int      `json:"policyCount"`
+	Policies    []string `json:"policies"`
+}
+
+// ProductNode represents a product, or a version of it, that supportedOn
+// definitions refer to.
+type ProductNode struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Version  int            `json:"version"`
+	Children []*ProductNode `json:"children"`
 }
 
 // EnumOptionInfo represents enum options.
@@ -61,13 +330,15 @@ type CategoriesResponse struct {
 
 // SearchResultItem represents a single policy search result.
 type SearchResultItem struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	Description  string `json:"description"`
-	State        string `json:"state"`
-	Section      string `json:"section"`
-	CategoryID   string `json:"categoryId"`
-	CategoryName string `json:"categoryName"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	State        string   `json:"state"`
+	Section      string   `json:"section"`
+	CategoryID   string   `json:"categoryId"`
+	CategoryName string   `json:"categoryName"`
+	Keywords     []string `json:"keywords,omitempty"`
+	Tattooing    bool     `json:"tattooing,omitempty"`
 }
 
 // SearchResponse represents the response with search results grouped by section.
@@ -77,3 +348,147 @@ type SearchResponse struct {
 	Query    string             `json:"query"`
 	Total    int                `json:"total"`
 }
+
+// ReloadResponse reports the result of a template reload.
+type ReloadResponse struct {
+	Success     bool     `json:"success"`
+	Message     string   `json:"message"`
+	Added       []string `json:"added"`
+	Removed     []string `json:"removed"`
+	Changed     []string `json:"changed"`
+	PolicyCount int      `json:"policyCount"`
+	Failures    int      `json:"failures"`
+	DurationMs  int64    `json:"durationMs"`
+}
+
+// OptionErrorInfo describes an option value rejected for one element.
+type OptionErrorInfo struct {
+	ElementID string `json:"elementId"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// AppliedDefaultInfo is a presentation default used for an omitted option.
+type AppliedDefaultInfo struct {
+	ElementID string      `json:"elementId"`
+	Value     interface{} `json:"value"`
+}
+
+// TemplatePackInfo describes an installed custom template pack.
+type TemplatePackInfo struct {
+	ID          string    `json:"id"`
+	Namespaces  []string  `json:"namespaces"`
+	Files       []string  `json:"files"`
+	Locales     []string  `json:"locales"`
+	Installed   time.Time `json:"installed"`
+	PolicyCount int       `json:"policyCount"`
+	Loaded      bool      `json:"loaded"`
+}
+
+// PackChangeResponse reports an installed or removed template pack and the
+// reload that followed.
+type PackChangeResponse struct {
+	Success  bool              `json:"success"`
+	Message  string            `json:"message"`
+	Error    string            `json:"error,omitempty"`
+	Pack     *TemplatePackInfo `json:"pack,omitempty"`
+	Replaces map[string]string `json:"replaces,omitempty"`
+	Reload   *ReloadResponse   `json:"reload,omitempty"`
+}
+
+// TemplateDiagnosticsResponse lists what went wrong while loading the
+// templates and what was loaded.
+type TemplateDiagnosticsResponse struct {
+	FailureCount int                   `json:"failureCount"`
+	Failures     []LoadFailureInfo     `json:"failures"`
+	Files        []TemplateFileSummary `json:"files"`
+	Stats        LoadStatsInfo         `json:"stats"`
+}
+
+// LoadFailureInfo describes a template that could not be loaded.
+type LoadFailureInfo struct {
+	Type    string `json:"type"`
+	File    string `json:"file"`
+	Info    string `json:"info,omitempty"`
+	Message string `json:"message"`
+}
+
+// TemplateFileSummary describes a loaded template file.
+type TemplateFileSummary struct {
+	Namespace     string            `json:"namespace"`
+	File          string            `json:"file"`
+	Root          string            `json:"root,omitempty"`
+	Legacy        bool              `json:"legacy"`
+	Revision      string            `json:"revision"`
+	Prefixes      map[string]string `json:"prefixes"`
+	AdmlFile      string            `json:"admlFile"`
+	Locale        string            `json:"locale,omitempty"`
+	Locales       []string          `json:"locales"`
+	PolicyCount   int               `json:"policyCount"`
+	CategoryCount int               `json:"categoryCount"`
+}
+
+// LoadStatsInfo summarizes the most recent template load.
+type LoadStatsInfo struct {
+	Roots      string `json:"roots"`
+	Files      int    `json:"files"`
+	Failures   int    `json:"failures"`
+	CacheHit   bool   `json:"cacheHit"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// NamespaceInfo describes which template file provides a namespace.
+type NamespaceInfo struct {
+	Namespace       string             `json:"namespace"`
+	File            string             `json:"file"`
+	Root            string             `json:"root"`
+	Revision        string             `json:"revision"`
+	SchemaVersion   string             `json:"schemaVersion,omitempty"`
+	MinAdmlRevision string             `json:"minAdmlRevision,omitempty"`
+	OutdatedAdmls   []OutdatedAdmlInfo `json:"outdatedAdmls"`
+	Overridden      []string           `json:"overridden"`
+}
+
+// OutdatedAdmlInfo describes an ADML older than its ADMX requires.
+type OutdatedAdmlInfo struct {
+	File     string `json:"file"`
+	Locale   string `json:"locale,omitempty"`
+	Revision string `json:"revision"`
+	Required string `json:"required"`
+}
+
+// LocaleInfo describes a loaded ADML locale.
+type LocaleInfo struct {
+	Code  string `json:"code"`
+	Files int    `json:"files"`
+}
+
+// LocalesResponse lists the loaded locales and the one used for the request.
+type LocalesResponse struct {
+	Locales []LocaleInfo `json:"locales"`
+	Current string       `json:"current"`
+}
+
+// StringFallbackInfo is a string resolved from a fallback locale. An empty
+// locale means the string is missing from every ADML.
+type StringFallbackInfo struct {
+	Code   string `json:"code"`
+	Locale string `json:"locale"`
+}
+
+// FileCoverage lists the fallbacks of one template.
+type FileCoverage struct {
+	File      string               `json:"file"`
+	Namespace string               `json:"namespace"`
+	Locale    string               `json:"locale"`
+	Total     int                  `json:"total"`
+	Fallbacks []StringFallbackInfo `json:"fallbacks"`
+}
+
+// CoverageResponse is the localization coverage report.
+type CoverageResponse struct {
+	Locale   string         `json:"locale"`
+	FellBack int            `json:"fellBack"`
+	Missing  int            `json:"missing"`
+	Files    []FileCoverage `json:"files"`
+}
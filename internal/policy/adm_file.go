@@ -0,0 +1,1020 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// admVersion is the template engine version reported to "#if version"
+// directives. Windows Vista and later report 5.
+const admVersion = 5
+
+// LoadAdmFile loads a legacy .adm template and converts it into the same
+// structures produced by LoadAdmxFile/LoadAdmlFile. The [strings] section of
+// the ADM becomes the string table of the returned AdmlFile, and each policy
+// with parts gets a presentation named after the policy.
+func LoadAdmFile(path string) (*AdmxFile, *AdmlFile, error) {
+	data, err := readTemplateFile(nil, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseAdm(data, path)
+}
+
+// LoadAdmFS loads a legacy .adm template from a file system
+func LoadAdmFS(fsys fs.FS, name string) (*AdmxFile, *AdmlFile, error) {
+	data, err := readTemplateFile(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseAdm(data, name)
+}
+
+func parseAdm(data []byte, path string) (*AdmxFile, *AdmlFile, error) {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	namespace := "ADM." + sanitizeAdmID(base)
+
+	admx := &AdmxFile{
+		SourceFile:             path,
+		AdmxNamespace:          namespace,
+		Prefixes:               map[string]string{sanitizeAdmID(base): namespace},
+		Products:               []*AdmxProduct{},
+		SupportedOnDefinitions: []*AdmxSupportDefinition{},
+		Categories:             []*AdmxCategory{},
+		Policies:               []*AdmxPolicy{},
+	}
+	adml := &AdmlFile{
+		SourceFile:        path,
+		DisplayName:       base,
+		StringTable:       make(map[string]string),
+		PresentationTable: make(map[string]*Presentation),
+	}
+
+	policyText, stringsText := splitAdmSections(decodeAdmText(data))
+
+	p := &admParser{
+		admx:       admx,
+		adml:       adml,
+		strings:    parseAdmStrings(stringsText),
+		categories: make(map[string]*AdmxCategory),
+		usedIDs:    make(map[string]struct{}),
+		supports:   make(map[string]*AdmxSupportDefinition),
+	}
+	tokens, err := tokenizeAdm(policyText)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.tokens = tokens
+
+	if err := p.parse(); err != nil {
+		return nil, nil, err
+	}
+	return admx, adml, nil
+}
+
+// decodeAdmText converts the raw bytes of an ADM file to a string. ADM files
+// are usually UTF-16LE with a BOM, but ANSI files are also common.
+func decodeAdmText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	// ANSI fallback; windows-1252 is what Western systems wrote
+	return string(decodeSingleByte(data, &windows1252))
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	chars := make([]uint16, len(data)/2)
+	for i := range chars {
+		if bigEndian {
+			chars[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			chars[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+	}
+	return string(utf16.Decode(chars))
+}
+
+// splitAdmSections separates the policy definitions from the [strings]
+// section. Any other bracketed section is ignored.
+func splitAdmSections(text string) (string, string) {
+	var policyLines, stringLines []string
+	current := &policyLines
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if strings.EqualFold(trimmed, "[strings]") {
+				current = &stringLines
+			} else {
+				current = nil
+			}
+			continue
+		}
+		if current != nil {
+			*current = append(*current, line)
+		}
+	}
+	return strings.Join(policyLines, "\n"), strings.Join(stringLines, "\n")
+}
+
+// parseAdmStrings parses name=value lines. Keys are stored in lower case
+// because ADM string references are case-insensitive.
+func parseAdmStrings(text string) map[string]admString {
+	result := make(map[string]admString)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if len(value) >= 2 && value[0] == '"' {
+			if end := strings.LastIndex(value, "\""); end > 0 {
+				value = value[1:end]
+			}
+			value = strings.ReplaceAll(value, `""`, `"`)
+		} else if semi := strings.Index(value, ";"); semi >= 0 {
+			value = strings.TrimSpace(value[:semi])
+		}
+		value = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(value)
+		result[strings.ToLower(name)] = admString{name: name, value: value}
+	}
+	return result
+}
+
+type admString struct {
+	name  string
+	value string
+}
+
+type admToken struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+// tokenizeAdm splits the policy section into tokens, dropping comments and
+// evaluating #if/#else/#endif directives.
+func tokenizeAdm(text string) ([]admToken, error) {
+	var tokens []admToken
+	var active []bool
+	isActive := func() bool {
+		for _, a := range active {
+			if !a {
+				return false
+			}
+		}
+		return true
+	}
+
+	for lineNo, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			fields := strings.Fields(strings.ToLower(trimmed))
+			switch fields[0] {
+			case "#if":
+				active = append(active, evalAdmCondition(fields[1:]))
+			case "#ifdef", "#ifndef":
+				active = append(active, fields[0] == "#ifndef")
+			case "#else":
+				if len(active) == 0 {
+					return nil, fmt.Errorf("line %d: #else without #if", lineNo+1)
+				}
+				active[len(active)-1] = !active[len(active)-1]
+			case "#endif":
+				if len(active) == 0 {
+					return nil, fmt.Errorf("line %d: #endif without #if", lineNo+1)
+				}
+				active = active[:len(active)-1]
+			}
+			continue
+		}
+		if !isActive() {
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			c := line[i]
+			switch {
+			case c == ';':
+				i = len(line)
+			case c == ' ' || c == '\t' || c == '\r':
+				i++
+			case c == '"':
+				end := strings.IndexByte(line[i+1:], '"')
+				if end < 0 {
+					return nil, fmt.Errorf("line %d: unterminated string", lineNo+1)
+				}
+				tokens = append(tokens, admToken{text: line[i+1 : i+1+end], quoted: true, line: lineNo + 1})
+				i += end + 2
+			default:
+				start := i
+				for i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' && line[i] != ';' && line[i] != '"' {
+					i++
+				}
+				tokens = append(tokens, admToken{text: line[start:i], line: lineNo + 1})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func evalAdmCondition(fields []string) bool {
+	if len(fields) != 3 || fields[0] != "version" {
+		return false
+	}
+	n, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return false
+	}
+	switch fields[1] {
+	case ">=":
+		return admVersion >= n
+	case ">":
+		return admVersion > n
+	case "<=":
+		return admVersion <= n
+	case "<":
+		return admVersion < n
+	case "==":
+		return admVersion == n
+	case "!=":
+		return admVersion != n
+	}
+	return false
+}
+
+func sanitizeAdmID(name string) string {
+	name = strings.TrimPrefix(name, "!!")
+	var sb strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	id := sb.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') || id[0] == '.' || id[0] == '-' {
+		id = "_" + id
+	}
+	return id
+}
+
+type admParser struct {
+	tokens     []admToken
+	pos        int
+	admx       *AdmxFile
+	adml       *AdmlFile
+	strings    map[string]admString
+	section    AdmxPolicySection
+	categories map[string]*AdmxCategory
+	usedIDs    map[string]struct{}
+	supports   map[string]*AdmxSupportDefinition
+	literals   int
+}
+
+func (p *admParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("ADM parse error (line %d): %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *admParser) next() (admToken, error) {
+	if p.pos >= len(p.tokens) {
+		return admToken{}, p.errorf("unexpected end of file")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, nil
+}
+
+func (p *admParser) peekKeyword() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return ""
+	}
+	return strings.ToUpper(p.tokens[p.pos].text)
+}
+
+func (p *admParser) expectKeyword(keyword string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.quoted || !strings.EqualFold(tok.text, keyword) {
+		return p.errorf("expected %s, got %q", keyword, tok.text)
+	}
+	return nil
+}
+
+func (p *admParser) nextString() (string, error) {
+	tok, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if !tok.quoted && strings.HasPrefix(tok.text, "!!") {
+		return p.lookupString(tok.text), nil
+	}
+	return tok.text, nil
+}
+
+// lookupString resolves a !!name reference to its literal text.
+func (p *admParser) lookupString(ref string) string {
+	if str, ok := p.strings[strings.ToLower(strings.TrimPrefix(ref, "!!"))]; ok {
+		return str.value
+	}
+	return ref
+}
+
+// nextDisplayCode reads a display name token and returns a $(string.X) code,
+// adding the string to the ADML string table.
+func (p *admParser) nextDisplayCode() (string, string, error) {
+	tok, err := p.next()
+	if err != nil {
+		return "", "", err
+	}
+	if !tok.quoted && strings.HasPrefix(tok.text, "!!") {
+		name := strings.TrimPrefix(tok.text, "!!")
+		if str, ok := p.strings[strings.ToLower(name)]; ok {
+			p.adml.StringTable[str.name] = str.value
+			return "$(string." + str.name + ")", str.name, nil
+		}
+		return "$(string." + name + ")", name, nil
+	}
+	p.literals++
+	id := fmt.Sprintf("ADM_Literal_%d", p.literals)
+	p.adml.StringTable[id] = tok.text
+	return "$(string." + id + ")", tok.text, nil
+}
+
+func (p *admParser) nextNumber() (uint32, error) {
+	tok, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(tok.text, 0, 32)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", tok.text)
+	}
+	return uint32(n), nil
+}
+
+func (p *admParser) nextValue() (*PolicyRegistryValue, error) {
+	switch p.peekKeyword() {
+	case "NUMERIC":
+		p.pos++
+		n, err := p.nextNumber()
+		if err != nil {
+			return nil, err
+		}
+		return &PolicyRegistryValue{RegistryType: Numeric, NumberValue: n}, nil
+	case "DELETE":
+		p.pos++
+		return &PolicyRegistryValue{RegistryType: Delete}, nil
+	}
+	str, err := p.nextString()
+	if err != nil {
+		return nil, err
+	}
+	return &PolicyRegistryValue{RegistryType: Text, StringValue: str}, nil
+}
+
+func (p *admParser) uniqueID(name string) string {
+	id := sanitizeAdmID(name)
+	candidate := id
+	for n := 2; ; n++ {
+		if _, used := p.usedIDs[candidate]; !used {
+			p.usedIDs[candidate] = struct{}{}
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", id, n)
+	}
+}
+
+func (p *admParser) parse() error {
+	p.section = Machine
+	for p.pos < len(p.tokens) {
+		switch kw := p.peekKeyword(); kw {
+		case "CLASS":
+			p.pos++
+			tok, err := p.next()
+			if err != nil {
+				return err
+			}
+			switch strings.ToUpper(tok.text) {
+			case "MACHINE":
+				p.section = Machine
+			case "USER":
+				p.section = User
+			default:
+				return p.errorf("unknown class %q", tok.text)
+			}
+		case "CATEGORY":
+			p.pos++
+			if err := p.parseCategory(nil, ""); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unexpected token %q", p.tokens[p.pos].text)
+		}
+	}
+	return nil
+}
+
+func (p *admParser) parseCategory(parent *AdmxCategory, inheritedKey string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	// The same category is usually declared under both CLASS MACHINE and
+	// CLASS USER; merge those declarations into one category.
+	parentID := ""
+	if parent != nil {
+		parentID = parent.ID
+	}
+	pathKey := parentID + "\\" + strings.ToLower(tok.text)
+	category, exists := p.categories[pathKey]
+	if !exists {
+		p.pos--
+		code, _, err := p.nextDisplayCode()
+		if err != nil {
+			return err
+		}
+		category = &AdmxCategory{
+			ID:          p.uniqueID(tok.text),
+			DisplayCode: code,
+			DefinedIn:   p.admx,
+		}
+		if parent != nil {
+			category.ParentID = parent.ID
+		}
+		p.categories[pathKey] = category
+		p.admx.Categories = append(p.admx.Categories, category)
+	}
+
+	key := inheritedKey
+	for {
+		kw := p.peekKeyword()
+		p.pos++
+		switch kw {
+		case "KEYNAME":
+			if key, err = p.nextString(); err != nil {
+				return err
+			}
+		case "EXPLAIN":
+			code, _, err := p.nextDisplayCode()
+			if err != nil {
+				return err
+			}
+			category.ExplainCode = code
+		case "CATEGORY":
+			if err := p.parseCategory(category, key); err != nil {
+				return err
+			}
+		case "POLICY":
+			if err := p.parsePolicy(category, key); err != nil {
+				return err
+			}
+		case "END":
+			return p.expectKeyword("CATEGORY")
+		default:
+			p.pos--
+			if p.pos >= len(p.tokens) {
+				return p.errorf("missing END CATEGORY")
+			}
+			return p.errorf("unexpected token %q in category", p.tokens[p.pos].text)
+		}
+	}
+}
+
+func (p *admParser) parsePolicy(category *AdmxCategory, inheritedKey string) error {
+	nameTok, err := p.next()
+	if err != nil {
+		return err
+	}
+	p.pos--
+	code, _, err := p.nextDisplayCode()
+	if err != nil {
+		return err
+	}
+
+	pol := &AdmxPolicy{
+		ID:             p.uniqueID(nameTok.text),
+		Section:        p.section,
+		CategoryID:     category.ID,
+		DisplayCode:    code,
+		RegistryKey:    inheritedKey,
+		AffectedValues: &PolicyRegistryList{},
+		DefinedIn:      p.admx,
+	}
+	pres := &Presentation{Name: pol.ID, Elements: []PresentationElement{}}
+	partIDs := make(map[string]struct{})
+
+	for {
+		kw := p.peekKeyword()
+		p.pos++
+		switch kw {
+		case "KEYNAME":
+			if pol.RegistryKey, err = p.nextString(); err != nil {
+				return err
+			}
+		case "VALUENAME":
+			if pol.RegistryValue, err = p.nextString(); err != nil {
+				return err
+			}
+		case "VALUEON":
+			if pol.AffectedValues.OnValue, err = p.nextValue(); err != nil {
+				return err
+			}
+		case "VALUEOFF":
+			if pol.AffectedValues.OffValue, err = p.nextValue(); err != nil {
+				return err
+			}
+		case "ACTIONLISTON":
+			if pol.AffectedValues.OnValueList, err = p.parseActionList("ACTIONLISTON"); err != nil {
+				return err
+			}
+		case "ACTIONLISTOFF":
+			if pol.AffectedValues.OffValueList, err = p.parseActionList("ACTIONLISTOFF"); err != nil {
+				return err
+			}
+		case "EXPLAIN":
+			if pol.ExplainCode, _, err = p.nextDisplayCode(); err != nil {
+				return err
+			}
+		case "SUPPORTED":
+			if pol.SupportedCode, err = p.supportDefinition(); err != nil {
+				return err
+			}
+		case "CLIENTEXT":
+			if pol.ClientExtension, err = p.nextString(); err != nil {
+				return err
+			}
+		case "PART":
+			elem, presElem, err := p.parsePart(partIDs)
+			if err != nil {
+				return err
+			}
+			if elem != nil {
+				pol.Elements = append(pol.Elements, elem)
+			}
+			pres.Elements = append(pres.Elements, presElem)
+		case "END":
+			if err := p.expectKeyword("POLICY"); err != nil {
+				return err
+			}
+			if len(pres.Elements) > 0 {
+				pol.PresentationID = "$(presentation." + pres.Name + ")"
+				p.adml.PresentationTable[pres.Name] = pres
+			}
+			p.admx.Policies = append(p.admx.Policies, pol)
+			return nil
+		default:
+			p.pos--
+			if p.pos >= len(p.tokens) {
+				return p.errorf("missing END POLICY")
+			}
+			return p.errorf("unexpected token %q in policy", p.tokens[p.pos].text)
+		}
+	}
+}
+
+// supportDefinition turns a SUPPORTED string into a support definition
+// without product references, which is all ADM can express.
+func (p *admParser) supportDefinition() (string, error) {
+	code, name, err := p.nextDisplayCode()
+	if err != nil {
+		return "", err
+	}
+	if sup, ok := p.supports[code]; ok {
+		return sup.ID, nil
+	}
+	sup := &AdmxSupportDefinition{
+		ID:          p.uniqueID("SUPPORTED_" + name),
+		DisplayCode: code,
+		Logic:       Blank,
+		Entries:     []*AdmxSupportEntry{},
+		DefinedIn:   p.admx,
+	}
+	p.supports[code] = sup
+	p.admx.SupportedOnDefinitions = append(p.admx.SupportedOnDefinitions, sup)
+	return sup.ID, nil
+}
+
+func (p *admParser) parseActionList(terminator string) (*PolicyRegistrySingleList, error) {
+	list := &PolicyRegistrySingleList{AffectedValues: []*PolicyRegistryListEntry{}}
+	key := ""
+	var entry *PolicyRegistryListEntry
+	for {
+		kw := p.peekKeyword()
+		p.pos++
+		switch kw {
+		case "KEYNAME":
+			var err error
+			if key, err = p.nextString(); err != nil {
+				return nil, err
+			}
+		case "VALUENAME":
+			name, err := p.nextString()
+			if err != nil {
+				return nil, err
+			}
+			entry = &PolicyRegistryListEntry{RegistryKey: key, RegistryValue: name}
+			list.AffectedValues = append(list.AffectedValues, entry)
+		case "VALUE":
+			if entry == nil {
+				return nil, p.errorf("VALUE without VALUENAME")
+			}
+			var err error
+			if entry.Value, err = p.nextValue(); err != nil {
+				return nil, err
+			}
+		case "END":
+			return list, p.expectKeyword(terminator)
+		default:
+			p.pos--
+			if p.pos >= len(p.tokens) {
+				return nil, p.errorf("missing END %s", terminator)
+			}
+			return nil, p.errorf("unexpected token %q in action list", p.tokens[p.pos].text)
+		}
+	}
+}
+
+// parsePart parses a PART block. Parts without KEYNAME keep an empty key so
+// they inherit the policy key. TEXT parts only produce a label and return a
+// nil policy element.
+func (p *admParser) parsePart(partIDs map[string]struct{}) (PolicyElement, PresentationElement, error) {
+	nameTok, err := p.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	label := nameTok.text
+	if !nameTok.quoted {
+		label = p.lookupString(nameTok.text)
+	}
+	typeTok, err := p.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	partType := strings.ToUpper(typeTok.text)
+
+	id := sanitizeAdmID(nameTok.text)
+	for n := 2; ; n++ {
+		if _, used := partIDs[id]; !used {
+			break
+		}
+		id = fmt.Sprintf("%s_%d", sanitizeAdmID(nameTok.text), n)
+	}
+	partIDs[id] = struct{}{}
+
+	base := BasePolicyElement{ID: id}
+
+	// Attributes shared by every part type
+	var (
+		minimum, maximum   uint32 = 0, 9999
+		spin               uint32 = 1
+		maxLen                    = 1023
+		defaultText        string
+		defaultNum         *uint32
+		defaultItem        *int
+		required, txtConv  bool
+		expandable, soft   bool
+		noSort, defChecked bool
+		additive, explicit bool
+		valuePrefix        *string
+		suggestions        []string
+		items              []*EnumPolicyElementItem
+		affected           = &PolicyRegistryList{}
+	)
+
+	for {
+		kw := p.peekKeyword()
+		p.pos++
+		switch kw {
+		case "KEYNAME":
+			if base.RegistryKey, err = p.nextString(); err != nil {
+				return nil, nil, err
+			}
+		case "VALUENAME":
+			if base.RegistryValue, err = p.nextString(); err != nil {
+				return nil, nil, err
+			}
+		case "CLIENTEXT":
+			if base.ClientExtension, err = p.nextString(); err != nil {
+				return nil, nil, err
+			}
+		case "MIN":
+			if minimum, err = p.nextNumber(); err != nil {
+				return nil, nil, err
+			}
+		case "MAX":
+			if maximum, err = p.nextNumber(); err != nil {
+				return nil, nil, err
+			}
+		case "SPIN":
+			if spin, err = p.nextNumber(); err != nil {
+				return nil, nil, err
+			}
+		case "MAXLEN":
+			n, err := p.nextNumber()
+			if err != nil {
+				return nil, nil, err
+			}
+			maxLen = int(n)
+		case "DEFAULT":
+			switch partType {
+			case "NUMERIC":
+				n, err := p.nextNumber()
+				if err != nil {
+					return nil, nil, err
+				}
+				defaultNum = &n
+			case "DROPDOWNLIST":
+				tok, err := p.next()
+				if err != nil {
+					return nil, nil, err
+				}
+				defaultItem = p.resolveDefaultItem(tok, items)
+			default:
+				if defaultText, err = p.nextString(); err != nil {
+					return nil, nil, err
+				}
+			}
+		case "VALUEON":
+			if affected.OnValue, err = p.nextValue(); err != nil {
+				return nil, nil, err
+			}
+		case "VALUEOFF":
+			if affected.OffValue, err = p.nextValue(); err != nil {
+				return nil, nil, err
+			}
+		case "ACTIONLISTON":
+			if affected.OnValueList, err = p.parseActionList("ACTIONLISTON"); err != nil {
+				return nil, nil, err
+			}
+		case "ACTIONLISTOFF":
+			if affected.OffValueList, err = p.parseActionList("ACTIONLISTOFF"); err != nil {
+				return nil, nil, err
+			}
+		case "ITEMLIST":
+			var listDefault *int
+			if items, listDefault, err = p.parseItemList(); err != nil {
+				return nil, nil, err
+			}
+			if listDefault != nil {
+				defaultItem = listDefault
+			}
+		case "SUGGESTIONS":
+			for p.peekKeyword() != "END" {
+				str, err := p.nextString()
+				if err != nil {
+					return nil, nil, err
+				}
+				suggestions = append(suggestions, str)
+			}
+			p.pos++
+			if err := p.expectKeyword("SUGGESTIONS"); err != nil {
+				return nil, nil, err
+			}
+		case "VALUEPREFIX":
+			prefix, err := p.nextString()
+			if err != nil {
+				return nil, nil, err
+			}
+			valuePrefix = &prefix
+		case "REQUIRED":
+			required = true
+		case "TXTCONVERT":
+			txtConv = true
+		case "EXPANDABLETEXT":
+			expandable = true
+		case "SOFT":
+			soft = true
+		case "NOSORT":
+			noSort = true
+		case "DEFCHECKED":
+			defChecked = true
+		case "ADDITIVE":
+			additive = true
+		case "EXPLICITVALUE":
+			explicit = true
+		case "OEMCONVERT":
+			// Character conversion hint only; nothing to store
+		case "END":
+			if err := p.expectKeyword("PART"); err != nil {
+				return nil, nil, err
+			}
+			return p.buildPart(partType, base, label, admPartOptions{
+				minimum: minimum, maximum: maximum, spin: spin, maxLen: maxLen,
+				defaultText: defaultText, defaultNum: defaultNum, defaultItem: defaultItem,
+				required: required, txtConv: txtConv, expandable: expandable, soft: soft,
+				noSort: noSort, defChecked: defChecked, additive: additive, explicit: explicit,
+				valuePrefix: valuePrefix, suggestions: suggestions, items: items, affected: affected,
+			})
+		default:
+			p.pos--
+			if p.pos >= len(p.tokens) {
+				return nil, nil, p.errorf("missing END PART")
+			}
+			return nil, nil, p.errorf("unexpected token %q in part", p.tokens[p.pos].text)
+		}
+	}
+}
+
+type admPartOptions struct {
+	minimum, maximum, spin uint32
+	maxLen                 int
+	defaultText            string
+	defaultNum             *uint32
+	defaultItem            *int
+	required, txtConv      bool
+	expandable, soft       bool
+	noSort, defChecked     bool
+	additive, explicit     bool
+	valuePrefix            *string
+	suggestions            []string
+	items                  []*EnumPolicyElementItem
+	affected               *PolicyRegistryList
+}
+
+func (p *admParser) buildPart(partType string, base BasePolicyElement, label string, o admPartOptions) (PolicyElement, PresentationElement, error) {
+	presBase := BasePresentationElement{ID: base.ID}
+
+	switch partType {
+	case "TEXT":
+		presBase.ID = ""
+		presBase.ElementType = "text"
+		return nil, &LabelPresentationElement{BasePresentationElement: presBase, Text: label}, nil
+	case "NUMERIC":
+		base.ElementType = "decimal"
+		presBase.ElementType = "decimalTextBox"
+		pres := &NumericBoxPresentationElement{
+			BasePresentationElement: presBase,
+			HasSpinner:              o.spin != 0,
+			SpinnerIncrement:        o.spin,
+			Label:                   label,
+		}
+		if o.defaultNum != nil {
+			pres.DefaultValue = *o.defaultNum
+		}
+		return &DecimalPolicyElement{
+			BasePolicyElement: base,
+			Required:          o.required,
+			Minimum:           o.minimum,
+			Maximum:           o.maximum,
+			StoreAsText:       o.txtConv,
+			NoOverwrite:       o.soft,
+		}, pres, nil
+	case "EDITTEXT", "COMBOBOX":
+		base.ElementType = "text"
+		elem := &TextPolicyElement{
+			BasePolicyElement: base,
+			Required:          o.required,
+			MaxLength:         o.maxLen,
+			RegExpandSz:       o.expandable,
+			NoOverwrite:       o.soft,
+		}
+		if partType == "COMBOBOX" {
+			presBase.ElementType = "comboBox"
+			return elem, &ComboBoxPresentationElement{
+				BasePresentationElement: presBase,
+				NoSort:                  o.noSort,
+				Label:                   label,
+				DefaultText:             o.defaultText,
+				Suggestions:             o.suggestions,
+			}, nil
+		}
+		presBase.ElementType = "textBox"
+		return elem, &TextBoxPresentationElement{
+			BasePresentationElement: presBase,
+			Label:                   label,
+			DefaultValue:            o.defaultText,
+		}, nil
+	case "CHECKBOX":
+		base.ElementType = "boolean"
+		presBase.ElementType = "checkBox"
+		return &BooleanPolicyElement{
+			BasePolicyElement: base,
+			AffectedRegistry:  o.affected,
+		}, &CheckBoxPresentationElement{
+			BasePresentationElement: presBase,
+			DefaultState:            o.defChecked,
+			Text:                    label,
+		}, nil
+	case "DROPDOWNLIST":
+		base.ElementType = "enum"
+		presBase.ElementType = "dropdownList"
+		return &EnumPolicyElement{
+			BasePolicyElement: base,
+			Required:          o.required,
+			Items:             o.items,
+		}, &DropDownPresentationElement{
+			BasePresentationElement: presBase,
+			NoSort:                  o.noSort,
+			DefaultItemID:           o.defaultItem,
+			Label:                   label,
+		}, nil
+	case "LISTBOX":
+		base.ElementType = "list"
+		presBase.ElementType = "listBox"
+		elem := &ListPolicyElement{
+			BasePolicyElement: base,
+			NoPurgeOthers:     o.additive,
+			RegExpandSz:       o.expandable,
+			UserProvidesNames: o.explicit,
+		}
+		if o.valuePrefix != nil {
+			elem.RegistryValue = *o.valuePrefix
+			elem.HasPrefix = *o.valuePrefix != ""
+		}
+		return elem, &ListPresentationElement{BasePresentationElement: presBase, Label: label}, nil
+	}
+	return nil, nil, p.errorf("unsupported part type %q", partType)
+}
+
+func (p *admParser) resolveDefaultItem(tok admToken, items []*EnumPolicyElementItem) *int {
+	if n, err := strconv.Atoi(tok.text); err == nil && !tok.quoted {
+		return &n
+	}
+	name := strings.TrimPrefix(tok.text, "!!")
+	for idx, item := range items {
+		if strings.EqualFold(item.DisplayCode, "$(string."+name+")") {
+			return &idx
+		}
+	}
+	return nil
+}
+
+// parseItemList reads the items of a drop-down list up to END ITEMLIST,
+// with the index of the item marked DEFAULT, if any
+func (p *admParser) parseItemList() ([]*EnumPolicyElementItem, *int, error) {
+	items := []*EnumPolicyElementItem{}
+	var item *EnumPolicyElementItem
+	var defaultItem *int
+	for {
+		kw := p.peekKeyword()
+		p.pos++
+		switch kw {
+		case "NAME":
+			code, _, err := p.nextDisplayCode()
+			if err != nil {
+				return nil, nil, err
+			}
+			item = &EnumPolicyElementItem{DisplayCode: code}
+			items = append(items, item)
+		case "VALUE":
+			if item == nil {
+				return nil, nil, p.errorf("VALUE without NAME")
+			}
+			var err error
+			if item.Value, err = p.nextValue(); err != nil {
+				return nil, nil, err
+			}
+		case "DEFAULT":
+			if item == nil {
+				return nil, nil, p.errorf("DEFAULT without NAME")
+			}
+			index := len(items) - 1
+			defaultItem = &index
+		case "ACTIONLIST":
+			if item == nil {
+				return nil, nil, p.errorf("ACTIONLIST without NAME")
+			}
+			var err error
+			if item.ValueList, err = p.parseActionList("ACTIONLIST"); err != nil {
+				return nil, nil, err
+			}
+		case "END":
+			return items, defaultItem, p.expectKeyword("ITEMLIST")
+		default:
+			p.pos--
+			if p.pos >= len(p.tokens) {
+				return nil, nil, p.errorf("missing END ITEMLIST")
+			}
+			return nil, nil, p.errorf("unexpected token %q in item list", p.tokens[p.pos].text)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const testAdm = `CLASS MACHINE
+CATEGORY !!CatMain
+  KEYNAME "Software\Policies\Vendor"
+  POLICY !!PolA
+    EXPLAIN !!PolA_Help
+    VALUENAME "A"
+    VALUEON NUMERIC 1
+    VALUEOFF NUMERIC 0
+    PART !!PartNum NUMERIC
+      VALUENAME "Num"
+      MIN 1 MAX 50 DEFAULT 5
+    END PART
+    PART !!Drop DROPDOWNLIST
+      VALUENAME "Mode"
+      ITEMLIST
+        NAME !!M1 VALUE NUMERIC 1
+        NAME !!M2 VALUE "two"
+      END ITEMLIST
+      DEFAULT 1
+    END PART
+    PART !!Level DROPDOWNLIST
+      VALUENAME "Level"
+      ITEMLIST
+        NAME !!M1 VALUE NUMERIC 1
+        NAME !!M2 VALUE NUMERIC 2 DEFAULT
+        NAME !!M3 VALUE NUMERIC 3
+      END ITEMLIST
+    END PART
+  END POLICY
+END CATEGORY
+CLASS USER
+CATEGORY !!CatMain
+  POLICY "Literal policy" ; comment
+    KEYNAME "Software\Policies\Vendor"
+    VALUENAME "B"
+    ACTIONLISTON
+      VALUENAME "X" VALUE NUMERIC 2
+    END ACTIONLISTON
+  END POLICY
+END CATEGORY
+[strings]
+CatMain="Vendor Settings"
+PolA="Policy A"
+PolA_Help="Line1\nLine2"
+PartNum="Number"
+Drop="Mode"
+Level="Level"
+M1=One
+M2="Two"
+M3="Three"
+`
+
+func TestParseAdm(t *testing.T) {
+	admx, adml, err := parseAdm([]byte(testAdm), "templates/vendor.adm")
+	if err != nil {
+		t.Fatalf("parseAdm: %v", err)
+	}
+	if admx.AdmxNamespace != "ADM.vendor" {
+		t.Errorf("namespace = %q, want ADM.vendor", admx.AdmxNamespace)
+	}
+	if len(admx.Policies) != 2 {
+		t.Fatalf("got %d policies, want 2", len(admx.Policies))
+	}
+
+	polA := admx.Policies[0]
+	if polA.Section != Machine || polA.RegistryKey != `Software\Policies\Vendor` || polA.RegistryValue != "A" {
+		t.Errorf("PolA = %v %q %q", polA.Section, polA.RegistryKey, polA.RegistryValue)
+	}
+	if len(polA.Elements) != 3 {
+		t.Fatalf("PolA has %d elements, want 3", len(polA.Elements))
+	}
+	num, ok := polA.Elements[0].(*DecimalPolicyElement)
+	if !ok || num.Minimum != 1 || num.Maximum != 50 {
+		t.Errorf("numeric part = %#v", polA.Elements[0])
+	}
+
+	pres := adml.PresentationTable[polA.ID]
+	if pres == nil || len(pres.Elements) != 3 {
+		t.Fatalf("presentation of PolA = %#v", pres)
+	}
+	if box := pres.Elements[0].(*NumericBoxPresentationElement); box.DefaultValue != 5 {
+		t.Errorf("numeric default = %d, want 5", box.DefaultValue)
+	}
+
+	// DEFAULT after the item list and on an item of the list
+	for i, want := range map[int]int{1: 1, 2: 1} {
+		drop := pres.Elements[i].(*DropDownPresentationElement)
+		if drop.DefaultItemID == nil || *drop.DefaultItemID != want {
+			t.Errorf("drop-down %d default = %v, want %d", i, drop.DefaultItemID, want)
+		}
+	}
+	if items := polA.Elements[2].(*EnumPolicyElement).Items; len(items) != 3 {
+		t.Errorf("level has %d items, want 3", len(items))
+	}
+
+	literal := admx.Policies[1]
+	if literal.Section != User || literal.AffectedValues.OnValueList == nil {
+		t.Errorf("literal policy = %v %#v", literal.Section, literal.AffectedValues)
+	}
+}
+
+func TestParseAdmErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		adm  string
+		want string
+	}{
+		{"missing end policy", "CLASS MACHINE\nCATEGORY \"C\"\nPOLICY \"P\"\nKEYNAME \"K\"\n", "missing END POLICY"},
+		{"unknown token in item list", "CLASS MACHINE\nCATEGORY \"C\"\nPOLICY \"P\"\nKEYNAME \"K\"\nPART \"D\" DROPDOWNLIST\nITEMLIST\nNAME \"A\" VALUE NUMERIC 1 BOGUS\nEND ITEMLIST\nEND PART\nEND POLICY\nEND CATEGORY\n", "unexpected token"},
+		{"default without item", "CLASS MACHINE\nCATEGORY \"C\"\nPOLICY \"P\"\nKEYNAME \"K\"\nPART \"D\" DROPDOWNLIST\nITEMLIST\nDEFAULT\nEND ITEMLIST\nEND PART\nEND POLICY\nEND CATEGORY\n", "DEFAULT without NAME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseAdm([]byte(tt.adm), "test.adm")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeAdmText(t *testing.T) {
+	utf16le := func(s string) []byte {
+		data := []byte{0xFF, 0xFE}
+		for _, c := range utf16.Encode([]rune(s)) {
+			data = append(data, byte(c), byte(c>>8))
+		}
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"utf-16le", utf16le("CLASS Ä"), "CLASS Ä"},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'O', 0, 'K'}, "OK"},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFÄ"), "Ä"},
+		{"windows-1252", []byte{'C', 0xC4}, "CÄ"},
+	}
+	for _, tt := range tests {
+		if got := decodeAdmText(tt.data); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,15 +3,16 @@ package policy
 import (
 	"encoding/xml"
 	"fmt"
-	"io"
-	"os"
+	"io/fs"
 	"strconv"
 )
 
 // AdmlFile ADML localization file
 type AdmlFile struct {
 	SourceFile        string
-	Revision          float64
+	Locale            string
+	Revision          Revision
+	SchemaVersion     string
 	DisplayName       string
 	Description       string
 	StringTable       map[string]string
@@ -21,7 +22,9 @@ type AdmlFile struct {
 // ADML XML structures
 type admlPolicyDefinitionResources struct {
 	XMLName           xml.Name               `xml:"policyDefinitionResources"`
-	Revision          string                 `xml:"revision,attr"`
+	Xmlns             string                 `xml:"xmlns,attr,omitempty"`
+	Revision          string                 `xml:"revision,attr,omitempty"`
+	SchemaVersion     string                 `xml:"schemaVersion,attr,omitempty"`
 	DisplayName       string                 `xml:"displayName"`
 	Description       string                 `xml:"description"`
 	StringTable       *admlStringTable       `xml:"resources>stringTable"`
@@ -33,7 +36,7 @@ type admlStringTable struct {
 }
 
 type admlString struct {
-	ID    string `xml:"id,attr"`
+	ID    string `xml:"id,attr,omitempty"`
 	Value string `xml:",chardata"`
 }
 
@@ -42,73 +45,51 @@ type admlPresentationTable struct {
 }
 
 type admlPresentation struct {
-	ID               string               `xml:"id,attr"`
-	Texts            []string             `xml:"text"`
-	DecimalTextBoxes []admlDecimalTextBox `xml:"decimalTextBox"`
-	TextBoxes        []admlTextBox        `xml:"textBox"`
-	CheckBoxes       []admlCheckBox       `xml:"checkBox"`
-	ComboBoxes       []admlComboBox       `xml:"comboBox"`
-	DropdownLists    []admlDropdownList   `xml:"dropdownList"`
-	ListBoxes        []admlListBox        `xml:"listBox"`
-	MultiTextBoxes   []admlMultiTextBox   `xml:"multiTextBox"`
+	ID       string                    `xml:"id,attr,omitempty"`
+	Elements []admlPresentationElement `xml:",any"`
 }
 
-type admlDecimalTextBox struct {
-	RefID        string `xml:"refId,attr"`
-	DefaultValue string `xml:"defaultValue,attr"`
-	Spin         string `xml:"spin,attr"`
-	SpinStep     string `xml:"spinStep,attr"`
-	Text         string `xml:",chardata"`
-}
-
-type admlTextBox struct {
-	RefID        string `xml:"refId,attr"`
-	Label        string `xml:"label"`
-	DefaultValue string `xml:"defaultValue"`
-}
-
-type admlCheckBox struct {
-	RefID          string `xml:"refId,attr"`
-	DefaultChecked string `xml:"defaultChecked,attr"`
-	Text           string `xml:",chardata"`
-}
-
-type admlComboBox struct {
-	RefID       string   `xml:"refId,attr"`
-	NoSort      string   `xml:"noSort,attr"`
-	Label       string   `xml:"label"`
-	Default     string   `xml:"default"`
-	Suggestions []string `xml:"suggestion"`
-}
-
-type admlDropdownList struct {
-	RefID       string `xml:"refId,attr"`
-	NoSort      string `xml:"noSort,attr"`
-	DefaultItem string `xml:"defaultItem,attr"`
-	Text        string `xml:",chardata"`
-}
-
-type admlListBox struct {
-	RefID string `xml:"refId,attr"`
-	Text  string `xml:",chardata"`
-}
-
-type admlMultiTextBox struct {
-	RefID string `xml:"refId,attr"`
-	Text  string `xml:",chardata"`
+// admlPresentationElement is any control of a presentation. Controls are
+// kept in one slice so their document order, including the text labels
+// between them, survives parsing. XMLName.Local tells the control type.
+type admlPresentationElement struct {
+	XMLName        xml.Name
+	RefID          string   `xml:"refId,attr,omitempty"`
+	DefaultValue   string   `xml:"defaultValue,attr,omitempty"`
+	Spin           string   `xml:"spin,attr,omitempty"`
+	SpinStep       string   `xml:"spinStep,attr,omitempty"`
+	DefaultChecked string   `xml:"defaultChecked,attr,omitempty"`
+	NoSort         string   `xml:"noSort,attr,omitempty"`
+	DefaultItem    string   `xml:"defaultItem,attr,omitempty"`
+	Label          string   `xml:"label,omitempty"`
+	DefaultText    string   `xml:"defaultValue,omitempty"`
+	Default        string   `xml:"default,omitempty"`
+	Suggestions    []string `xml:"suggestion"`
+	Text           string   `xml:",chardata"`
 }
 
 // LoadAdmlFile loads ADML file
 func LoadAdmlFile(path string) (*AdmlFile, error) {
-	file, err := os.Open(path)
+	data, err := readTemplateFile(nil, path)
+	if err != nil {
+		return nil, err
+	}
+	return parseAdml(data, path)
+}
+
+// LoadAdmlFS loads an ADML file from a file system
+func LoadAdmlFS(fsys fs.FS, name string) (*AdmlFile, error) {
+	data, err := readTemplateFile(fsys, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	return parseAdml(data, name)
+}
 
-	data, err := io.ReadAll(file)
+func parseAdml(data []byte, path string) (*AdmlFile, error) {
+	data, err := decodeXMLText(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
 
 	var policyResources admlPolicyDefinitionResources
@@ -125,8 +106,11 @@ func LoadAdmlFile(path string) (*AdmlFile, error) {
 	}
 
 	if policyResources.Revision != "" {
-		adml.Revision, _ = strconv.ParseFloat(policyResources.Revision, 64)
+		if adml.Revision, err = parseRevision(policyResources.Revision); err != nil {
+			return nil, err
+		}
 	}
+	adml.SchemaVersion = policyResources.SchemaVersion
 
 	// String table
 	if policyResources.StringTable != nil {
@@ -143,119 +127,10 @@ func LoadAdmlFile(path string) (*AdmlFile, error) {
 				Elements: []PresentationElement{},
 			}
 
-			// Text elements
-			for _, txt := range pres.Texts {
-				elem := &LabelPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ElementType: "text",
-					},
-					Text: txt,
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// DecimalTextBox elements
-			for _, dtb := range pres.DecimalTextBoxes {
-				elem := &NumericBoxPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          dtb.RefID,
-						ElementType: "decimalTextBox",
-					},
-					HasSpinner:       dtb.Spin != "false",
-					SpinnerIncrement: 1,
-					Label:            dtb.Text,
-				}
-				if dtb.DefaultValue != "" {
-					val, _ := strconv.ParseUint(dtb.DefaultValue, 10, 32)
-					elem.DefaultValue = uint32(val)
-				}
-				if dtb.SpinStep != "" {
-					step, _ := strconv.ParseUint(dtb.SpinStep, 10, 32)
-					elem.SpinnerIncrement = uint32(step)
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// TextBox elements
-			for _, tb := range pres.TextBoxes {
-				elem := &TextBoxPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          tb.RefID,
-						ElementType: "textBox",
-					},
-					Label:        tb.Label,
-					DefaultValue: tb.DefaultValue,
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// CheckBox elements
-			for _, cb := range pres.CheckBoxes {
-				elem := &CheckBoxPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          cb.RefID,
-						ElementType: "checkBox",
-					},
-					DefaultState: cb.DefaultChecked == "true",
-					Text:         cb.Text,
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// ComboBox elements
-			for _, cmb := range pres.ComboBoxes {
-				elem := &ComboBoxPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          cmb.RefID,
-						ElementType: "comboBox",
-					},
-					NoSort:      cmb.NoSort == "true",
-					Label:       cmb.Label,
-					DefaultText: cmb.Default,
-					Suggestions: cmb.Suggestions,
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// DropdownList elements
-			for _, ddl := range pres.DropdownLists {
-				elem := &DropDownPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          ddl.RefID,
-						ElementType: "dropdownList",
-					},
-					NoSort: ddl.NoSort == "true",
-					Label:  ddl.Text,
-				}
-				if ddl.DefaultItem != "" {
-					item, _ := strconv.Atoi(ddl.DefaultItem)
-					elem.DefaultItemID = &item
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// ListBox elements
-			for _, lb := range pres.ListBoxes {
-				elem := &ListPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          lb.RefID,
-						ElementType: "listBox",
-					},
-					Label: lb.Text,
-				}
-				presentation.Elements = append(presentation.Elements, elem)
-			}
-
-			// MultiTextBox elements
-			for _, mtb := range pres.MultiTextBoxes {
-				elem := &MultiTextPresentationElement{
-					BasePresentationElement: BasePresentationElement{
-						ID:          mtb.RefID,
-						ElementType: "multiTextBox",
-					},
-					Label: mtb.Text,
+			for _, child := range pres.Elements {
+				if elem := buildPresentationElement(child); elem != nil {
+					presentation.Elements = append(presentation.Elements, elem)
 				}
-				presentation.Elements = append(presentation.Elements, elem)
 			}
 
 			adml.PresentationTable[presentation.Name] = presentation
@@ -264,3 +139,98 @@ func LoadAdmlFile(path string) (*AdmlFile, error) {
 
 	return adml, nil
 }
+
+// buildPresentationElement converts one presentation control. Unknown
+// controls are skipped.
+func buildPresentationElement(child admlPresentationElement) PresentationElement {
+	switch child.XMLName.Local {
+	case "text":
+		return &LabelPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ElementType: "text",
+			},
+			Text: child.Text,
+		}
+	case "decimalTextBox":
+		elem := &NumericBoxPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "decimalTextBox",
+			},
+			// schema default of decimalTextBox/@defaultValue
+			DefaultValue:     1,
+			HasSpinner:       child.Spin != "false",
+			SpinnerIncrement: 1,
+			Label:            child.Text,
+		}
+		if child.DefaultValue != "" {
+			val, _ := strconv.ParseUint(child.DefaultValue, 10, 32)
+			elem.DefaultValue = uint32(val)
+		}
+		if child.SpinStep != "" {
+			step, _ := strconv.ParseUint(child.SpinStep, 10, 32)
+			elem.SpinnerIncrement = uint32(step)
+		}
+		return elem
+	case "textBox":
+		return &TextBoxPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "textBox",
+			},
+			Label:        child.Label,
+			DefaultValue: child.DefaultText,
+		}
+	case "checkBox":
+		return &CheckBoxPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "checkBox",
+			},
+			DefaultState: child.DefaultChecked == "true",
+			Text:         child.Text,
+		}
+	case "comboBox":
+		return &ComboBoxPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "comboBox",
+			},
+			NoSort:      child.NoSort == "true",
+			Label:       child.Label,
+			DefaultText: child.Default,
+			Suggestions: child.Suggestions,
+		}
+	case "dropdownList":
+		elem := &DropDownPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "dropdownList",
+			},
+			NoSort: child.NoSort == "true",
+			Label:  child.Text,
+		}
+		if child.DefaultItem != "" {
+			item, _ := strconv.Atoi(child.DefaultItem)
+			elem.DefaultItemID = &item
+		}
+		return elem
+	case "listBox":
+		return &ListPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "listBox",
+			},
+			Label: child.Text,
+		}
+	case "multiTextBox":
+		return &MultiTextPresentationElement{
+			BasePresentationElement: BasePresentationElement{
+				ID:          child.RefID,
+				ElementType: "multiTextBox",
+			},
+			Label: child.Text,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package policy
+
+import "sort"
+
+// AdmlRevisionIssue ADML whose revision is lower than the
+// minRequiredRevision of its ADMX
+type AdmlRevisionIssue struct {
+	Namespace string
+	AdmxPath  string
+	AdmlPath  string
+	Locale    string
+	Revision  Revision
+	Required  Revision
+}
+
+// AdmlRevisionIssues lists loaded ADMLs, of any locale, that do not satisfy
+// the resources minRequiredRevision of their ADMX. Such ADMLs are still used
+// but may lack strings or presentations the ADMX refers to.
+func (b *AdmxBundle) AdmlRevisionIssues() []AdmlRevisionIssue {
+	issues := []AdmlRevisionIssue{}
+	for ns, admx := range b.namespaces {
+		if admx.MinAdmlVersion.IsZero() {
+			continue
+		}
+
+		admls := make(map[*AdmlFile]string)
+		if adml := b.sourceFiles[admx]; adml != nil {
+			admls[adml] = adml.Locale
+		}
+		for locale, adml := range b.localizedFiles[admx] {
+			admls[adml] = locale
+		}
+
+		for adml, locale := range admls {
+			if compareRevision(adml.Revision, admx.MinAdmlVersion) >= 0 {
+				continue
+			}
+			issues = append(issues, AdmlRevisionIssue{
+				Namespace: ns,
+				AdmxPath:  admx.SourceFile,
+				AdmlPath:  adml.SourceFile,
+				Locale:    locale,
+				Revision:  adml.Revision,
+				Required:  admx.MinAdmlVersion,
+			})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Namespace != issues[j].Namespace {
+			return issues[i].Namespace < issues[j].Namespace
+		}
+		return issues[i].AdmlPath < issues[j].AdmlPath
+	})
+	return issues
+}
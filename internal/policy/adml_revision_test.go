@@ -0,0 +1,35 @@
+package policy
+
+import "testing"
+
+func TestAdmlRevisionIssues(t *testing.T) {
+	tests := []struct {
+		revision string
+		outdated bool
+	}{
+		{"1.10", false},
+		{"1.9", false},
+		{"1.2", true},
+	}
+	for _, tt := range tests {
+		b := NewAdmxBundle()
+		failures, err := b.LoadFS(revisionTemplates("1.0", tt.revision), ".", "en-US")
+		if err != nil || len(failures) > 0 {
+			t.Fatalf("LoadFS: %v %v", err, failures)
+		}
+		if issues := b.AdmlRevisionIssues(); (len(issues) > 0) != tt.outdated {
+			t.Errorf("ADML revision %s: issues = %+v, want outdated %v", tt.revision, issues, tt.outdated)
+		}
+	}
+}
+
+func TestMalformedRevision(t *testing.T) {
+	b := NewAdmxBundle()
+	failures, err := b.LoadFS(revisionTemplates("1.0", "1.x"), ".", "en-US")
+	if err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+	if len(failures) == 0 {
+		t.Errorf("ADML with revision 1.x loaded without failure")
+	}
+}
@@ -4,13 +4,20 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
 // AdmxBundle collection of ADMX files
 type AdmxBundle struct {
 	sourceFiles        map[*AdmxFile]*AdmlFile
+	localizedFiles     map[*AdmxFile]map[string]*AdmlFile
+	chains             map[*AdmxFile][]*AdmlFile
+	languageCodes      []string
 	namespaces         map[string]*AdmxFile
 	rawCategories      []*AdmxCategory
 	rawProducts        []*AdmxProduct
@@ -22,6 +29,15 @@ type AdmxBundle struct {
 	Products           map[string]*PolicyPlusProduct
 	Policies           map[string]*PolicyPlusPolicy
 	SupportDefinitions map[string]*PolicyPlusSupport
+	NamespaceSources   map[string]*NamespaceSource
+	conflictRule       NamespaceConflictRule
+	cacheDir           string
+	lastLoadStats      AdmxLoadStats
+	loadFailures       []*AdmxLoadFailure
+	viewsMu            sync.Mutex
+	views              map[string]*AdmxBundle
+	registryMu         sync.Mutex
+	registryIndex      *registryIndex
 }
 
 // AdmxLoadFailure loading error
@@ -41,6 +57,7 @@ const (
 	BadAdmlParse
 	BadAdml
 	DuplicateNamespace
+	BadAdmParse
 )
 
 func (f *AdmxLoadFailure) Error() string {
@@ -58,6 +75,8 @@ func (f *AdmxLoadFailure) Error() string {
 		msg += "ADML invalid: " + f.Info
 	case DuplicateNamespace:
 		msg += f.Info + " namespace already in use"
+	case BadAdmParse:
+		msg += "ADM could not be parsed: " + f.Info
 	default:
 		msg += "Unknown error"
 	}
@@ -68,6 +87,8 @@ func (f *AdmxLoadFailure) Error() string {
 func NewAdmxBundle() *AdmxBundle {
 	return &AdmxBundle{
 		sourceFiles:        make(map[*AdmxFile]*AdmlFile),
+		localizedFiles:     make(map[*AdmxFile]map[string]*AdmlFile),
+		chains:             make(map[*AdmxFile][]*AdmlFile),
 		namespaces:         make(map[string]*AdmxFile),
 		rawCategories:      []*AdmxCategory{},
 		rawProducts:        []*AdmxProduct{},
@@ -79,129 +100,446 @@ func NewAdmxBundle() *AdmxBundle {
 		Products:           make(map[string]*PolicyPlusProduct),
 		Policies:           make(map[string]*PolicyPlusPolicy),
 		SupportDefinitions: make(map[string]*PolicyPlusSupport),
+		NamespaceSources:   make(map[string]*NamespaceSource),
+		conflictRule:       HighestRevision,
 	}
 }
 
-// LoadFolder loads all ADMX files in a folder. languageCodes is a preference
-// list; the loader will try each locale (and their base languages) until a
-// matching ADML dosyası bulunur.
+// LoadFolder loads all ADMX and legacy ADM files in a folder. languageCodes
+// is a preference list; the loader will try each locale (and their base
+// languages) until a matching ADML dosyası bulunur. ADM files that an ADMX
+// declares as superseded are skipped.
 func (b *AdmxBundle) LoadFolder(path string, languageCodes ...string) ([]*AdmxLoadFailure, error) {
+	return b.LoadFolders([]string{path}, languageCodes...)
+}
+
+// LoadFolders loads several template roots in order, for example the local
+// store, a copy of the Central Store and vendor folders. A root may also be
+// a zip archive holding a template pack. When two files
+// define the same namespace, the bundle's NamespaceConflictRule picks one.
+//
+// Files are parsed concurrently, but they are staged in root and path order
+// so the result (including namespace conflicts) is deterministic. When a
+// cache folder is configured, an unchanged root is loaded from the cache
+// without parsing.
+func (b *AdmxBundle) LoadFolders(paths []string, languageCodes ...string) ([]*AdmxLoadFailure, error) {
+	roots := make([]*templateRoot, 0, len(paths))
+	defer func() {
+		for _, root := range roots {
+			root.Close()
+		}
+	}()
+	for _, path := range paths {
+		root, err := openTemplateRoot(path)
+		if err != nil {
+			return []*AdmxLoadFailure{}, err
+		}
+		roots = append(roots, root)
+	}
+	return b.loadRoots(roots, languageCodes)
+}
+
+// LoadFS loads all ADMX and legacy ADM files below dir of a file system,
+// such as an embed.FS holding a template pack. Paths in failures and
+// namespace sources are the slash separated names within fsys. The cache is
+// not used.
+func (b *AdmxBundle) LoadFS(fsys fs.FS, dir string, languageCodes ...string) ([]*AdmxLoadFailure, error) {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return []*AdmxLoadFailure{}, err
+	}
+	name := ""
+	if dir != "." {
+		name = dir
+	}
+	return b.loadRoots([]*templateRoot{{fsys: sub, name: name}}, languageCodes)
+}
+
+func (b *AdmxBundle) loadRoots(roots []*templateRoot, languageCodes []string) ([]*AdmxLoadFailure, error) {
 	if len(languageCodes) == 0 {
 		languageCodes = []string{"en-US"}
 	}
+	b.languageCodes = languageCodes
 
-	failures := []*AdmxLoadFailure{}
+	names := make([]string, len(roots))
+	for i, root := range roots {
+		names[i] = root.name
+	}
+	stats := AdmxLoadStats{Folder: strings.Join(names, ";"), CacheHit: len(roots) > 0}
+	start := time.Now()
+	var parsed []*parsedTemplate
 
-	err := filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+	for _, root := range roots {
+		scanStart := time.Now()
+		scan, err := scanTemplateFolder(root, languageCodes)
 		if err != nil {
-			return nil // Continue even if there is an error
+			return []*AdmxLoadFailure{}, err
 		}
-		if d.IsDir() {
-			return nil
+		stats.Files += len(scan.templates)
+		stats.ScanTime += time.Since(scanStart)
+
+		parseStart := time.Now()
+		var rootParsed []*parsedTemplate
+		hit := false
+		if root.cacheable {
+			rootParsed, hit = b.readCache(root.name, scan.fingerprint)
 		}
-		if strings.HasSuffix(strings.ToLower(filePath), ".admx") {
-			if fail := b.addSingleAdmx(filePath, languageCodes); fail != nil {
-				failures = append(failures, fail)
+		if !hit {
+			stats.CacheHit = false
+			workers := loadWorkerCount(len(scan.templates))
+			if workers > stats.Workers {
+				stats.Workers = workers
+			}
+			rootParsed = parseTemplates(root, scan.templates, languageCodes, workers)
+			if root.cacheable {
+				b.writeCache(root.name, scan.fingerprint, rootParsed)
 			}
 		}
-		return nil
-	})
-
-	if err != nil {
-		return failures, err
+		for _, t := range rootParsed {
+			t.Root = root.name
+		}
+		parsed = append(parsed, rootParsed...)
+		stats.ParseTime += time.Since(parseStart)
 	}
 
+	buildStart := time.Now()
+	failures := b.stageTemplates(parsed)
+	b.loadFailures = append(b.loadFailures, failures...)
 	b.buildStructures()
+	b.resetViews()
+	b.resetRegistryIndex()
+	stats.BuildTime = time.Since(buildStart)
+	stats.Failures = len(failures)
+	stats.TotalTime = time.Since(start)
+	b.lastLoadStats = stats
+
 	return failures, nil
 }
 
-// LoadFile loads a single ADMX file using the provided locale preference list.
+// LoadFile loads a single ADMX (or legacy ADM) file using the provided
+// locale preference list.
 func (b *AdmxBundle) LoadFile(path string, languageCodes ...string) ([]*AdmxLoadFailure, error) {
 	if len(languageCodes) == 0 {
 		languageCodes = []string{"en-US"}
 	}
+	b.languageCodes = languageCodes
 
+	dir := filepath.Dir(path)
+	root := &templateRoot{fsys: os.DirFS(dir), name: dir}
 	failures := []*AdmxLoadFailure{}
-	if fail := b.addSingleAdmx(path, languageCodes); fail != nil {
+	if fail := b.addParsed(parseTemplate(root, filepath.Base(path), languageCodes)); fail != nil {
 		failures = append(failures, fail)
 	}
+	b.loadFailures = append(b.loadFailures, failures...)
 	b.buildStructures()
+	b.resetViews()
+	b.resetRegistryIndex()
 	return failures, nil
 }
 
-func (b *AdmxBundle) addSingleAdmx(admxPath string, languageCodes []string) *AdmxLoadFailure {
+// LastLoadStats returns timing statistics of the most recent LoadFolder call.
+func (b *AdmxBundle) LastLoadStats() AdmxLoadStats {
+	return b.lastLoadStats
+}
+
+// parsedTemplate is the result of parsing one template file before it is
+// staged into a bundle.
+type parsedTemplate struct {
+	Path    string
+	Root    string
+	Admx    *AdmxFile
+	Adml    *AdmlFile
+	Admls   map[string]*AdmlFile
+	Failure *AdmxLoadFailure
+}
+
+func isAdmPath(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".adm")
+}
+
+// parseTemplate loads an ADMX file with its ADML, or a legacy ADM file, from
+// a template root. name is slash separated; reported paths are display
+// paths of the root. It does not touch the bundle and is safe to call
+// concurrently.
+func parseTemplate(root *templateRoot, name string, languageCodes []string) *parsedTemplate {
+	displayPath := root.displayPath(name)
+	result := &parsedTemplate{Path: displayPath}
+
+	if isAdmPath(name) {
+		admx, adml, err := LoadAdmFS(root.fsys, name)
+		if err != nil {
+			result.Failure = &AdmxLoadFailure{
+				FailType: BadAdmParse,
+				AdmxPath: displayPath,
+				Info:     err.Error(),
+			}
+			return result
+		}
+		admx.SourceFile, adml.SourceFile = displayPath, displayPath
+		result.Admx, result.Adml = admx, adml
+		return result
+	}
+
 	// Load ADMX
-	admx, err := LoadAdmxFile(admxPath)
+	admx, err := LoadAdmxFS(root.fsys, name)
 	if err != nil {
-		return &AdmxLoadFailure{
+		result.Failure = &AdmxLoadFailure{
 			FailType: BadAdmxParse,
-			AdmxPath: admxPath,
+			AdmxPath: displayPath,
 			Info:     err.Error(),
 		}
+		return result
 	}
+	admx.SourceFile = displayPath
+	result.Admx = admx
 
-	// Check namespace
-	if _, exists := b.namespaces[admx.AdmxNamespace]; exists {
-		return &AdmxLoadFailure{
-			FailType: DuplicateNamespace,
-			AdmxPath: admxPath,
-			Info:     admx.AdmxNamespace,
+	dir, admxFileName := path.Split(name)
+	dir = path.Clean(dir)
+	admlName, err := resolveAdmlPath(root.fsys, dir, admxFileName, languageCodes)
+	if err != nil {
+		result.Failure = &AdmxLoadFailure{
+			FailType: NoAdml,
+			AdmxPath: displayPath,
+			Info:     err.Error(),
 		}
+		return result
 	}
 
-	admlPath, err := resolveAdmlPath(filepath.Dir(admxPath), filepath.Base(admxPath), languageCodes)
+	// Load ADML
+	adml, err := LoadAdmlFS(root.fsys, admlName)
 	if err != nil {
-		return &AdmxLoadFailure{
-			FailType: NoAdml,
-			AdmxPath: admxPath,
+		result.Failure = &AdmxLoadFailure{
+			FailType: BadAdmlParse,
+			AdmxPath: displayPath,
 			Info:     err.Error(),
 		}
+		return result
+	}
+	adml.SourceFile = root.displayPath(admlName)
+	if admlDir := path.Dir(admlName); !strings.EqualFold(admlDir, dir) {
+		adml.Locale = path.Base(admlDir)
 	}
+	result.Adml = adml
+	result.Admls = loadLocalizedAdmls(root, dir, admxFileName, adml)
+	return result
+}
 
-	// Check ADML
-	if _, err := os.Stat(admlPath); os.IsNotExist(err) {
-		return &AdmxLoadFailure{
-			FailType: NoAdml,
-			AdmxPath: admxPath,
+// loadLocalizedAdmls loads the ADML of every locale folder next to an ADMX
+// file, keyed by folder name. The already loaded ADML is reused.
+func loadLocalizedAdmls(root *templateRoot, dir string, admxFileName string, loaded *AdmlFile) map[string]*AdmlFile {
+	base := strings.TrimSuffix(admxFileName, path.Ext(admxFileName)) + ".adml"
+	result := make(map[string]*AdmlFile)
+
+	entries, err := fs.ReadDir(root.fsys, dir)
+	if err != nil {
+		return result
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if loaded.Locale != "" && strings.EqualFold(entry.Name(), loaded.Locale) {
+			result[entry.Name()] = loaded
+			continue
 		}
+		localeName, ok := findPath(root.fsys, path.Join(dir, entry.Name()), base)
+		if !ok {
+			continue
+		}
+		adml, err := LoadAdmlFS(root.fsys, localeName)
+		if err != nil {
+			continue
+		}
+		adml.SourceFile = root.displayPath(localeName)
+		adml.Locale = entry.Name()
+		result[entry.Name()] = adml
 	}
+	return result
+}
 
-	// Load ADML
-	adml, err := LoadAdmlFile(admlPath)
-	if err != nil {
+// parseTemplates parses the given files of a root with a bounded worker
+// pool. Results keep the order of names.
+func parseTemplates(root *templateRoot, names []string, languageCodes []string, workers int) []*parsedTemplate {
+	results := make([]*parsedTemplate, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = parseTemplate(root, names[idx], languageCodes)
+			}
+		}()
+	}
+	for idx := range names {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func loadWorkerCount(files int) int {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > files {
+		workers = files
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// stageTemplates resolves namespace conflicts between the parsed ADMX
+// files, stages the chosen ones and then the ADM files that are not
+// superseded by one of them.
+func (b *AdmxBundle) stageTemplates(parsed []*parsedTemplate) []*AdmxLoadFailure {
+	failures := []*AdmxLoadFailure{}
+	var adms []*parsedTemplate
+
+	chosen := make(map[string]*parsedTemplate)
+	overridden := make(map[string][]string)
+	var candidates []*parsedTemplate
+
+	for _, t := range parsed {
+		if isAdmPath(t.Path) {
+			adms = append(adms, t)
+			continue
+		}
+		if t.Failure != nil || b.namespaces[t.Admx.AdmxNamespace] != nil {
+			// Failed files never win a conflict, and namespaces of earlier
+			// loads are already staged
+			if fail := b.addParsed(t); fail != nil {
+				failures = append(failures, fail)
+			}
+			continue
+		}
+
+		ns := t.Admx.AdmxNamespace
+		current, exists := chosen[ns]
+		if !exists {
+			chosen[ns] = t
+			candidates = append(candidates, t)
+			continue
+		}
+
+		switch b.conflictRule {
+		case ConflictError:
+			failures = append(failures, &AdmxLoadFailure{
+				FailType: DuplicateNamespace,
+				AdmxPath: t.Path,
+				Info:     ns,
+			})
+		case HighestRevision:
+			if compareRevision(t.Admx.Revision, current.Admx.Revision) > 0 {
+				chosen[ns] = t
+				candidates = append(candidates, t)
+				overridden[ns] = append(overridden[ns], current.Path)
+			} else {
+				overridden[ns] = append(overridden[ns], t.Path)
+			}
+		default:
+			overridden[ns] = append(overridden[ns], t.Path)
+		}
+	}
+
+	for _, t := range candidates {
+		if chosen[t.Admx.AdmxNamespace] != t {
+			continue
+		}
+		b.stage(t)
+		b.NamespaceSources[t.Admx.AdmxNamespace].Overridden = overridden[t.Admx.AdmxNamespace]
+	}
+
+	superseded := b.supersededAdms()
+	for _, t := range adms {
+		if _, ok := superseded[strings.ToLower(filepath.Base(t.Path))]; ok {
+			continue
+		}
+		if fail := b.addParsed(t); fail != nil {
+			failures = append(failures, fail)
+		}
+	}
+	return failures
+}
+
+// addParsed stages a parsed template after checking its namespace.
+func (b *AdmxBundle) addParsed(t *parsedTemplate) *AdmxLoadFailure {
+	if t.Failure != nil && t.Admx == nil {
+		return t.Failure
+	}
+
+	// Check namespace
+	if _, exists := b.namespaces[t.Admx.AdmxNamespace]; exists {
 		return &AdmxLoadFailure{
-			FailType: BadAdmlParse,
-			AdmxPath: admxPath,
-			Info:     err.Error(),
+			FailType: DuplicateNamespace,
+			AdmxPath: t.Path,
+			Info:     t.Admx.AdmxNamespace,
 		}
 	}
 
-	// Stage for building
+	if t.Failure != nil {
+		return t.Failure
+	}
+
+	b.stage(t)
+	return nil
+}
+
+// stage queues a loaded file for the next buildStructures call and records
+// it as the source of its namespace.
+func (b *AdmxBundle) stage(t *parsedTemplate) {
+	admx, adml := t.Admx, t.Adml
 	b.rawCategories = append(b.rawCategories, admx.Categories...)
 	b.rawProducts = append(b.rawProducts, admx.Products...)
 	b.rawPolicies = append(b.rawPolicies, admx.Policies...)
 	b.rawSupport = append(b.rawSupport, admx.SupportedOnDefinitions...)
 	b.sourceFiles[admx] = adml
+	if len(t.Admls) > 0 {
+		b.localizedFiles[admx] = t.Admls
+	}
 	b.namespaces[admx.AdmxNamespace] = admx
+	b.NamespaceSources[admx.AdmxNamespace] = &NamespaceSource{
+		Namespace:       admx.AdmxNamespace,
+		File:            t.Path,
+		Root:            t.Root,
+		Revision:        admx.Revision,
+		SchemaVersion:   admx.SchemaVersion,
+		MinAdmlRevision: admx.MinAdmlVersion,
+	}
+}
 
-	return nil
+// supersededAdms returns the lower-case ADM file names replaced by loaded
+// ADMX files.
+func (b *AdmxBundle) supersededAdms() map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, admx := range b.namespaces {
+		if admx.SupersededAdm != "" {
+			result[strings.ToLower(filepath.Base(admx.SupersededAdm))] = struct{}{}
+		}
+	}
+	return result
 }
 
-func resolveAdmlPath(dir string, admxFileName string, languageCodes []string) (string, error) {
-	base := strings.TrimSuffix(admxFileName, ".admx") + ".adml"
+// resolveAdmlPath finds the ADML of an ADMX file below dir of fsys. Names
+// are matched case-insensitively, as Windows does.
+func resolveAdmlPath(fsys fs.FS, dir string, admxFileName string, languageCodes []string) (string, error) {
+	base := strings.TrimSuffix(admxFileName, path.Ext(admxFileName)) + ".adml"
 
 	// Some OEM images keep ADMLs next to ADMX files.
-	directPath := filepath.Join(dir, base)
-	if _, err := os.Stat(directPath); err == nil {
+	if directPath, ok := findPath(fsys, dir, base); ok {
 		return directPath, nil
 	}
 
 	candidateDirs := []string{dir}
-	entries, err := os.ReadDir(dir)
+	entries, err := fs.ReadDir(fsys, dir)
 	if err == nil {
 		for _, entry := range entries {
 			if entry.IsDir() {
-				candidateDirs = append(candidateDirs, filepath.Join(dir, entry.Name()))
+				candidateDirs = append(candidateDirs, path.Join(dir, entry.Name()))
 			}
 		}
 	}
@@ -210,27 +548,24 @@ func resolveAdmlPath(dir string, admxFileName string, languageCodes []string) (s
 	candidates := expandLocaleCandidates(languageCodes)
 
 	for _, candidateDir := range candidateDirs {
-		localPath := filepath.Join(candidateDir, base)
-		if _, err := os.Stat(localPath); err == nil {
+		if localPath, ok := findPath(fsys, candidateDir, base); ok {
 			return localPath, nil
 		}
 
 		for _, locale := range candidates {
-			localePath := filepath.Join(candidateDir, locale, base)
-			key := strings.ToLower(localePath)
+			key := strings.ToLower(path.Join(candidateDir, locale, base))
 			if _, ok := tried[key]; ok {
 				continue
 			}
 			tried[key] = struct{}{}
-			if _, err := os.Stat(localePath); err == nil {
+			if localePath, ok := findPath(fsys, candidateDir, locale, base); ok {
 				return localePath, nil
 			}
 		}
 	}
 
 	// final fallback to en-US regardless of duplicates
-	fallback := filepath.Join(dir, "en-US", base)
-	if _, err := os.Stat(fallback); err == nil {
+	if fallback, ok := findPath(fsys, dir, "en-US", base); ok {
 		return fallback, nil
 	}
 
@@ -264,6 +599,12 @@ func expandLocaleCandidates(languageCodes []string) []string {
 }
 
 func (b *AdmxBundle) buildStructures() {
+	for admx := range b.sourceFiles {
+		if _, ok := b.chains[admx]; !ok {
+			b.chains[admx] = b.buildAdmlChain(admx)
+		}
+	}
+
 	catIds := make(map[string]*PolicyPlusCategory)
 	productIds := make(map[string]*PolicyPlusProduct)
 	supIds := make(map[string]*PolicyPlusSupport)
@@ -274,6 +615,8 @@ func (b *AdmxBundle) buildStructures() {
 		cat := &PolicyPlusCategory{
 			DisplayName:        b.resolveString(rawCat.DisplayCode, rawCat.DefinedIn),
 			DisplayExplanation: b.resolveString(rawCat.ExplainCode, rawCat.DefinedIn),
+			SeeAlso:            b.resolveStrings(rawCat.SeeAlso, rawCat.DefinedIn),
+			Keywords:           b.resolveKeywords(rawCat.Keywords, rawCat.DefinedIn),
 			UniqueID:           b.qualifyName(rawCat.ID, rawCat.DefinedIn),
 			RawCategory:        rawCat,
 			Children:           []*PolicyPlusCategory{},
@@ -314,6 +657,8 @@ func (b *AdmxBundle) buildStructures() {
 		pol := &PolicyPlusPolicy{
 			DisplayExplanation: b.resolveString(rawPol.ExplainCode, rawPol.DefinedIn),
 			DisplayName:        b.resolveString(rawPol.DisplayCode, rawPol.DefinedIn),
+			SeeAlso:            b.resolveStrings(rawPol.SeeAlso, rawPol.DefinedIn),
+			Keywords:           b.resolveKeywords(rawPol.Keywords, rawPol.DefinedIn),
 			UniqueID:           b.qualifyName(rawPol.ID, rawPol.DefinedIn),
 			RawPolicy:          rawPol,
 		}
@@ -421,12 +766,38 @@ func (b *AdmxBundle) resolveString(displayCode string, admx *AdmxFile) string {
 		return displayCode
 	}
 	stringID := displayCode[9 : len(displayCode)-1]
-	if adml, ok := b.sourceFiles[admx]; ok {
-		if str, ok := adml.StringTable[stringID]; ok {
-			return str
+	if str, _, ok := b.lookupString(stringID, admx); ok {
+		return str
+	}
+	// Better the bare ID than the raw reference
+	return stringID
+}
+
+// resolveStrings resolves a list of texts that may be string references
+func (b *AdmxBundle) resolveStrings(codes []string, admx *AdmxFile) []string {
+	var result []string
+	for _, code := range codes {
+		if text := strings.TrimSpace(b.resolveString(code, admx)); text != "" {
+			result = append(result, text)
 		}
 	}
-	return displayCode
+	return result
+}
+
+// resolveKeywords resolves keyword texts and splits them at commas,
+// semicolons and line breaks
+func (b *AdmxBundle) resolveKeywords(codes []string, admx *AdmxFile) []string {
+	var result []string
+	for _, text := range b.resolveStrings(codes, admx) {
+		for _, keyword := range strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ';' || r == '\n' || r == '\r'
+		}) {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				result = append(result, keyword)
+			}
+		}
+	}
+	return result
 }
 
 // ResolveString resolves a string code from ADML string table (public method)
@@ -439,7 +810,7 @@ func (b *AdmxBundle) resolvePresentation(displayCode string, admx *AdmxFile) *Pr
 		return nil
 	}
 	presID := displayCode[15 : len(displayCode)-1]
-	if adml, ok := b.sourceFiles[admx]; ok {
+	for _, adml := range b.chains[admx] {
 		if pres, ok := adml.PresentationTable[presID]; ok {
 			return pres
 		}
@@ -0,0 +1,522 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheFormatVersion is part of every cache key; bump it whenever the
+// parsed structures change shape or templates are decoded differently,
+// since failed files are cached too.
+const cacheFormatVersion = 8
+
+// AdmxLoadStats timing statistics of a folder load
+type AdmxLoadStats struct {
+	Folder    string
+	Files     int
+	Failures  int
+	Workers   int
+	CacheHit  bool
+	ScanTime  time.Duration
+	ParseTime time.Duration
+	BuildTime time.Duration
+	TotalTime time.Duration
+}
+
+func (s AdmxLoadStats) String() string {
+	source := fmt.Sprintf("parsed with %d workers", s.Workers)
+	if s.CacheHit {
+		source = "loaded from cache"
+	}
+	return fmt.Sprintf("%s: %d files (%d failed) %s in %v (scan %v, parse %v, build %v)",
+		s.Folder, s.Files, s.Failures, source,
+		s.TotalTime.Round(time.Millisecond), s.ScanTime.Round(time.Millisecond),
+		s.ParseTime.Round(time.Millisecond), s.BuildTime.Round(time.Millisecond))
+}
+
+// EnableCache stores parsed folders under dir so that unchanged folders are
+// not parsed again on the next start. An empty dir disables the cache.
+func (b *AdmxBundle) EnableCache(dir string) {
+	b.cacheDir = dir
+}
+
+// templateScan template files of a folder and their fingerprint
+type templateScan struct {
+	templates   []string
+	fingerprint string
+}
+
+// scanTemplateFolder lists the .admx and .adm files of a root in path
+// order. The fingerprint covers every template and language file (path,
+// size and modification time) together with the requested locales.
+func scanTemplateFolder(root *templateRoot, languageCodes []string) (*templateScan, error) {
+	scan := &templateScan{}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d|%s\n", cacheFormatVersion, strings.Join(languageCodes, ","))
+
+	var entries []string
+	err := fs.WalkDir(root.fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Continue even if there is an error
+		}
+		if d.IsDir() {
+			// Hidden folders hold staged packs and version control data
+			if filePath != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		lowerPath := strings.ToLower(filePath)
+		switch {
+		case strings.HasSuffix(lowerPath, ".admx"), strings.HasSuffix(lowerPath, ".adm"):
+			scan.templates = append(scan.templates, filePath)
+		case !strings.HasSuffix(lowerPath, ".adml"):
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, fmt.Sprintf("%s|%d|%d", filePath, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(scan.templates)
+	sort.Strings(entries)
+	for _, entry := range entries {
+		fmt.Fprintln(hash, entry)
+	}
+	scan.fingerprint = hex.EncodeToString(hash.Sum(nil))
+	return scan, nil
+}
+
+// cachePath returns the cache file of a folder, one file per folder.
+func (b *AdmxBundle) cachePath(folder string) string {
+	if abs, err := filepath.Abs(folder); err == nil {
+		folder = abs
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(folder)))
+	return filepath.Join(b.cacheDir, "bundle-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// readCache returns the cached parse results of a folder when the
+// fingerprint still matches.
+func (b *AdmxBundle) readCache(folder, fingerprint string) ([]*parsedTemplate, bool) {
+	if b.cacheDir == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(b.cachePath(folder))
+	if err != nil {
+		return nil, false
+	}
+
+	var cache cachedBundle
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Fingerprint != fingerprint {
+		return nil, false
+	}
+
+	parsed := make([]*parsedTemplate, 0, len(cache.Templates))
+	for _, t := range cache.Templates {
+		result, err := t.restore()
+		if err != nil {
+			return nil, false
+		}
+		parsed = append(parsed, result)
+	}
+	return parsed, true
+}
+
+// writeCache stores parse results. Errors are ignored; the cache is only an
+// optimization.
+func (b *AdmxBundle) writeCache(folder, fingerprint string, parsed []*parsedTemplate) {
+	if b.cacheDir == "" {
+		return
+	}
+
+	cache := cachedBundle{Fingerprint: fingerprint}
+	for _, t := range parsed {
+		entry, err := newCachedTemplate(t)
+		if err != nil {
+			return
+		}
+		cache.Templates = append(cache.Templates, entry)
+	}
+
+	data, err := json.Marshal(&cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(b.cacheDir, 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so a crash never leaves a torn cache
+	target := b.cachePath(folder)
+	tmp, err := os.CreateTemp(b.cacheDir, "bundle-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Cache file structures. AdmxFile is cyclic (DefinedIn, Parent) and holds
+// interface slices, so it is flattened before encoding.
+type cachedBundle struct {
+	Fingerprint string
+	Templates   []*cachedTemplate
+}
+
+type cachedTemplate struct {
+	Path    string
+	Admx    *cachedAdmxFile            `json:",omitempty"`
+	Adml    *cachedAdmlFile            `json:",omitempty"`
+	Admls   map[string]*cachedAdmlFile `json:",omitempty"`
+	Failure *AdmxLoadFailure           `json:",omitempty"`
+}
+
+type cachedAdmxFile struct {
+	SourceFile             string
+	AdmxNamespace          string
+	SupersededAdm          string
+	Revision               Revision
+	SchemaVersion          string
+	MinAdmlVersion         Revision
+	Prefixes               map[string]string
+	Products               []*cachedProduct
+	SupportedOnDefinitions []*AdmxSupportDefinition
+	Categories             []*AdmxCategory
+	Policies               []*cachedPolicy
+}
+
+type cachedProduct struct {
+	ID          string
+	DisplayCode string
+	Type        AdmxProductType
+	Version     int
+	ParentIndex int
+}
+
+type cachedPolicy struct {
+	AdmxPolicy
+	Elements []*cachedElement
+}
+
+type cachedAdmlFile struct {
+	SourceFile        string
+	Locale            string
+	Revision          Revision
+	SchemaVersion     string
+	DisplayName       string
+	Description       string
+	StringTable       map[string]string
+	PresentationTable map[string]*cachedPresentation
+}
+
+type cachedPresentation struct {
+	Name     string
+	Elements []*cachedElement
+}
+
+// cachedElement policy or presentation element tagged with its type
+type cachedElement struct {
+	Type string
+	Data json.RawMessage
+}
+
+func newCachedElement(elementType string, elem interface{}) (*cachedElement, error) {
+	data, err := json.Marshal(elem)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedElement{Type: elementType, Data: data}, nil
+}
+
+func newCachedTemplate(t *parsedTemplate) (*cachedTemplate, error) {
+	entry := &cachedTemplate{Path: t.Path, Failure: t.Failure}
+
+	if t.Admx != nil {
+		admx := &cachedAdmxFile{
+			SourceFile:     t.Admx.SourceFile,
+			AdmxNamespace:  t.Admx.AdmxNamespace,
+			SupersededAdm:  t.Admx.SupersededAdm,
+			Revision:       t.Admx.Revision,
+			SchemaVersion:  t.Admx.SchemaVersion,
+			MinAdmlVersion: t.Admx.MinAdmlVersion,
+			Prefixes:       t.Admx.Prefixes,
+		}
+
+		productIndex := make(map[*AdmxProduct]int)
+		for i, product := range t.Admx.Products {
+			productIndex[product] = i
+		}
+		for _, product := range t.Admx.Products {
+			parent := -1
+			if idx, ok := productIndex[product.Parent]; ok && product.Parent != nil {
+				parent = idx
+			}
+			admx.Products = append(admx.Products, &cachedProduct{
+				ID:          product.ID,
+				DisplayCode: product.DisplayCode,
+				Type:        product.Type,
+				Version:     product.Version,
+				ParentIndex: parent,
+			})
+		}
+		for _, support := range t.Admx.SupportedOnDefinitions {
+			copied := *support
+			copied.DefinedIn = nil
+			admx.SupportedOnDefinitions = append(admx.SupportedOnDefinitions, &copied)
+		}
+		for _, category := range t.Admx.Categories {
+			copied := *category
+			copied.DefinedIn = nil
+			admx.Categories = append(admx.Categories, &copied)
+		}
+		for _, pol := range t.Admx.Policies {
+			copied := &cachedPolicy{AdmxPolicy: *pol}
+			copied.AdmxPolicy.DefinedIn = nil
+			copied.AdmxPolicy.Elements = nil
+			if pol.Elements != nil {
+				copied.Elements = []*cachedElement{}
+			}
+			for _, elem := range pol.Elements {
+				cached, err := newCachedElement(elem.GetElementType(), elem)
+				if err != nil {
+					return nil, err
+				}
+				copied.Elements = append(copied.Elements, cached)
+			}
+			admx.Policies = append(admx.Policies, copied)
+		}
+		entry.Admx = admx
+	}
+
+	if t.Adml != nil {
+		adml, err := newCachedAdml(t.Adml)
+		if err != nil {
+			return nil, err
+		}
+		entry.Adml = adml
+	}
+
+	// The preferred ADML is stored once and linked again on restore
+	for locale, localized := range t.Admls {
+		if localized == t.Adml {
+			continue
+		}
+		adml, err := newCachedAdml(localized)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Admls == nil {
+			entry.Admls = make(map[string]*cachedAdmlFile)
+		}
+		entry.Admls[locale] = adml
+	}
+
+	return entry, nil
+}
+
+func newCachedAdml(src *AdmlFile) (*cachedAdmlFile, error) {
+	adml := &cachedAdmlFile{
+		SourceFile:        src.SourceFile,
+		Locale:            src.Locale,
+		Revision:          src.Revision,
+		SchemaVersion:     src.SchemaVersion,
+		DisplayName:       src.DisplayName,
+		Description:       src.Description,
+		StringTable:       src.StringTable,
+		PresentationTable: make(map[string]*cachedPresentation),
+	}
+	for id, pres := range src.PresentationTable {
+		cachedPres := &cachedPresentation{Name: pres.Name, Elements: []*cachedElement{}}
+		for _, elem := range pres.Elements {
+			cached, err := newCachedElement(elem.GetElementType(), elem)
+			if err != nil {
+				return nil, err
+			}
+			cachedPres.Elements = append(cachedPres.Elements, cached)
+		}
+		adml.PresentationTable[id] = cachedPres
+	}
+	return adml, nil
+}
+
+func (c *cachedTemplate) restore() (*parsedTemplate, error) {
+	result := &parsedTemplate{Path: c.Path, Failure: c.Failure}
+
+	if c.Admx != nil {
+		admx := &AdmxFile{
+			SourceFile:             c.Admx.SourceFile,
+			AdmxNamespace:          c.Admx.AdmxNamespace,
+			SupersededAdm:          c.Admx.SupersededAdm,
+			Revision:               c.Admx.Revision,
+			SchemaVersion:          c.Admx.SchemaVersion,
+			MinAdmlVersion:         c.Admx.MinAdmlVersion,
+			Prefixes:               c.Admx.Prefixes,
+			SupportedOnDefinitions: c.Admx.SupportedOnDefinitions,
+			Categories:             c.Admx.Categories,
+		}
+
+		for _, product := range c.Admx.Products {
+			admx.Products = append(admx.Products, &AdmxProduct{
+				ID:          product.ID,
+				DisplayCode: product.DisplayCode,
+				Type:        product.Type,
+				Version:     product.Version,
+				DefinedIn:   admx,
+			})
+		}
+		for i, product := range c.Admx.Products {
+			if product.ParentIndex >= 0 && product.ParentIndex < len(admx.Products) {
+				admx.Products[i].Parent = admx.Products[product.ParentIndex]
+			}
+		}
+		for _, support := range admx.SupportedOnDefinitions {
+			support.DefinedIn = admx
+		}
+		for _, category := range admx.Categories {
+			category.DefinedIn = admx
+		}
+		for _, cached := range c.Admx.Policies {
+			pol := cached.AdmxPolicy
+			pol.DefinedIn = admx
+			pol.Elements = nil
+			if cached.Elements != nil {
+				pol.Elements = []PolicyElement{}
+			}
+			for _, elem := range cached.Elements {
+				restored, err := restorePolicyElement(elem)
+				if err != nil {
+					return nil, err
+				}
+				pol.Elements = append(pol.Elements, restored)
+			}
+			admx.Policies = append(admx.Policies, &pol)
+		}
+		result.Admx = admx
+	}
+
+	if c.Adml != nil {
+		adml, err := c.Adml.restore()
+		if err != nil {
+			return nil, err
+		}
+		result.Adml = adml
+	}
+
+	if c.Adml != nil || c.Admls != nil {
+		result.Admls = make(map[string]*AdmlFile)
+		if result.Adml != nil && result.Adml.Locale != "" {
+			result.Admls[result.Adml.Locale] = result.Adml
+		}
+		for locale, cached := range c.Admls {
+			adml, err := cached.restore()
+			if err != nil {
+				return nil, err
+			}
+			result.Admls[locale] = adml
+		}
+	}
+
+	return result, nil
+}
+
+func (c *cachedAdmlFile) restore() (*AdmlFile, error) {
+	adml := &AdmlFile{
+		SourceFile:        c.SourceFile,
+		Locale:            c.Locale,
+		Revision:          c.Revision,
+		SchemaVersion:     c.SchemaVersion,
+		DisplayName:       c.DisplayName,
+		Description:       c.Description,
+		StringTable:       c.StringTable,
+		PresentationTable: make(map[string]*Presentation),
+	}
+	for id, cached := range c.PresentationTable {
+		pres := &Presentation{Name: cached.Name, Elements: []PresentationElement{}}
+		for _, elem := range cached.Elements {
+			restored, err := restorePresentationElement(elem)
+			if err != nil {
+				return nil, err
+			}
+			pres.Elements = append(pres.Elements, restored)
+		}
+		adml.PresentationTable[id] = pres
+	}
+	return adml, nil
+}
+
+func restorePolicyElement(c *cachedElement) (PolicyElement, error) {
+	var elem PolicyElement
+	switch c.Type {
+	case "decimal":
+		elem = &DecimalPolicyElement{}
+	case "boolean":
+		elem = &BooleanPolicyElement{}
+	case "text":
+		elem = &TextPolicyElement{}
+	case "list":
+		elem = &ListPolicyElement{}
+	case "enum":
+		elem = &EnumPolicyElement{}
+	case "multiText":
+		elem = &MultiTextPolicyElement{}
+	default:
+		return nil, fmt.Errorf("unknown cached policy element type: %s", c.Type)
+	}
+	if err := json.Unmarshal(c.Data, elem); err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
+
+func restorePresentationElement(c *cachedElement) (PresentationElement, error) {
+	var elem PresentationElement
+	switch c.Type {
+	case "text":
+		elem = &LabelPresentationElement{}
+	case "decimalTextBox":
+		elem = &NumericBoxPresentationElement{}
+	case "textBox":
+		elem = &TextBoxPresentationElement{}
+	case "checkBox":
+		elem = &CheckBoxPresentationElement{}
+	case "comboBox":
+		elem = &ComboBoxPresentationElement{}
+	case "dropdownList":
+		elem = &DropDownPresentationElement{}
+	case "listBox":
+		elem = &ListPresentationElement{}
+	case "multiTextBox":
+		elem = &MultiTextPresentationElement{}
+	default:
+		return nil, fmt.Errorf("unknown cached presentation element type: %s", c.Type)
+	}
+	if err := json.Unmarshal(c.Data, elem); err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
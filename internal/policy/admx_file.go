@@ -3,8 +3,7 @@ package policy
 import (
 	"encoding/xml"
 	"fmt"
-	"io"
-	"os"
+	"io/fs"
 	"strconv"
 	"strings"
 )
@@ -14,7 +13,9 @@ type AdmxFile struct {
 	SourceFile             string
 	AdmxNamespace          string
 	SupersededAdm          string
-	MinAdmlVersion         float64
+	Revision               Revision
+	SchemaVersion          string
+	MinAdmlVersion         Revision
 	Prefixes               map[string]string
 	Products               []*AdmxProduct
 	SupportedOnDefinitions []*AdmxSupportDefinition
@@ -25,6 +26,9 @@ type AdmxFile struct {
 // ADMX XML structures
 type admxPolicyDefinitions struct {
 	XMLName          xml.Name              `xml:"policyDefinitions"`
+	Xmlns            string                `xml:"xmlns,attr,omitempty"`
+	Revision         string                `xml:"revision,attr,omitempty"`
+	SchemaVersion    string                `xml:"schemaVersion,attr,omitempty"`
 	PolicyNamespaces *admxPolicyNamespaces `xml:"policyNamespaces"`
 	SupersededAdm    *admxSupersededAdm    `xml:"supersededAdm"`
 	Resources        *admxResources        `xml:"resources"`
@@ -39,16 +43,16 @@ type admxPolicyNamespaces struct {
 }
 
 type admxNamespace struct {
-	Prefix    string `xml:"prefix,attr"`
-	Namespace string `xml:"namespace,attr"`
+	Prefix    string `xml:"prefix,attr,omitempty"`
+	Namespace string `xml:"namespace,attr,omitempty"`
 }
 
 type admxSupersededAdm struct {
-	FileName string `xml:"fileName,attr"`
+	FileName string `xml:"fileName,attr,omitempty"`
 }
 
 type admxResources struct {
-	MinRequiredRevision string `xml:"minRequiredRevision,attr"`
+	MinRequiredRevision string `xml:"minRequiredRevision,attr,omitempty"`
 }
 
 type admxSupportedOn struct {
@@ -61,8 +65,8 @@ type admxSupportDefinitions struct {
 }
 
 type admxSupportDefinition struct {
-	Name        string            `xml:"name,attr"`
-	DisplayName string            `xml:"displayName,attr"`
+	Name        string            `xml:"name,attr,omitempty"`
+	DisplayName string            `xml:"displayName,attr,omitempty"`
 	Or          *admxSupportLogic `xml:"or"`
 	And         *admxSupportLogic `xml:"and"`
 }
@@ -73,13 +77,13 @@ type admxSupportLogic struct {
 }
 
 type admxSupportReference struct {
-	Ref string `xml:"ref,attr"`
+	Ref string `xml:"ref,attr,omitempty"`
 }
 
 type admxSupportRange struct {
-	Ref             string `xml:"ref,attr"`
-	MinVersionIndex string `xml:"minVersionIndex,attr"`
-	MaxVersionIndex string `xml:"maxVersionIndex,attr"`
+	Ref             string `xml:"ref,attr,omitempty"`
+	MinVersionIndex string `xml:"minVersionIndex,attr,omitempty"`
+	MaxVersionIndex string `xml:"maxVersionIndex,attr,omitempty"`
 }
 
 type admxProducts struct {
@@ -87,22 +91,22 @@ type admxProducts struct {
 }
 
 type admxProductDef struct {
-	Name          string             `xml:"name,attr"`
-	DisplayName   string             `xml:"displayName,attr"`
+	Name          string             `xml:"name,attr,omitempty"`
+	DisplayName   string             `xml:"displayName,attr,omitempty"`
 	MajorVersions []admxMajorVersion `xml:"majorVersion"`
 }
 
 type admxMajorVersion struct {
-	Name          string             `xml:"name,attr"`
-	DisplayName   string             `xml:"displayName,attr"`
-	VersionIndex  string             `xml:"versionIndex,attr"`
+	Name          string             `xml:"name,attr,omitempty"`
+	DisplayName   string             `xml:"displayName,attr,omitempty"`
+	VersionIndex  string             `xml:"versionIndex,attr,omitempty"`
 	MinorVersions []admxMinorVersion `xml:"minorVersion"`
 }
 
 type admxMinorVersion struct {
-	Name         string `xml:"name,attr"`
-	DisplayName  string `xml:"displayName,attr"`
-	VersionIndex string `xml:"versionIndex,attr"`
+	Name         string `xml:"name,attr,omitempty"`
+	DisplayName  string `xml:"displayName,attr,omitempty"`
+	VersionIndex string `xml:"versionIndex,attr,omitempty"`
 }
 
 type admxCategories struct {
@@ -110,14 +114,16 @@ type admxCategories struct {
 }
 
 type admxCategoryDef struct {
-	Name           string              `xml:"name,attr"`
-	DisplayName    string              `xml:"displayName,attr"`
-	ExplainText    string              `xml:"explainText,attr"`
+	Name           string              `xml:"name,attr,omitempty"`
+	DisplayName    string              `xml:"displayName,attr,omitempty"`
+	ExplainText    string              `xml:"explainText,attr,omitempty"`
 	ParentCategory *admxParentCategory `xml:"parentCategory"`
+	SeeAlso        []string            `xml:"seeAlso"`
+	Keywords       []string            `xml:"keywords"`
 }
 
 type admxParentCategory struct {
-	Ref string `xml:"ref,attr"`
+	Ref string `xml:"ref,attr,omitempty"`
 }
 
 type admxPolicies struct {
@@ -125,15 +131,17 @@ type admxPolicies struct {
 }
 
 type admxPolicyDef struct {
-	Name            string              `xml:"name,attr"`
-	Class           string              `xml:"class,attr"`
-	DisplayName     string              `xml:"displayName,attr"`
-	ExplainText     string              `xml:"explainText,attr"`
-	Key             string              `xml:"key,attr"`
-	ValueName       string              `xml:"valueName,attr"`
-	Presentation    string              `xml:"presentation,attr"`
-	ClientExtension string              `xml:"clientExtension,attr"`
+	Name            string              `xml:"name,attr,omitempty"`
+	Class           string              `xml:"class,attr,omitempty"`
+	DisplayName     string              `xml:"displayName,attr,omitempty"`
+	ExplainText     string              `xml:"explainText,attr,omitempty"`
+	Key             string              `xml:"key,attr,omitempty"`
+	ValueName       string              `xml:"valueName,attr,omitempty"`
+	Presentation    string              `xml:"presentation,attr,omitempty"`
+	ClientExtension string              `xml:"clientExtension,attr,omitempty"`
 	ParentCategory  admxParentCategory  `xml:"parentCategory"`
+	SeeAlso         []string            `xml:"seeAlso"`
+	Keywords        []string            `xml:"keywords"`
 	SupportedOn     *admxSupportedOnRef `xml:"supportedOn"`
 	EnabledValue    *admxValue          `xml:"enabledValue"`
 	DisabledValue   *admxValue          `xml:"disabledValue"`
@@ -143,7 +151,7 @@ type admxPolicyDef struct {
 }
 
 type admxSupportedOnRef struct {
-	Ref string `xml:"ref,attr"`
+	Ref string `xml:"ref,attr,omitempty"`
 }
 
 type admxValue struct {
@@ -153,7 +161,7 @@ type admxValue struct {
 }
 
 type admxDecimalValue struct {
-	Value string `xml:"value,attr"`
+	Value string `xml:"value,attr,omitempty"`
 }
 
 type admxStringValue struct {
@@ -161,13 +169,13 @@ type admxStringValue struct {
 }
 
 type admxValueList struct {
-	DefaultKey string          `xml:"defaultKey,attr"`
+	DefaultKey string          `xml:"defaultKey,attr,omitempty"`
 	Items      []admxValueItem `xml:"item"`
 }
 
 type admxValueItem struct {
-	ValueName string     `xml:"valueName,attr"`
-	Key       string     `xml:"key,attr"`
+	ValueName string     `xml:"valueName,attr,omitempty"`
+	Key       string     `xml:"key,attr,omitempty"`
 	Value     *admxValue `xml:"value"`
 }
 
@@ -181,21 +189,22 @@ type admxElements struct {
 }
 
 type admxDecimalElement struct {
-	ID              string `xml:"id,attr"`
-	ValueName       string `xml:"valueName,attr"`
-	Key             string `xml:"key,attr"`
-	MinValue        string `xml:"minValue,attr"`
-	MaxValue        string `xml:"maxValue,attr"`
-	Soft            string `xml:"soft,attr"`
-	StoreAsText     string `xml:"storeAsText,attr"`
-	ClientExtension string `xml:"clientExtension,attr"`
+	ID              string `xml:"id,attr,omitempty"`
+	ValueName       string `xml:"valueName,attr,omitempty"`
+	Key             string `xml:"key,attr,omitempty"`
+	MinValue        string `xml:"minValue,attr,omitempty"`
+	MaxValue        string `xml:"maxValue,attr,omitempty"`
+	Required        string `xml:"required,attr,omitempty"`
+	Soft            string `xml:"soft,attr,omitempty"`
+	StoreAsText     string `xml:"storeAsText,attr,omitempty"`
+	ClientExtension string `xml:"clientExtension,attr,omitempty"`
 }
 
 type admxBooleanElement struct {
-	ID              string         `xml:"id,attr"`
-	ValueName       string         `xml:"valueName,attr"`
-	Key             string         `xml:"key,attr"`
-	ClientExtension string         `xml:"clientExtension,attr"`
+	ID              string         `xml:"id,attr,omitempty"`
+	ValueName       string         `xml:"valueName,attr,omitempty"`
+	Key             string         `xml:"key,attr,omitempty"`
+	ClientExtension string         `xml:"clientExtension,attr,omitempty"`
 	TrueValue       *admxValue     `xml:"trueValue"`
 	FalseValue      *admxValue     `xml:"falseValue"`
 	TrueList        *admxValueList `xml:"trueList"`
@@ -203,59 +212,71 @@ type admxBooleanElement struct {
 }
 
 type admxTextElement struct {
-	ID              string `xml:"id,attr"`
-	ValueName       string `xml:"valueName,attr"`
-	Key             string `xml:"key,attr"`
-	MaxLength       string `xml:"maxLength,attr"`
-	Required        string `xml:"required,attr"`
-	Expandable      string `xml:"expandable,attr"`
-	Soft            string `xml:"soft,attr"`
-	ClientExtension string `xml:"clientExtension,attr"`
+	ID              string `xml:"id,attr,omitempty"`
+	ValueName       string `xml:"valueName,attr,omitempty"`
+	Key             string `xml:"key,attr,omitempty"`
+	MaxLength       string `xml:"maxLength,attr,omitempty"`
+	Required        string `xml:"required,attr,omitempty"`
+	Expandable      string `xml:"expandable,attr,omitempty"`
+	Soft            string `xml:"soft,attr,omitempty"`
+	ClientExtension string `xml:"clientExtension,attr,omitempty"`
 }
 
 type admxListElement struct {
-	ID              string `xml:"id,attr"`
-	Key             string `xml:"key,attr"`
-	ValuePrefix     string `xml:"valuePrefix,attr"`
-	Additive        string `xml:"additive,attr"`
-	Expandable      string `xml:"expandable,attr"`
-	ExplicitValue   string `xml:"explicitValue,attr"`
-	ClientExtension string `xml:"clientExtension,attr"`
+	ID              string `xml:"id,attr,omitempty"`
+	Key             string `xml:"key,attr,omitempty"`
+	ValuePrefix     string `xml:"valuePrefix,attr,omitempty"`
+	Additive        string `xml:"additive,attr,omitempty"`
+	Expandable      string `xml:"expandable,attr,omitempty"`
+	ExplicitValue   string `xml:"explicitValue,attr,omitempty"`
+	ClientExtension string `xml:"clientExtension,attr,omitempty"`
 }
 
 type admxEnumElement struct {
-	ID              string         `xml:"id,attr"`
-	ValueName       string         `xml:"valueName,attr"`
-	Key             string         `xml:"key,attr"`
-	Required        string         `xml:"required,attr"`
-	ClientExtension string         `xml:"clientExtension,attr"`
+	ID              string         `xml:"id,attr,omitempty"`
+	ValueName       string         `xml:"valueName,attr,omitempty"`
+	Key             string         `xml:"key,attr,omitempty"`
+	Required        string         `xml:"required,attr,omitempty"`
+	ClientExtension string         `xml:"clientExtension,attr,omitempty"`
 	Items           []admxEnumItem `xml:"item"`
 }
 
 type admxEnumItem struct {
-	DisplayName string         `xml:"displayName,attr"`
+	DisplayName string         `xml:"displayName,attr,omitempty"`
 	Value       *admxValue     `xml:"value"`
 	ValueList   *admxValueList `xml:"valueList"`
 }
 
 type admxMultiTextElement struct {
-	ID              string `xml:"id,attr"`
-	ValueName       string `xml:"valueName,attr"`
-	Key             string `xml:"key,attr"`
-	ClientExtension string `xml:"clientExtension,attr"`
+	ID              string `xml:"id,attr,omitempty"`
+	ValueName       string `xml:"valueName,attr,omitempty"`
+	Key             string `xml:"key,attr,omitempty"`
+	ClientExtension string `xml:"clientExtension,attr,omitempty"`
 }
 
 // LoadAdmxFile loads ADMX file
 func LoadAdmxFile(path string) (*AdmxFile, error) {
-	file, err := os.Open(path)
+	data, err := readTemplateFile(nil, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	return parseAdmx(data, path)
+}
+
+// LoadAdmxFS loads an ADMX file from a file system. name is a slash
+// separated path as used by fs.FS.
+func LoadAdmxFS(fsys fs.FS, name string) (*AdmxFile, error) {
+	data, err := readTemplateFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return parseAdmx(data, name)
+}
 
-	data, err := io.ReadAll(file)
+func parseAdmx(data []byte, path string) (*AdmxFile, error) {
+	data, err := decodeXMLText(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
 
 	var policyDefs admxPolicyDefinitions
@@ -286,9 +307,18 @@ func LoadAdmxFile(path string) (*AdmxFile, error) {
 		admx.SupersededAdm = policyDefs.SupersededAdm.FileName
 	}
 
+	if policyDefs.Revision != "" {
+		if admx.Revision, err = parseRevision(policyDefs.Revision); err != nil {
+			return nil, err
+		}
+	}
+	admx.SchemaVersion = policyDefs.SchemaVersion
+
 	// Resources
 	if policyDefs.Resources != nil && policyDefs.Resources.MinRequiredRevision != "" {
-		admx.MinAdmlVersion, _ = strconv.ParseFloat(policyDefs.Resources.MinRequiredRevision, 64)
+		if admx.MinAdmlVersion, err = parseRevision(policyDefs.Resources.MinRequiredRevision); err != nil {
+			return nil, fmt.Errorf("minRequiredRevision: %w", err)
+		}
 	}
 
 	// Categories
@@ -298,6 +328,8 @@ func LoadAdmxFile(path string) (*AdmxFile, error) {
 				ID:          cat.Name,
 				DisplayCode: cat.DisplayName,
 				ExplainCode: cat.ExplainText,
+				SeeAlso:     trimmedTexts(cat.SeeAlso),
+				Keywords:    trimmedTexts(cat.Keywords),
 				DefinedIn:   admx,
 			}
 			if cat.ParentCategory != nil {
@@ -411,6 +443,8 @@ func LoadAdmxFile(path string) (*AdmxFile, error) {
 				RegistryValue:   polDef.ValueName,
 				PresentationID:  polDef.Presentation,
 				ClientExtension: polDef.ClientExtension,
+				SeeAlso:         trimmedTexts(polDef.SeeAlso),
+				Keywords:        trimmedTexts(polDef.Keywords),
 				DefinedIn:       admx,
 				AffectedValues:  &PolicyRegistryList{},
 			}
@@ -446,7 +480,9 @@ func LoadAdmxFile(path string) (*AdmxFile, error) {
 
 			// Elements
 			if polDef.Elements != nil {
-				policy.Elements = parseAdmxElements(polDef.Elements)
+				if policy.Elements, err = parseAdmxElements(polDef.Elements); err != nil {
+					return nil, fmt.Errorf("policy %s: %w", polDef.Name, err)
+				}
 			}
 
 			admx.Policies = append(admx.Policies, policy)
@@ -456,6 +492,17 @@ func LoadAdmxFile(path string) (*AdmxFile, error) {
 	return admx, nil
 }
 
+// trimmedTexts trims element texts and drops empty ones
+func trimmedTexts(texts []string) []string {
+	var result []string
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
 func parseAdmxValue(val *admxValue) *PolicyRegistryValue {
 	if val.Delete != nil {
 		return &PolicyRegistryValue{RegistryType: Delete}
@@ -494,7 +541,7 @@ func parseAdmxValueList(list *admxValueList) *PolicyRegistrySingleList {
 	return result
 }
 
-func parseAdmxElements(elements *admxElements) []PolicyElement {
+func parseAdmxElements(elements *admxElements) ([]PolicyElement, error) {
 	var result []PolicyElement
 
 	// Decimal elements
@@ -507,16 +554,24 @@ func parseAdmxElements(elements *admxElements) []PolicyElement {
 				ClientExtension: dec.ClientExtension,
 				ElementType:     "decimal",
 			},
-			Maximum: ^uint32(0), // Max uint32
+			// schema default of decimal/@maxValue
+			Maximum: 9999,
 		}
 		if dec.MinValue != "" {
-			min, _ := strconv.ParseUint(dec.MinValue, 10, 32)
+			min, err := strconv.ParseUint(dec.MinValue, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("element %s: invalid minValue %q", dec.ID, dec.MinValue)
+			}
 			elem.Minimum = uint32(min)
 		}
 		if dec.MaxValue != "" {
-			max, _ := strconv.ParseUint(dec.MaxValue, 10, 32)
+			max, err := strconv.ParseUint(dec.MaxValue, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("element %s: invalid maxValue %q", dec.ID, dec.MaxValue)
+			}
 			elem.Maximum = uint32(max)
 		}
+		elem.Required = dec.Required == "true"
 		elem.StoreAsText = dec.StoreAsText == "true"
 		elem.NoOverwrite = dec.Soft == "true"
 		result = append(result, elem)
@@ -559,10 +614,14 @@ func parseAdmxElements(elements *admxElements) []PolicyElement {
 				ClientExtension: txt.ClientExtension,
 				ElementType:     "text",
 			},
-			MaxLength: 255,
+			// schema default of text/@maxLength
+			MaxLength: 1023,
 		}
 		if txt.MaxLength != "" {
-			maxLen, _ := strconv.Atoi(txt.MaxLength)
+			maxLen, err := strconv.Atoi(txt.MaxLength)
+			if err != nil {
+				return nil, fmt.Errorf("element %s: invalid maxLength %q", txt.ID, txt.MaxLength)
+			}
 			elem.MaxLength = maxLen
 		}
 		elem.Required = txt.Required == "true"
@@ -631,5 +690,5 @@ func parseAdmxElements(elements *admxElements) []PolicyElement {
 		result = append(result, elem)
 	}
 
-	return result
+	return result, nil
 }
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Locales returns the locale folder names for which at least one ADML is
+// loaded, sorted by name.
+func (b *AdmxBundle) Locales() []string {
+	seen := make(map[string]string)
+	for _, admls := range b.localizedFiles {
+		for locale := range admls {
+			key := strings.ToLower(locale)
+			if _, ok := seen[key]; !ok {
+				seen[key] = locale
+			}
+		}
+	}
+
+	locales := make([]string, 0, len(seen))
+	for _, locale := range seen {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// LanguageCodes returns the locale preference list the bundle was loaded
+// with.
+func (b *AdmxBundle) LanguageCodes() []string {
+	return append([]string(nil), b.languageCodes...)
+}
+
+// LocaleFileCount returns how many loaded ADMX files have an ADML for the
+// given locale.
+func (b *AdmxBundle) LocaleFileCount(locale string) int {
+	count := 0
+	for _, admls := range b.localizedFiles {
+		for name := range admls {
+			if strings.EqualFold(name, locale) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// MatchLocale returns the first loaded locale that matches one of the
+// requested language tags. A tag matches a locale with the same name, and a
+// bare language ("tr") or a tag of another region ("en-GB") matches a
+// locale of the same language.
+func (b *AdmxBundle) MatchLocale(tags ...string) (string, bool) {
+	locales := b.Locales()
+	for _, tag := range tags {
+		tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+		if tag == "" {
+			continue
+		}
+		for _, locale := range locales {
+			if strings.EqualFold(locale, tag) {
+				return locale, true
+			}
+		}
+		language := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		for _, locale := range locales {
+			if strings.ToLower(strings.SplitN(locale, "-", 2)[0]) == language {
+				return locale, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Localized returns a view of the bundle whose names, explanations and
+// presentations come from the ADMLs of locale. Files without an ADML for
+// that locale keep the ADML chosen at load time. An unknown locale returns
+// the bundle itself. Views are built once and shared; like the bundle they
+// must be treated as read-only.
+func (b *AdmxBundle) Localized(locale string) *AdmxBundle {
+	matched, ok := b.MatchLocale(locale)
+	if !ok {
+		return b
+	}
+	key := strings.ToLower(matched)
+
+	b.viewsMu.Lock()
+	defer b.viewsMu.Unlock()
+
+	if view, ok := b.views[key]; ok {
+		return view
+	}
+
+	view := NewAdmxBundle()
+	view.languageCodes = []string{matched}
+	view.conflictRule = b.conflictRule
+	view.lastLoadStats = b.lastLoadStats
+	view.NamespaceSources = b.NamespaceSources
+	view.localizedFiles = b.localizedFiles
+
+	namespaces := make([]string, 0, len(b.namespaces))
+	for ns := range b.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		admx := b.namespaces[ns]
+		adml := b.sourceFiles[admx]
+		for name, localized := range b.localizedFiles[admx] {
+			if strings.EqualFold(name, matched) {
+				adml = localized
+				break
+			}
+		}
+
+		view.rawCategories = append(view.rawCategories, admx.Categories...)
+		view.rawProducts = append(view.rawProducts, admx.Products...)
+		view.rawPolicies = append(view.rawPolicies, admx.Policies...)
+		view.rawSupport = append(view.rawSupport, admx.SupportedOnDefinitions...)
+		view.sourceFiles[admx] = adml
+		view.namespaces[ns] = admx
+	}
+	view.buildStructures()
+
+	if b.views == nil {
+		b.views = make(map[string]*AdmxBundle)
+	}
+	b.views[key] = view
+	return view
+}
+
+// resetViews drops localized views after the bundle changed.
+func (b *AdmxBundle) resetViews() {
+	b.viewsMu.Lock()
+	b.views = nil
+	b.viewsMu.Unlock()
+}
@@ -0,0 +1,470 @@
+package policy
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	admxSchemaNamespace = "http://schemas.microsoft.com/GroupPolicy/2006/07/PolicyDefinitions"
+	admxSchemaVersion   = "1.0"
+)
+
+// WriteAdmx serializes an AdmxFile back to ADMX XML.
+func WriteAdmx(w io.Writer, admx *AdmxFile) error {
+	doc := admxPolicyDefinitions{
+		Xmlns:         admxSchemaNamespace,
+		Revision:      formatRevision(admx.Revision),
+		SchemaVersion: formatSchemaVersion(admx.SchemaVersion),
+		Resources:     &admxResources{MinRequiredRevision: formatRevision(admx.MinAdmlVersion)},
+	}
+
+	// Namespaces
+	namespaces := &admxPolicyNamespaces{}
+	prefixes := make([]string, 0, len(admx.Prefixes))
+	for prefix := range admx.Prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		ns := admxNamespace{Prefix: prefix, Namespace: admx.Prefixes[prefix]}
+		if ns.Namespace == admx.AdmxNamespace && namespaces.Target.Namespace == "" {
+			namespaces.Target = ns
+		} else {
+			namespaces.Usings = append(namespaces.Usings, ns)
+		}
+	}
+	if namespaces.Target.Namespace == "" {
+		namespaces.Target = admxNamespace{Prefix: "target", Namespace: admx.AdmxNamespace}
+	}
+	doc.PolicyNamespaces = namespaces
+
+	if admx.SupersededAdm != "" {
+		doc.SupersededAdm = &admxSupersededAdm{FileName: admx.SupersededAdm}
+	}
+
+	// Products and support definitions
+	supportedOn := &admxSupportedOn{}
+	if products := buildAdmxProducts(admx.Products); len(products) > 0 {
+		supportedOn.Products = &admxProducts{Products: products}
+	}
+	if len(admx.SupportedOnDefinitions) > 0 {
+		supportedOn.Definitions = &admxSupportDefinitions{}
+		for _, sup := range admx.SupportedOnDefinitions {
+			supportedOn.Definitions.Definitions = append(supportedOn.Definitions.Definitions, buildAdmxSupportDefinition(sup))
+		}
+	}
+	if supportedOn.Products != nil || supportedOn.Definitions != nil {
+		doc.SupportedOn = supportedOn
+	}
+
+	// Categories
+	if len(admx.Categories) > 0 {
+		doc.Categories = &admxCategories{}
+		for _, cat := range admx.Categories {
+			catDef := admxCategoryDef{
+				Name:        cat.ID,
+				DisplayName: cat.DisplayCode,
+				ExplainText: cat.ExplainCode,
+				SeeAlso:     cat.SeeAlso,
+				Keywords:    cat.Keywords,
+			}
+			if cat.ParentID != "" {
+				catDef.ParentCategory = &admxParentCategory{Ref: cat.ParentID}
+			}
+			doc.Categories.Categories = append(doc.Categories.Categories, catDef)
+		}
+	}
+
+	// Policies
+	if len(admx.Policies) > 0 {
+		doc.Policies = &admxPolicies{}
+		for _, pol := range admx.Policies {
+			doc.Policies.Policies = append(doc.Policies.Policies, buildAdmxPolicyDef(pol))
+		}
+	}
+
+	return writeXMLDocument(w, doc)
+}
+
+// WriteAdml serializes an AdmlFile back to ADML XML.
+func WriteAdml(w io.Writer, adml *AdmlFile) error {
+	doc := admlPolicyDefinitionResources{
+		Xmlns:         admxSchemaNamespace,
+		Revision:      formatRevision(adml.Revision),
+		SchemaVersion: formatSchemaVersion(adml.SchemaVersion),
+		DisplayName:   adml.DisplayName,
+		Description:   adml.Description,
+		StringTable:   &admlStringTable{},
+	}
+
+	ids := make([]string, 0, len(adml.StringTable))
+	for id := range adml.StringTable {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc.StringTable.Strings = append(doc.StringTable.Strings, admlString{ID: id, Value: adml.StringTable[id]})
+	}
+
+	if len(adml.PresentationTable) > 0 {
+		doc.PresentationTable = &admlPresentationTable{}
+		names := make([]string, 0, len(adml.PresentationTable))
+		for name := range adml.PresentationTable {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc.PresentationTable.Presentations = append(doc.PresentationTable.Presentations, buildAdmlPresentation(adml.PresentationTable[name]))
+		}
+	}
+
+	return writeXMLDocument(w, doc)
+}
+
+// ConvertAdmFile converts a legacy .adm template into an ADMX file in outDir
+// and an ADML file in outDir/<locale>. The generated ADMX declares the ADM as
+// superseded so both are not loaded side by side.
+func ConvertAdmFile(admPath, outDir, locale string) (string, string, error) {
+	admx, adml, err := LoadAdmFile(admPath)
+	if err != nil {
+		return "", "", err
+	}
+	if locale == "" {
+		locale = "en-US"
+	}
+	admx.SupersededAdm = filepath.Base(admPath)
+
+	base := strings.TrimSuffix(filepath.Base(admPath), filepath.Ext(admPath))
+	admxPath := filepath.Join(outDir, base+".admx")
+	admlPath := filepath.Join(outDir, locale, base+".adml")
+
+	if err := os.MkdirAll(filepath.Dir(admlPath), 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create output folder: %w", err)
+	}
+	if err := writeFileWith(admxPath, func(w io.Writer) error { return WriteAdmx(w, admx) }); err != nil {
+		return "", "", err
+	}
+	if err := writeFileWith(admlPath, func(w io.Writer) error { return WriteAdml(w, adml) }); err != nil {
+		return "", "", err
+	}
+	return admxPath, admlPath, nil
+}
+
+func writeFileWith(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeXMLDocument(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("XML write error: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatRevision(rev Revision) string {
+	if rev.IsZero() {
+		return "1.0"
+	}
+	return rev.String()
+}
+
+func formatSchemaVersion(version string) string {
+	if version == "" {
+		return admxSchemaVersion
+	}
+	return version
+}
+
+func buildAdmxProducts(products []*AdmxProduct) []admxProductDef {
+	var result []admxProductDef
+	for _, prod := range products {
+		if prod.Type != Product {
+			continue
+		}
+		def := admxProductDef{Name: prod.ID, DisplayName: prod.DisplayCode}
+		for _, major := range products {
+			if major.Type != MajorRevision || major.Parent != prod {
+				continue
+			}
+			majorDef := admxMajorVersion{
+				Name:         major.ID,
+				DisplayName:  major.DisplayCode,
+				VersionIndex: strconv.Itoa(major.Version),
+			}
+			for _, minor := range products {
+				if minor.Type != MinorRevision || minor.Parent != major {
+					continue
+				}
+				majorDef.MinorVersions = append(majorDef.MinorVersions, admxMinorVersion{
+					Name:         minor.ID,
+					DisplayName:  minor.DisplayCode,
+					VersionIndex: strconv.Itoa(minor.Version),
+				})
+			}
+			def.MajorVersions = append(def.MajorVersions, majorDef)
+		}
+		result = append(result, def)
+	}
+	return result
+}
+
+func buildAdmxSupportDefinition(sup *AdmxSupportDefinition) admxSupportDefinition {
+	def := admxSupportDefinition{Name: sup.ID, DisplayName: sup.DisplayCode}
+	if len(sup.Entries) == 0 {
+		return def
+	}
+
+	logic := &admxSupportLogic{}
+	for _, entry := range sup.Entries {
+		if !entry.IsRange {
+			logic.References = append(logic.References, admxSupportReference{Ref: entry.ProductID})
+			continue
+		}
+		rng := admxSupportRange{Ref: entry.ProductID}
+		if entry.MinVersion != nil {
+			rng.MinVersionIndex = strconv.Itoa(*entry.MinVersion)
+		}
+		if entry.MaxVersion != nil {
+			rng.MaxVersionIndex = strconv.Itoa(*entry.MaxVersion)
+		}
+		logic.Ranges = append(logic.Ranges, rng)
+	}
+	if sup.Logic == AllOf {
+		def.And = logic
+	} else {
+		def.Or = logic
+	}
+	return def
+}
+
+func buildAdmxPolicyDef(pol *AdmxPolicy) admxPolicyDef {
+	def := admxPolicyDef{
+		Name:            pol.ID,
+		DisplayName:     pol.DisplayCode,
+		ExplainText:     pol.ExplainCode,
+		Key:             pol.RegistryKey,
+		ValueName:       pol.RegistryValue,
+		Presentation:    pol.PresentationID,
+		ClientExtension: pol.ClientExtension,
+		ParentCategory:  admxParentCategory{Ref: pol.CategoryID},
+		SeeAlso:         pol.SeeAlso,
+		Keywords:        pol.Keywords,
+	}
+
+	switch pol.Section {
+	case Machine:
+		def.Class = "Machine"
+	case User:
+		def.Class = "User"
+	default:
+		def.Class = "Both"
+	}
+
+	if pol.SupportedCode != "" {
+		def.SupportedOn = &admxSupportedOnRef{Ref: pol.SupportedCode}
+	}
+
+	if pol.AffectedValues != nil {
+		def.EnabledValue = buildAdmxValue(pol.AffectedValues.OnValue)
+		def.DisabledValue = buildAdmxValue(pol.AffectedValues.OffValue)
+		def.EnabledList = buildAdmxValueList(pol.AffectedValues.OnValueList)
+		def.DisabledList = buildAdmxValueList(pol.AffectedValues.OffValueList)
+	}
+
+	if len(pol.Elements) > 0 {
+		def.Elements = buildAdmxElements(pol.Elements)
+	}
+	return def
+}
+
+func buildAdmxValue(val *PolicyRegistryValue) *admxValue {
+	if val == nil {
+		return nil
+	}
+	switch val.RegistryType {
+	case Delete:
+		return &admxValue{Delete: &struct{}{}}
+	case Numeric:
+		return &admxValue{Decimal: &admxDecimalValue{Value: strconv.FormatUint(uint64(val.NumberValue), 10)}}
+	default:
+		return &admxValue{String: &admxStringValue{Value: val.StringValue}}
+	}
+}
+
+func buildAdmxValueList(list *PolicyRegistrySingleList) *admxValueList {
+	if list == nil {
+		return nil
+	}
+	result := &admxValueList{DefaultKey: list.DefaultRegistryKey}
+	for _, entry := range list.AffectedValues {
+		result.Items = append(result.Items, admxValueItem{
+			ValueName: entry.RegistryValue,
+			Key:       entry.RegistryKey,
+			Value:     buildAdmxValue(entry.Value),
+		})
+	}
+	return result
+}
+
+func buildAdmxElements(elements []PolicyElement) *admxElements {
+	result := &admxElements{}
+	for _, element := range elements {
+		base := element.GetBase()
+		switch e := element.(type) {
+		case *DecimalPolicyElement:
+			dec := admxDecimalElement{
+				ID:              base.ID,
+				ValueName:       base.RegistryValue,
+				Key:             base.RegistryKey,
+				ClientExtension: base.ClientExtension,
+				MinValue:        strconv.FormatUint(uint64(e.Minimum), 10),
+				MaxValue:        strconv.FormatUint(uint64(e.Maximum), 10),
+				Required:        boolAttr(e.Required),
+				StoreAsText:     boolAttr(e.StoreAsText),
+				Soft:            boolAttr(e.NoOverwrite),
+			}
+			result.Decimals = append(result.Decimals, dec)
+		case *BooleanPolicyElement:
+			boo := admxBooleanElement{
+				ID:              base.ID,
+				ValueName:       base.RegistryValue,
+				Key:             base.RegistryKey,
+				ClientExtension: base.ClientExtension,
+			}
+			if e.AffectedRegistry != nil {
+				boo.TrueValue = buildAdmxValue(e.AffectedRegistry.OnValue)
+				boo.FalseValue = buildAdmxValue(e.AffectedRegistry.OffValue)
+				boo.TrueList = buildAdmxValueList(e.AffectedRegistry.OnValueList)
+				boo.FalseList = buildAdmxValueList(e.AffectedRegistry.OffValueList)
+			}
+			result.Booleans = append(result.Booleans, boo)
+		case *TextPolicyElement:
+			result.Texts = append(result.Texts, admxTextElement{
+				ID:              base.ID,
+				ValueName:       base.RegistryValue,
+				Key:             base.RegistryKey,
+				ClientExtension: base.ClientExtension,
+				MaxLength:       strconv.Itoa(e.MaxLength),
+				Required:        boolAttr(e.Required),
+				Expandable:      boolAttr(e.RegExpandSz),
+				Soft:            boolAttr(e.NoOverwrite),
+			})
+		case *ListPolicyElement:
+			result.Lists = append(result.Lists, admxListElement{
+				ID:              base.ID,
+				Key:             base.RegistryKey,
+				ValuePrefix:     base.RegistryValue,
+				ClientExtension: base.ClientExtension,
+				Additive:        boolAttr(e.NoPurgeOthers),
+				Expandable:      boolAttr(e.RegExpandSz),
+				ExplicitValue:   boolAttr(e.UserProvidesNames),
+			})
+		case *EnumPolicyElement:
+			enm := admxEnumElement{
+				ID:              base.ID,
+				ValueName:       base.RegistryValue,
+				Key:             base.RegistryKey,
+				ClientExtension: base.ClientExtension,
+				Required:        boolAttr(e.Required),
+			}
+			for _, item := range e.Items {
+				enm.Items = append(enm.Items, admxEnumItem{
+					DisplayName: item.DisplayCode,
+					Value:       buildAdmxValue(item.Value),
+					ValueList:   buildAdmxValueList(item.ValueList),
+				})
+			}
+			result.Enums = append(result.Enums, enm)
+		case *MultiTextPolicyElement:
+			result.MultiTexts = append(result.MultiTexts, admxMultiTextElement{
+				ID:              base.ID,
+				ValueName:       base.RegistryValue,
+				Key:             base.RegistryKey,
+				ClientExtension: base.ClientExtension,
+			})
+		}
+	}
+	return result
+}
+
+func buildAdmlPresentation(pres *Presentation) admlPresentation {
+	result := admlPresentation{ID: pres.Name}
+	for _, element := range pres.Elements {
+		child := admlPresentationElement{RefID: element.GetID()}
+		switch e := element.(type) {
+		case *LabelPresentationElement:
+			child.XMLName.Local = "text"
+			child.Text = e.Text
+		case *NumericBoxPresentationElement:
+			child.XMLName.Local = "decimalTextBox"
+			child.DefaultValue = strconv.FormatUint(uint64(e.DefaultValue), 10)
+			child.Text = e.Label
+			if !e.HasSpinner {
+				child.Spin = "false"
+			} else if e.SpinnerIncrement != 1 {
+				child.SpinStep = strconv.FormatUint(uint64(e.SpinnerIncrement), 10)
+			}
+		case *TextBoxPresentationElement:
+			child.XMLName.Local = "textBox"
+			child.Label = e.Label
+			child.DefaultText = e.DefaultValue
+		case *CheckBoxPresentationElement:
+			child.XMLName.Local = "checkBox"
+			child.DefaultChecked = boolAttr(e.DefaultState)
+			child.Text = e.Text
+		case *ComboBoxPresentationElement:
+			child.XMLName.Local = "comboBox"
+			child.NoSort = boolAttr(e.NoSort)
+			child.Label = e.Label
+			child.Default = e.DefaultText
+			child.Suggestions = e.Suggestions
+		case *DropDownPresentationElement:
+			child.XMLName.Local = "dropdownList"
+			child.NoSort = boolAttr(e.NoSort)
+			child.Text = e.Label
+			if e.DefaultItemID != nil {
+				child.DefaultItem = strconv.Itoa(*e.DefaultItemID)
+			}
+		case *ListPresentationElement:
+			child.XMLName.Local = "listBox"
+			child.Text = e.Label
+		case *MultiTextPresentationElement:
+			child.XMLName.Local = "multiTextBox"
+			child.Text = e.Label
+		default:
+			continue
+		}
+		result.Elements = append(result.Elements, child)
+	}
+	return result
+}
+
+// boolAttr returns "true" for set flags and an empty string (omitted
+// attribute) otherwise.
+func boolAttr(value bool) string {
+	if value {
+		return "true"
+	}
+	return ""
+}
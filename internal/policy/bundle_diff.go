@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// BundleDiff policies that differ between two bundles
+type BundleDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// DiffBundles compares the policies of two bundles by unique ID. A policy is
+// changed when its texts, category, registry settings, elements or
+// presentation differ.
+func DiffBundles(oldBundle, newBundle *AdmxBundle) BundleDiff {
+	diff := BundleDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	for id, newPol := range newBundle.Policies {
+		oldPol, ok := oldBundle.Policies[id]
+		if !ok {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		if policySignature(oldPol) != policySignature(newPol) {
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+	for id := range oldBundle.Policies {
+		if _, ok := newBundle.Policies[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// policySignature hashes everything of a compiled policy that is visible to
+// API clients. A policy that cannot be encoded gets a signature of its own
+// address, so it always counts as changed.
+func policySignature(pol *PolicyPlusPolicy) string {
+	hash := sha256.New()
+	raw := pol.RawPolicy
+
+	categoryID := ""
+	if pol.Category != nil {
+		categoryID = pol.Category.UniqueID
+	}
+	supportedOn := ""
+	if pol.SupportedOn != nil {
+		supportedOn = pol.SupportedOn.DisplayName
+	}
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00",
+		pol.DisplayName, pol.DisplayExplanation, categoryID, supportedOn,
+		raw.Section, raw.RegistryKey, raw.RegistryValue, raw.ClientExtension)
+
+	// Elements, registry lists and presentations hold no back references
+	for _, part := range []interface{}{raw.AffectedValues, raw.Elements, pol.Presentation} {
+		data, err := json.Marshal(part)
+		if err != nil {
+			return fmt.Sprintf("unencodable %p: %v", pol, err)
+		}
+		hash.Write(data)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// FolderFingerprint returns a hash over the names, sizes and modification
+// times of all template files in a folder or template pack. It changes
+// whenever a template is added, removed or modified.
+func FolderFingerprint(path string) (string, error) {
+	root, err := openTemplateRoot(path)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+
+	scan, err := scanTemplateFolder(root, nil)
+	if err != nil {
+		return "", err
+	}
+	return scan.fingerprint, nil
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var xmlEncodingDecl = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)(["'])([^"']*)(["'])`)
+
+// codePages single byte encodings understood by decodeXMLText, keyed by
+// normalized name
+var codePages = map[string]*[128]rune{
+	"windows-1250": &windows1250,
+	"windows-1251": &windows1251,
+	"windows-1252": &windows1252,
+	"windows-1253": &windows1253,
+	"windows-1254": &windows1254,
+	"windows-1255": &windows1255,
+	"windows-1256": &windows1256,
+	"windows-1257": &windows1257,
+	"windows-1258": &windows1258,
+	"iso-8859-9":   &iso88599,
+}
+
+// normalizeEncodingName maps common aliases ("cp1254", "x-cp1254",
+// "latin5", "UTF8") to one name.
+func normalizeEncodingName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "x-")
+	name = strings.ReplaceAll(name, "_", "-")
+
+	switch name {
+	case "utf8":
+		return "utf-8"
+	case "utf16", "ucs-2", "unicode":
+		return "utf-16"
+	case "utf16le", "unicodefffe":
+		return "utf-16le"
+	case "utf16be":
+		return "utf-16be"
+	case "latin1", "iso8859-1", "l1":
+		return "iso-8859-1"
+	case "latin5", "iso8859-9", "l5":
+		return "iso-8859-9"
+	case "ascii", "us-ascii":
+		return "us-ascii"
+	}
+	if strings.HasPrefix(name, "cp125") {
+		return "windows-" + name[2:]
+	}
+	return name
+}
+
+// decodeXMLText converts an ADMX/ADML document to UTF-8 so encoding/xml can
+// read it. The byte order mark decides the encoding, then the XML
+// declaration; without either the file must be UTF-8. The declaration of
+// the result is rewritten to utf-8.
+func decodeXMLText(data []byte) ([]byte, error) {
+	detected := ""
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		detected, data = "utf-8", data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		detected, data = "utf-16le", data[2:]
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		detected, data = "utf-16be", data[2:]
+	case bytes.HasPrefix(data, []byte{'<', 0, '?', 0}):
+		detected = "utf-16le"
+	case bytes.HasPrefix(data, []byte{0, '<', 0, '?'}):
+		detected = "utf-16be"
+	}
+
+	var text []byte
+	switch detected {
+	case "utf-16le":
+		text = []byte(decodeUTF16(data, false))
+	case "utf-16be":
+		text = []byte(decodeUTF16(data, true))
+	default:
+		text = data
+	}
+
+	declared := ""
+	if m := xmlEncodingDecl.FindSubmatch(text); m != nil {
+		declared = normalizeEncodingName(string(m[3]))
+	}
+
+	if detected != "" {
+		// The file is Unicode; the declaration may only name the same family
+		if declared == "us-ascii" {
+			declared = "utf-8"
+		}
+		if declared != "" && !strings.HasPrefix(detected, declared) {
+			return nil, fmt.Errorf("encoding mismatch: file is stored as %s but declares encoding=%q", strings.ToUpper(detected), declared)
+		}
+		return rewriteEncodingDecl(text), nil
+	}
+
+	switch declared {
+	case "", "utf-8", "us-ascii":
+		if !utf8.Valid(text) {
+			if declared == "" {
+				return nil, fmt.Errorf("file is not valid UTF-8 and declares no encoding")
+			}
+			return nil, fmt.Errorf("encoding mismatch: file declares encoding=%q but is not valid UTF-8", declared)
+		}
+		return text, nil
+	case "utf-16", "utf-16le", "utf-16be":
+		return nil, fmt.Errorf("encoding mismatch: file declares encoding=%q but has no UTF-16 byte order mark and is stored as 8-bit text", declared)
+	case "iso-8859-1":
+		return rewriteEncodingDecl(decodeSingleByte(text, nil)), nil
+	}
+
+	table, ok := codePages[declared]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", declared)
+	}
+	return rewriteEncodingDecl(decodeSingleByte(text, table)), nil
+}
+
+// decodeSingleByte converts single byte text to UTF-8. A nil table means
+// ISO-8859-1.
+func decodeSingleByte(data []byte, table *[128]rune) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			buf.WriteByte(c)
+		case table == nil:
+			buf.WriteRune(rune(c))
+		default:
+			buf.WriteRune(table[c-0x80])
+		}
+	}
+	return buf.Bytes()
+}
+
+func rewriteEncodingDecl(text []byte) []byte {
+	return xmlEncodingDecl.ReplaceAll(text, []byte("${1}${2}utf-8${4}"))
+}
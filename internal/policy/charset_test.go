@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestNormalizeEncodingName(t *testing.T) {
+	tests := map[string]string{
+		"UTF8":         "utf-8",
+		" utf-8 ":      "utf-8",
+		"Unicode":      "utf-16",
+		"cp1254":       "windows-1254",
+		"x-cp1251":     "windows-1251",
+		"latin5":       "iso-8859-9",
+		"ISO_8859-1":   "iso-8859-1",
+		"US-ASCII":     "us-ascii",
+		"windows-1252": "windows-1252",
+	}
+	for name, want := range tests {
+		if got := normalizeEncodingName(name); got != want {
+			t.Errorf("normalizeEncodingName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDecodeXMLText(t *testing.T) {
+	utf16le := func(s string, bom bool) []byte {
+		var data []byte
+		if bom {
+			data = []byte{0xFF, 0xFE}
+		}
+		for _, c := range utf16.Encode([]rune(s)) {
+			data = append(data, byte(c), byte(c>>8))
+		}
+		return data
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{"utf-8 without declaration", []byte(`<a>Ü</a>`), `<a>Ü</a>`, ""},
+		{"utf-8 bom", []byte("\xEF\xBB\xBF<?xml version=\"1.0\" encoding=\"utf-8\"?><a/>"), `<?xml version="1.0" encoding="utf-8"?><a/>`, ""},
+		{"utf-16le bom", utf16le(`<?xml version="1.0" encoding="utf-16"?><a>Ş</a>`, true), `<?xml version="1.0" encoding="utf-8"?><a>Ş</a>`, ""},
+		{"utf-16le without bom", utf16le(`<?xml version="1.0"?><a/>`, false), `<?xml version="1.0"?><a/>`, ""},
+		{"windows-1254", []byte("<?xml version='1.0' encoding='windows-1254'?><a>\xDE\xFE</a>"), `<?xml version='1.0' encoding='utf-8'?><a>Şş</a>`, ""},
+		{"iso-8859-1", []byte("<?xml version=\"1.0\" encoding=\"latin1\"?><a>\xE9</a>"), `<?xml version="1.0" encoding="utf-8"?><a>é</a>`, ""},
+		{"invalid utf-8", []byte("<a>\xE9</a>"), "", "declares no encoding"},
+		{"utf-16 declared in 8-bit file", []byte(`<?xml version="1.0" encoding="utf-16"?><a/>`), "", "no UTF-16 byte order mark"},
+		{"utf-16 file declaring a code page", utf16le(`<?xml version="1.0" encoding="windows-1252"?><a/>`, true), "", "encoding mismatch"},
+		{"unknown encoding", []byte(`<?xml version="1.0" encoding="koi8-r"?><a/>`), "", "unsupported encoding"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeXMLText(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeXMLText: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// RegistryExtensionGUID client-side extension that applies Registry.pol
+// settings. Policies without a clientExtension attribute are processed by
+// it.
+const RegistryExtensionGUID = "{35378EAC-683F-11D2-A89A-0080C7986CD2}"
+
+// Administrative Templates snap-in, recorded as the tool extension of the
+// client-side extensions it writes settings for
+const (
+	machineTemplatesToolGUID = "{D02B1F72-3407-48AE-BA88-E8213C6761F1}"
+	userTemplatesToolGUID    = "{D02B1F73-3407-48AE-BA88-E8213C6761F1}"
+)
+
+// knownClientExtensions friendly names of well-known client-side extensions,
+// keyed by upper-case GUID with braces
+var knownClientExtensions = map[string]string{
+	RegistryExtensionGUID:                    "Registry",
+	"{25537BA6-77A8-11D2-9B6C-0000F8080861}": "Folder Redirection",
+	"{C6DC5466-785A-11D2-84D0-00C04FB169F7}": "Software Installation",
+	"{827D319E-6EAC-11D2-A4EA-00C04F79F83A}": "Security",
+	"{42B5FAAE-6536-11D2-AE5A-0000F87571E3}": "Scripts",
+	"{B1BE8D72-6EAC-11D2-A4EA-00C04F79F83A}": "EFS Recovery",
+	"{E437BC1C-AA7D-11D2-A382-00C04F991E27}": "IP Security",
+	"{3610EDA5-77EF-11D2-8DC5-00C04FA31A66}": "Disk Quota",
+	"{0ACDD40C-75AC-47AB-BAA0-BF6DE7E7FE63}": "Wireless Group Policy",
+	"{B587E2B1-4D59-4E7E-AED9-22B9DF11D053}": "802.3 Wired Policy",
+	"{A2E30F80-D7DE-11D2-BBDE-00C04F86AE3B}": "Internet Explorer Branding",
+	"{8A28E2C5-8D06-49A4-A08C-632DAA493E17}": "Deployed Printer Connections",
+	"{426031C0-0B47-4852-B0CA-AC3D37BFCB39}": "QoS Packet Scheduler",
+	"{C631DF4C-088F-4156-B058-4375F0853CD8}": "Microsoft Offline Files",
+	"{F3CCC681-B74C-4060-9F26-CD84525DCA2A}": "Audit Policy Configuration",
+	"{7B849A69-220F-451E-B3FE-2CB811AF94AE}": "Internet Explorer User Accelerators",
+	"{CF7639F3-ABA2-41DB-97F2-81E2C5DBFC5D}": "Internet Explorer Machine Accelerators",
+	"{4D2F9B6F-1E52-4711-A382-6A8B1A003DE6}": "TCPIP",
+	"{F9C77450-3A41-477E-9310-9ACD617BD9E3}": "Group Policy Applications",
+	"{D76B9641-3288-4F75-942D-087DE603E3EA}": "AdmPwd (LAPS)",
+}
+
+// NormalizeExtensionGUID returns a GUID in the upper-case, braced form used
+// by Group Policy. Text that is not a GUID is returned trimmed.
+func NormalizeExtensionGUID(guid string) string {
+	guid = strings.TrimSpace(guid)
+	bare := strings.TrimSuffix(strings.TrimPrefix(guid, "{"), "}")
+	if len(bare) != 36 {
+		return guid
+	}
+	for i, c := range bare {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return guid
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return guid
+			}
+		}
+	}
+	return "{" + strings.ToUpper(bare) + "}"
+}
+
+// ClientExtensionName returns the friendly name of a client-side extension,
+// or false when the GUID is not a well-known one.
+func ClientExtensionName(guid string) (string, bool) {
+	name, ok := knownClientExtensions[NormalizeExtensionGUID(guid)]
+	return name, ok
+}
+
+// KnownClientExtensions returns the GUIDs of all well-known client-side
+// extensions, sorted
+func KnownClientExtensions() []string {
+	guids := make([]string, 0, len(knownClientExtensions))
+	for guid := range knownClientExtensions {
+		guids = append(guids, guid)
+	}
+	return sortedGUIDs(guids)
+}
+
+// ClientExtensions returns the client-side extensions that process the
+// policy: the one of the policy, or the registry extension when none is
+// declared, followed by those declared on elements.
+func (p *AdmxPolicy) ClientExtensions() []string {
+	first := RegistryExtensionGUID
+	if p.ClientExtension != "" {
+		first = NormalizeExtensionGUID(p.ClientExtension)
+	}
+	result := []string{first}
+	seen := map[string]bool{first: true}
+
+	for _, elem := range p.Elements {
+		guid := elem.GetClientExtension()
+		if guid == "" {
+			continue
+		}
+		guid = NormalizeExtensionGUID(guid)
+		if !seen[guid] {
+			seen[guid] = true
+			result = append(result, guid)
+		}
+	}
+	return result
+}
+
+// templatesToolGUID returns the Administrative Templates tool extension of a
+// section
+func templatesToolGUID(section AdmxPolicySection) string {
+	if section == User {
+		return userTemplatesToolGUID
+	}
+	return machineTemplatesToolGUID
+}
+
+// sortedGUIDs sorts GUIDs the way Group Policy expects them in extension
+// name lists
+func sortedGUIDs(guids []string) []string {
+	sort.Slice(guids, func(i, j int) bool {
+		return strings.ToUpper(guids[i]) < strings.ToUpper(guids[j])
+	})
+	return guids
+}
@@ -7,6 +7,8 @@ type PolicyPlusCategory struct {
 	Children           []*PolicyPlusCategory
 	DisplayName        string
 	DisplayExplanation string
+	SeeAlso            []string
+	Keywords           []string
 	Policies           []*PolicyPlusPolicy
 	RawCategory        *AdmxCategory
 }
@@ -43,5 +45,7 @@ type PolicyPlusPolicy struct {
 	DisplayExplanation string
 	SupportedOn        *PolicyPlusSupport
 	Presentation       *Presentation
+	SeeAlso            []string
+	Keywords           []string
 	RawPolicy          *AdmxPolicy
 }
@@ -0,0 +1,188 @@
+package policy
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ExplanationBlockType kind of an explanation block
+type ExplanationBlockType int
+
+const (
+	ExplanationParagraph ExplanationBlockType = iota
+	ExplanationBulletList
+	ExplanationNumberedList
+	ExplanationSupportedOn
+)
+
+// ExplanationBlock a paragraph, a list or a "Supported on" reference. A
+// paragraph holds one line per entry; a list holds one item per entry.
+type ExplanationBlock struct {
+	Type  ExplanationBlockType
+	Lines []string
+}
+
+// Explanation explanation text split into blocks
+type Explanation struct {
+	Blocks []ExplanationBlock
+}
+
+var (
+	explanationBullet   = regexp.MustCompile(`^\s*(?:[-*]\s+|[•·▪]\s*)(.*)$`)
+	explanationNumbered = regexp.MustCompile(`^\s*(?:\d{1,2}|[a-zA-Z])[.)]\s+(.*)$`)
+	explanationSupport  = regexp.MustCompile(`(?i)^\s*supported on\s*:\s*(.*)$`)
+	explanationURL      = regexp.MustCompile(`https?://[^\s<>"]+[^\s<>".,;:!?)\]']`)
+)
+
+// FormatExplanation splits ADML explanation text into paragraphs, lists
+// and "Supported on" references. Blank lines separate paragraphs; lines
+// starting with a bullet or a number form lists.
+func FormatExplanation(text string) *Explanation {
+	result := &Explanation{}
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var current *ExplanationBlock
+	flush := func() {
+		if current != nil && len(current.Lines) > 0 {
+			result.Blocks = append(result.Blocks, *current)
+		}
+		current = nil
+	}
+	start := func(blockType ExplanationBlockType) {
+		if current == nil || current.Type != blockType {
+			flush()
+			current = &ExplanationBlock{Type: blockType}
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if m := explanationSupport.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			result.Blocks = append(result.Blocks, ExplanationBlock{
+				Type:  ExplanationSupportedOn,
+				Lines: []string{strings.TrimSpace(m[1])},
+			})
+			continue
+		}
+		if m := explanationBullet.FindStringSubmatch(line); m != nil && strings.TrimSpace(m[1]) != "" {
+			start(ExplanationBulletList)
+			current.Lines = append(current.Lines, strings.TrimSpace(m[1]))
+			continue
+		}
+		if m := explanationNumbered.FindStringSubmatch(line); m != nil {
+			start(ExplanationNumberedList)
+			current.Lines = append(current.Lines, strings.TrimSpace(m[1]))
+			continue
+		}
+
+		// Indented text right after a list item continues that item
+		if current != nil && current.Type != ExplanationParagraph && line != strings.TrimLeft(line, " \t") {
+			current.Lines[len(current.Lines)-1] += " " + trimmed
+			continue
+		}
+
+		start(ExplanationParagraph)
+		current.Lines = append(current.Lines, trimmed)
+	}
+	flush()
+
+	return result
+}
+
+// HTML renders the explanation as HTML. All text is escaped and only
+// http(s) links are produced, so the result is safe to embed.
+func (e *Explanation) HTML() string {
+	var sb strings.Builder
+	for _, block := range e.Blocks {
+		switch block.Type {
+		case ExplanationParagraph:
+			sb.WriteString("<p>")
+			for i, line := range block.Lines {
+				if i > 0 {
+					sb.WriteString("<br>")
+				}
+				sb.WriteString(linkifyHTML(line))
+			}
+			sb.WriteString("</p>")
+		case ExplanationBulletList, ExplanationNumberedList:
+			tag := "ul"
+			if block.Type == ExplanationNumberedList {
+				tag = "ol"
+			}
+			sb.WriteString("<" + tag + ">")
+			for _, item := range block.Lines {
+				sb.WriteString("<li>" + linkifyHTML(item) + "</li>")
+			}
+			sb.WriteString("</" + tag + ">")
+		case ExplanationSupportedOn:
+			sb.WriteString(`<p class="supported-on"><strong>Supported on:</strong> ` + linkifyHTML(block.Lines[0]) + "</p>")
+		}
+	}
+	return sb.String()
+}
+
+// Markdown renders the explanation as Markdown.
+func (e *Explanation) Markdown() string {
+	var parts []string
+	for _, block := range e.Blocks {
+		var lines []string
+		switch block.Type {
+		case ExplanationParagraph:
+			for _, line := range block.Lines {
+				lines = append(lines, linkifyMarkdown(line))
+			}
+			parts = append(parts, strings.Join(lines, "  \n"))
+		case ExplanationBulletList:
+			for _, item := range block.Lines {
+				lines = append(lines, "- "+linkifyMarkdown(item))
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case ExplanationNumberedList:
+			for i, item := range block.Lines {
+				lines = append(lines, strconv.Itoa(i+1)+". "+linkifyMarkdown(item))
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case ExplanationSupportedOn:
+			parts = append(parts, "**Supported on:** "+linkifyMarkdown(block.Lines[0]))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func linkifyHTML(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range explanationURL.FindAllStringIndex(text, -1) {
+		sb.WriteString(html.EscapeString(text[last:loc[0]]))
+		url := html.EscapeString(text[loc[0]:loc[1]])
+		sb.WriteString(`<a href="` + url + `" target="_blank" rel="noopener noreferrer">` + url + "</a>")
+		last = loc[1]
+	}
+	sb.WriteString(html.EscapeString(text[last:]))
+	return sb.String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+func linkifyMarkdown(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range explanationURL.FindAllStringIndex(text, -1) {
+		sb.WriteString(markdownEscaper.Replace(text[last:loc[0]]))
+		sb.WriteString("<" + text[loc[0]:loc[1]] + ">")
+		last = loc[1]
+	}
+	sb.WriteString(markdownEscaper.Replace(text[last:]))
+	return sb.String()
+}
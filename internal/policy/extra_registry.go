@@ -0,0 +1,290 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// policyTrees are the registry trees Group Policy removes values from when
+// a policy is no longer applied. Values a policy writes elsewhere stay
+// behind ("tattooing").
+var policyTrees = []string{
+	`Software\Policies`,
+	`Software\Microsoft\Windows\CurrentVersion\Policies`,
+}
+
+// IsPolicyKey reports whether a key lies in one of the policy trees
+func IsPolicyKey(key string) bool {
+	key = strings.ToLower(strings.Trim(key, "\\"))
+	for _, tree := range policyTrees {
+		tree = strings.ToLower(tree)
+		if key == tree || strings.HasPrefix(key, tree+"\\") {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryEntry a raw registry value as stored in Registry.pol or the
+// registry. Type is a registry type name such as REG_SZ, Kind its number.
+type RegistryEntry struct {
+	Key       string
+	ValueName string
+	Type      string
+	Kind      ValueType
+	Data      interface{}
+}
+
+// ExtraRegistryValue a registry value that no loaded policy writes, which
+// GPMC shows under Extra Registry Settings. Deleted is set for a **del.
+// marker, which makes Group Policy delete the value; other markers, such
+// as **delvals., keep their name. Suggestion is nil when no template is
+// known for the key.
+type ExtraRegistryValue struct {
+	RegistryEntry
+	Section    AdmxPolicySection
+	Source     string
+	Deleted    bool
+	Suggestion *NamespaceSuggestion
+}
+
+// NamespaceSuggestion the template namespace that probably describes a
+// registry key. Loaded is set when the namespace is loaded but does not
+// know the value, which usually means the template is older than the
+// software that reads it. Template is the usual file name, when known.
+type NamespaceSuggestion struct {
+	Namespace string
+	Template  string
+	Loaded    bool
+	Reason    string
+}
+
+// knownTemplate a template that is not shipped with Windows, found by the
+// key its policies are written under
+type knownTemplate struct {
+	key       string
+	namespace string
+	template  string
+}
+
+var knownTemplates = []knownTemplate{
+	{`Software\Policies\Microsoft\Edge`, "Microsoft.Policies.Edge", "msedge.admx"},
+	{`Software\Policies\Microsoft\EdgeUpdate`, "Microsoft.Policies.Update", "msedgeupdate.admx"},
+	{`Software\Policies\Microsoft\OneDrive`, "Microsoft.Policies.OneDriveNGSC", "OneDrive.admx"},
+	{`Software\Policies\Microsoft\Office\16.0`, "office16.Office.Microsoft.Policies.Windows", "office16.admx"},
+	{`Software\Policies\Google\Chrome`, "Google.Policies.Chrome", "chrome.admx"},
+	{`Software\Policies\Google\Update`, "Google.Policies.Update", "GoogleUpdate.admx"},
+	{`Software\Policies\Mozilla\Firefox`, "Mozilla.Policies.Firefox", "firefox.admx"},
+}
+
+// minSuggestionDepth keeps the policy roots, such as Software\Policies,
+// from suggesting a namespace
+const minSuggestionDepth = 3
+
+// sharedPolicyKeys are parent keys that many templates write in, too
+// general to suggest a namespace for the keys below them
+var sharedPolicyKeys = map[string]bool{
+	`software\policies\microsoft`:                                 true,
+	`software\policies\microsoft\windows`:                         true,
+	`software\microsoft\windows\currentversion\policies`:          true,
+	`software\microsoft\windows\currentversion\policies\explorer`: true,
+}
+
+// ExtraRegistryValues returns the entries of section that no loaded policy
+// writes, in the order given. source names where the entries were read.
+func (b *AdmxBundle) ExtraRegistryValues(section AdmxPolicySection, source string, entries []RegistryEntry) []*ExtraRegistryValue {
+	var extra []*ExtraRegistryValue
+	suggestions := make(map[string]*NamespaceSuggestion)
+	for _, entry := range entries {
+		value := &ExtraRegistryValue{RegistryEntry: entry, Section: section, Source: source}
+		if name, ok := strings.CutPrefix(entry.ValueName, "**del."); ok {
+			value.ValueName, value.Deleted = name, true
+		}
+
+		var explained bool
+		if strings.HasPrefix(value.ValueName, "**") || value.ValueName == "" {
+			explained = b.writesRegistryKey(section, value.Key)
+		} else {
+			explained = b.ExplainsRegistryValue(section, value.Key, value.ValueName)
+		}
+		if explained {
+			continue
+		}
+
+		lower := strings.ToLower(value.Key)
+		suggestion, ok := suggestions[lower]
+		if !ok {
+			suggestion = b.SuggestNamespace(section, value.Key)
+			suggestions[lower] = suggestion
+		}
+		value.Suggestion = suggestion
+		extra = append(extra, value)
+	}
+	return extra
+}
+
+// ExplainsRegistryValue reports whether a loaded policy of section writes
+// or deletes a registry value
+func (b *AdmxBundle) ExplainsRegistryValue(section AdmxPolicySection, key, valueName string) bool {
+	for _, match := range b.LookupRegistry(section, key+"\\"+valueName) {
+		if !match.KeyOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// writesRegistryKey reports whether a loaded policy of section writes a
+// value in key
+func (b *AdmxBundle) writesRegistryKey(section AdmxPolicySection, key string) bool {
+	for _, match := range b.registryLookupIndex().keys[strings.ToLower(strings.Trim(key, "\\"))] {
+		if sectionsOverlap(section, match.Policy.RawPolicy.Section) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestNamespace guesses the template namespace that describes a
+// registry key: a known template whose key contains it, or else the loaded
+// namespace whose policies write in the key or the closest parent key.
+// It returns nil when neither is found.
+func (b *AdmxBundle) SuggestNamespace(section AdmxPolicySection, key string) *NamespaceSuggestion {
+	key = strings.Trim(key, "\\")
+	lower := strings.ToLower(key)
+	for _, known := range knownTemplates {
+		prefix := strings.ToLower(known.key)
+		if lower != prefix && !strings.HasPrefix(lower, prefix+"\\") {
+			continue
+		}
+		_, loaded := b.NamespaceSources[known.namespace]
+		suggestion := &NamespaceSuggestion{Namespace: known.namespace, Template: known.template, Loaded: loaded}
+		if loaded {
+			suggestion.Reason = fmt.Sprintf("%s is loaded but does not describe the value; a newer version of %s is probably needed", known.namespace, known.template)
+		} else {
+			suggestion.Reason = fmt.Sprintf("values under %s are described by %s", known.key, known.template)
+		}
+		return suggestion
+	}
+
+	index := b.registryLookupIndex()
+	parts := strings.Split(key, "\\")
+	for depth := len(parts); depth > 0 && (depth == len(parts) || depth >= minSuggestionDepth); depth-- {
+		parent := strings.Join(parts[:depth], "\\")
+		if depth < len(parts) && sharedPolicyKeys[strings.ToLower(parent)] {
+			break
+		}
+		counts := make(map[string]int)
+		for _, match := range index.keys[strings.ToLower(parent)] {
+			if sectionsOverlap(section, match.Policy.RawPolicy.Section) {
+				counts[match.Policy.RawPolicy.DefinedIn.AdmxNamespace]++
+			}
+		}
+		if namespace := mostCommon(counts); namespace != "" {
+			return &NamespaceSuggestion{
+				Namespace: namespace,
+				Template:  fileName(b.NamespaceSources[namespace]),
+				Loaded:    true,
+				Reason:    fmt.Sprintf("policies of %s write other values in %s; its template is probably older than the software that reads the value", namespace, parent),
+			}
+		}
+	}
+	return nil
+}
+
+// mostCommon returns the key with the highest count, the smallest on a tie
+func mostCommon(counts map[string]int) string {
+	best := ""
+	for name, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+func fileName(src *NamespaceSource) string {
+	if src == nil {
+		return ""
+	}
+	return src.File[strings.LastIndexAny(src.File, "\\/")+1:]
+}
+
+func sortRegistryEntries(entries []RegistryEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ki, kj := strings.ToLower(entries[i].Key), strings.ToLower(entries[j].Key)
+		if ki != kj {
+			return ki < kj
+		}
+		return strings.ToLower(entries[i].ValueName) < strings.ToLower(entries[j].ValueName)
+	})
+}
+
+// ParseRegistryData converts a value given as JSON data to the type named
+// by typeName: REG_SZ, REG_EXPAND_SZ, REG_DWORD or REG_MULTI_SZ. DWORDs
+// may be numbers or decimal or 0x text; multi-strings arrays or text with
+// one entry per line.
+func ParseRegistryData(typeName string, data interface{}) (ValueType, interface{}, error) {
+	typeName = strings.ToUpper(strings.TrimSpace(typeName))
+	switch typeName {
+	case RegTypeString, RegTypeExpandSz:
+		text, ok := data.(string)
+		if !ok {
+			return NONE, nil, fmt.Errorf("%s data must be text", typeName)
+		}
+		if typeName == RegTypeExpandSz {
+			return EXPAND_SZ, text, nil
+		}
+		return SZ, text, nil
+	case RegTypeDWord:
+		var n uint64
+		var err error
+		switch v := data.(type) {
+		case float64:
+			if v < 0 || v > 0xFFFFFFFF || v != float64(uint32(v)) {
+				return NONE, nil, fmt.Errorf("REG_DWORD data must be a whole number from 0 to 4294967295")
+			}
+			n = uint64(v)
+		case string:
+			n, err = parseDword(v)
+			if err != nil {
+				return NONE, nil, err
+			}
+		default:
+			return NONE, nil, fmt.Errorf("REG_DWORD data must be a number")
+		}
+		return DWORD, uint32(n), nil
+	case RegTypeMultiSz:
+		switch v := data.(type) {
+		case string:
+			return MULTI_SZ, strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n"), nil
+		case []interface{}:
+			lines := make([]string, 0, len(v))
+			for _, item := range v {
+				text, ok := item.(string)
+				if !ok {
+					return NONE, nil, fmt.Errorf("REG_MULTI_SZ entries must be text")
+				}
+				lines = append(lines, text)
+			}
+			return MULTI_SZ, lines, nil
+		}
+		return NONE, nil, fmt.Errorf("REG_MULTI_SZ data must be an array of text")
+	}
+	return NONE, nil, fmt.Errorf("unsupported type %q: REG_SZ, REG_EXPAND_SZ, REG_DWORD or REG_MULTI_SZ", typeName)
+}
+
+func parseDword(text string) (uint64, error) {
+	text = strings.TrimSpace(text)
+	base := 10
+	if hex, ok := strings.CutPrefix(strings.ToLower(text), "0x"); ok {
+		text, base = hex, 16
+	}
+	n, err := strconv.ParseUint(text, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("REG_DWORD data must be a whole number from 0 to 4294967295")
+	}
+	return n, nil
+}
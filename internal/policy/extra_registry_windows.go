@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// policyKeysRoot is the registry tree Group Policy writes policies to
+const policyKeysRoot = `Software\Policies`
+
+// ReadExtraRegistry returns the values of the Registry.pol of the local GPO
+// for section that no loaded policy writes. With policyKeys, the values
+// below Software\Policies in the registry that Registry.pol does not hold
+// are included as well. A missing Registry.pol has no values.
+func (b *AdmxBundle) ReadExtraRegistry(section AdmxPolicySection, policyKeys bool) ([]*ExtraRegistryValue, error) {
+	polPath, err := GetPolPath(section)
+	if err != nil {
+		return nil, err
+	}
+
+	pol, err := Load(polPath)
+	if os.IsNotExist(err) {
+		pol, err = NewPolFile(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s could not be read: %w", polPath, err)
+	}
+	extra := b.ExtraRegistryValues(section, SourcePolFile, pol.Entries())
+	if !policyKeys {
+		return extra, nil
+	}
+
+	source, err := NewRegistrySource(section)
+	if err != nil {
+		return nil, err
+	}
+	var entries []RegistryEntry
+	for _, entry := range readRegistryTree(source.RootKey, policyKeysRoot) {
+		if !pol.ContainsValue(entry.Key, entry.ValueName) {
+			entries = append(entries, entry)
+		}
+	}
+	return append(extra, b.ExtraRegistryValues(section, SourceRegistry, entries)...), nil
+}
+
+// readRegistryTree returns the values of a key and its subkeys, sorted.
+// Keys that cannot be opened are skipped.
+func readRegistryTree(root registry.Key, path string) []RegistryEntry {
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil
+	}
+	defer k.Close()
+
+	var entries []RegistryEntry
+	names, _ := k.ReadValueNames(0)
+	for _, name := range names {
+		if entry, ok := readRegistryEntry(k, path, name); ok {
+			entries = append(entries, entry)
+		}
+	}
+	subkeys, _ := k.ReadSubKeyNames(0)
+	for _, subkey := range subkeys {
+		entries = append(entries, readRegistryTree(root, path+"\\"+subkey)...)
+	}
+	sortRegistryEntries(entries)
+	return entries
+}
+
+func readRegistryEntry(k registry.Key, path, name string) (RegistryEntry, bool) {
+	size, valType, err := k.GetValue(name, nil)
+	if err != nil {
+		return RegistryEntry{}, false
+	}
+
+	entry := RegistryEntry{Key: path, ValueName: name, Type: ValueType(valType).String(), Kind: ValueType(valType)}
+	switch valType {
+	case registry.SZ, registry.EXPAND_SZ:
+		entry.Data, _, err = k.GetStringValue(name)
+	case registry.DWORD:
+		var n uint64
+		n, _, err = k.GetIntegerValue(name)
+		entry.Data = uint32(n)
+	case registry.QWORD:
+		entry.Data, _, err = k.GetIntegerValue(name)
+	case registry.MULTI_SZ:
+		entry.Data, _, err = k.GetStringsValue(name)
+	default:
+		// REG_BINARY, and types such as REG_NONE or REG_LINK that the
+		// registry package has no getter for, as raw bytes
+		buf := make([]byte, size)
+		size, _, err = k.GetValue(name, buf)
+		entry.Data = buf[:min(size, len(buf))]
+	}
+	return entry, err == nil
+}
+
+// SetRegistryValue writes a raw value to the registry and, for the
+// registry sources of the local GPO, to its Registry.pol, replacing a
+// **del. marker of the value. kind is SZ, EXPAND_SZ, DWORD or MULTI_SZ,
+// with data typed as ParseRegistryData returns it.
+func SetRegistryValue(source PolicySource, key, valueName string, kind ValueType, data interface{}) error {
+	var regKind RegistryValueKind
+	switch kind {
+	case SZ:
+		regKind = RegString
+	case EXPAND_SZ:
+		regKind = RegExpandString
+	case DWORD:
+		regKind = RegDWord
+	case MULTI_SZ:
+		regKind = RegMultiString
+	default:
+		return fmt.Errorf("unsupported registry type: %s", kind)
+	}
+
+	if err := source.SetValue(key, valueName, data, regKind); err != nil {
+		return err
+	}
+	return updateRegistryPol(source, func(pol *PolFile) error {
+		pol.ForgetValue(key, valueName)
+		return pol.SetValue(key, valueName, data, kind)
+	})
+}
+
+// DeleteRegistryValue removes a raw value, or a marker such as
+// **delvals., from Registry.pol together with its **del. marker, and
+// deletes the value from the registry
+func DeleteRegistryValue(source PolicySource, key, valueName string) error {
+	if err := updateRegistryPol(source, func(pol *PolFile) error {
+		pol.ForgetValue(key, valueName)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return source.DeleteValue(key, valueName)
+}
+
+// updateRegistryPol applies a change to the Registry.pol that belongs to a
+// registry source and records the registry extension in gpt.ini
+func updateRegistryPol(source PolicySource, change func(pol *PolFile) error) error {
+	section, ok := sourceSection(source)
+	if !ok {
+		return nil
+	}
+	polPath, err := GetPolPath(section)
+	if err != nil {
+		return err
+	}
+
+	pol, err := Load(polPath)
+	if os.IsNotExist(err) {
+		pol, err = NewPolFile(), nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s could not be read: %w", polPath, err)
+	}
+	if err := change(pol); err != nil {
+		return err
+	}
+	if err := pol.Save(polPath); err != nil {
+		return err
+	}
+	return UpdateGptIni(GetGptIniPath(), section, []string{RegistryExtensionGUID})
+}
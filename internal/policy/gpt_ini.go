@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// extensionGroup one [{cse}{tool}...] entry of a gPC*ExtensionNames value
+type extensionGroup struct {
+	Extension string
+	Tools     []string
+}
+
+// parseExtensionNames parses "[{cse}{tool}][{cse}{tool}{tool}]"
+func parseExtensionNames(value string) []extensionGroup {
+	var groups []extensionGroup
+	for _, part := range strings.Split(value, "]") {
+		part = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(part), "["))
+		if part == "" {
+			continue
+		}
+		var guids []string
+		for _, guid := range strings.Split(part, "}") {
+			if guid = strings.TrimSpace(guid); guid != "" {
+				guids = append(guids, NormalizeExtensionGUID(guid+"}"))
+			}
+		}
+		if len(guids) > 0 {
+			groups = append(groups, extensionGroup{Extension: guids[0], Tools: guids[1:]})
+		}
+	}
+	return groups
+}
+
+func formatExtensionNames(groups []extensionGroup) string {
+	byExtension := make(map[string]extensionGroup)
+	extensions := make([]string, 0, len(groups))
+	for _, group := range groups {
+		byExtension[group.Extension] = group
+		extensions = append(extensions, group.Extension)
+	}
+
+	var sb strings.Builder
+	for _, ext := range sortedGUIDs(extensions) {
+		sb.WriteString("[" + ext)
+		for _, tool := range sortedGUIDs(byExtension[ext].Tools) {
+			sb.WriteString(tool)
+		}
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+// addExtensions adds each extension with the given tool extension to a
+// gPC*ExtensionNames value
+func addExtensions(value string, extensions []string, tool string) string {
+	groups := parseExtensionNames(value)
+	for _, ext := range extensions {
+		ext = NormalizeExtensionGUID(ext)
+		found := false
+		for i := range groups {
+			if groups[i].Extension != ext {
+				continue
+			}
+			found = true
+			hasTool := false
+			for _, t := range groups[i].Tools {
+				if t == tool {
+					hasTool = true
+					break
+				}
+			}
+			if !hasTool {
+				groups[i].Tools = append(groups[i].Tools, tool)
+			}
+		}
+		if !found {
+			groups = append(groups, extensionGroup{Extension: ext, Tools: []string{tool}})
+		}
+	}
+	return formatExtensionNames(groups)
+}
+
+// UpdateGptIni records the client-side extensions of changed settings in the
+// gpt.ini of a GPO and increments the version of the section, so that Group
+// Policy runs those extensions on the next refresh. The machine version is
+// kept in the low and the user version in the high 16 bits. A missing file
+// is created.
+func UpdateGptIni(path string, section AdmxPolicySection, extensions []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read gpt.ini: %w", err)
+	}
+
+	namesKey := "gPCMachineExtensionNames"
+	if section == User {
+		namesKey = "gPCUserExtensionNames"
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// Locate [General]; keys are read and written inside it
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if start >= 0 {
+				end = i
+				break
+			}
+			if strings.EqualFold(trimmed, "[General]") {
+				start = i
+			}
+		}
+	}
+	if start < 0 {
+		lines = append(lines, "[General]")
+		start, end = len(lines)-1, len(lines)
+	}
+
+	versionLine, namesLine := -1, -1
+	for i := start + 1; i < end; i++ {
+		key, _, ok := strings.Cut(lines[i], "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.EqualFold(strings.TrimSpace(key), "Version"):
+			versionLine = i
+		case strings.EqualFold(strings.TrimSpace(key), namesKey):
+			namesLine = i
+		}
+	}
+
+	var version uint64
+	if versionLine >= 0 {
+		_, value, _ := strings.Cut(lines[versionLine], "=")
+		version, _ = strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	}
+	machineVersion, userVersion := version&0xFFFF, version>>16
+	if section == User {
+		userVersion = (userVersion + 1) & 0xFFFF
+	} else {
+		machineVersion = (machineVersion + 1) & 0xFFFF
+	}
+	versionText := "Version=" + strconv.FormatUint(userVersion<<16|machineVersion, 10)
+
+	namesValue := ""
+	if namesLine >= 0 {
+		_, namesValue, _ = strings.Cut(lines[namesLine], "=")
+	}
+	namesText := namesKey + "=" + addExtensions(namesValue, extensions, templatesToolGUID(section))
+
+	var inserted []string
+	if versionLine >= 0 {
+		lines[versionLine] = versionText
+	} else {
+		inserted = append(inserted, versionText)
+	}
+	if namesLine >= 0 {
+		lines[namesLine] = namesText
+	} else {
+		inserted = append(inserted, namesText)
+	}
+	if len(inserted) > 0 {
+		lines = append(lines[:end], append(inserted, lines[end:]...)...)
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0644)
+}
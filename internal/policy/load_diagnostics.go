@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+func (t AdmxLoadFailType) String() string {
+	switch t {
+	case BadAdmxParse:
+		return "BadAdmxParse"
+	case BadAdmx:
+		return "BadAdmx"
+	case NoAdml:
+		return "NoAdml"
+	case BadAdmlParse:
+		return "BadAdmlParse"
+	case BadAdml:
+		return "BadAdml"
+	case DuplicateNamespace:
+		return "DuplicateNamespace"
+	case BadAdmParse:
+		return "BadAdmParse"
+	default:
+		return "Unknown"
+	}
+}
+
+// LoadFailures returns the failures of all loads into the bundle, in the
+// order they were reported.
+func (b *AdmxBundle) LoadFailures() []*AdmxLoadFailure {
+	return append([]*AdmxLoadFailure{}, b.loadFailures...)
+}
+
+// TemplateFileInfo a loaded template file and what it contributes
+type TemplateFileInfo struct {
+	Namespace     string
+	File          string
+	Root          string
+	Legacy        bool
+	Revision      Revision
+	Prefixes      map[string]string
+	AdmlFile      string
+	Locale        string
+	Locales       []string
+	PolicyCount   int
+	CategoryCount int
+}
+
+// TemplateInventory lists the loaded template files sorted by namespace.
+// AdmlFile is the ADML used for the bundle's own language; Locales are all
+// locales with an ADML.
+func (b *AdmxBundle) TemplateInventory() []TemplateFileInfo {
+	policies := make(map[*AdmxFile]int)
+	for _, pol := range b.Policies {
+		policies[pol.RawPolicy.DefinedIn]++
+	}
+	categories := make(map[*AdmxFile]int)
+	for _, cat := range b.FlatCategories {
+		categories[cat.RawCategory.DefinedIn]++
+	}
+
+	result := make([]TemplateFileInfo, 0, len(b.namespaces))
+	for ns, admx := range b.namespaces {
+		info := TemplateFileInfo{
+			Namespace:     ns,
+			File:          admx.SourceFile,
+			Legacy:        isAdmPath(admx.SourceFile),
+			Revision:      admx.Revision,
+			Prefixes:      admx.Prefixes,
+			Locales:       []string{},
+			PolicyCount:   policies[admx],
+			CategoryCount: categories[admx],
+		}
+		if src, ok := b.NamespaceSources[ns]; ok {
+			info.File, info.Root = src.File, src.Root
+		}
+		if adml := b.sourceFiles[admx]; adml != nil {
+			info.AdmlFile, info.Locale = adml.SourceFile, adml.Locale
+		}
+		for locale := range b.localizedFiles[admx] {
+			info.Locales = append(info.Locales, locale)
+		}
+		sort.Slice(info.Locales, func(i, j int) bool {
+			return strings.ToLower(info.Locales[i]) < strings.ToLower(info.Locales[j])
+		})
+		if info.Prefixes == nil {
+			info.Prefixes = map[string]string{}
+		}
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// buildAdmlChain returns the ADMLs used to resolve the strings of an ADMX
+// file, in order: the preferred locales, their base languages, en-US, the
+// ADML chosen at load time and then any other available locale.
+func (b *AdmxBundle) buildAdmlChain(admx *AdmxFile) []*AdmlFile {
+	var chain []*AdmlFile
+	added := make(map[*AdmlFile]struct{})
+	add := func(adml *AdmlFile) {
+		if adml == nil {
+			return
+		}
+		if _, ok := added[adml]; ok {
+			return
+		}
+		added[adml] = struct{}{}
+		chain = append(chain, adml)
+	}
+
+	localized := b.localizedFiles[admx]
+	names := make([]string, 0, len(localized))
+	for name := range localized {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, candidate := range expandLocaleCandidates(b.languageCodes) {
+		for _, name := range names {
+			if strings.EqualFold(name, candidate) {
+				add(localized[name])
+			}
+		}
+		// A bare language matches any region of it
+		if !strings.Contains(candidate, "-") {
+			for _, name := range names {
+				if strings.EqualFold(strings.SplitN(name, "-", 2)[0], candidate) {
+					add(localized[name])
+				}
+			}
+		}
+	}
+	add(b.sourceFiles[admx])
+	for _, name := range names {
+		add(localized[name])
+	}
+	return chain
+}
+
+// lookupString resolves a string ID through the fallback chain of an ADMX
+// file. It also returns the ADML the string came from.
+func (b *AdmxBundle) lookupString(stringID string, admx *AdmxFile) (string, *AdmlFile, bool) {
+	for _, adml := range b.chains[admx] {
+		if str, ok := adml.StringTable[stringID]; ok {
+			return str, adml, true
+		}
+	}
+	return "", nil, false
+}
+
+// LocalizationCoverage strings of one ADMX file that are not in its
+// preferred ADML
+type LocalizationCoverage struct {
+	File      string
+	Namespace string
+	Locale    string
+	Total     int
+	Fallbacks []StringFallback
+}
+
+// StringFallback a string resolved from a later ADML of the chain. Locale is
+// empty when no ADML has the string.
+type StringFallback struct {
+	Code   string
+	Locale string
+}
+
+// Coverage reports, for every ADMX file whose preferred ADML lacks strings
+// or presentations, where they were resolved from instead.
+func (b *AdmxBundle) Coverage() []*LocalizationCoverage {
+	var report []*LocalizationCoverage
+
+	for _, admx := range b.namespaces {
+		chain := b.chains[admx]
+		if len(chain) == 0 {
+			continue
+		}
+		preferred := chain[0]
+		entry := &LocalizationCoverage{
+			File:      admx.SourceFile,
+			Namespace: admx.AdmxNamespace,
+			Locale:    preferred.Locale,
+		}
+
+		for _, code := range referencedCodes(admx) {
+			entry.Total++
+			var found *AdmlFile
+			if strings.HasPrefix(code, "$(presentation.") {
+				presID := code[15 : len(code)-1]
+				for _, adml := range chain {
+					if _, ok := adml.PresentationTable[presID]; ok {
+						found = adml
+						break
+					}
+				}
+			} else {
+				_, found, _ = b.lookupString(code[9:len(code)-1], admx)
+			}
+			if found == preferred {
+				continue
+			}
+			fallback := StringFallback{Code: code}
+			if found != nil {
+				fallback.Locale = found.Locale
+			}
+			entry.Fallbacks = append(entry.Fallbacks, fallback)
+		}
+
+		if len(entry.Fallbacks) > 0 {
+			report = append(report, entry)
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].File < report[j].File
+	})
+	return report
+}
+
+// referencedCodes returns the distinct $(string.X) and $(presentation.X)
+// references of an ADMX file in a stable order.
+func referencedCodes(admx *AdmxFile) []string {
+	seen := make(map[string]struct{})
+	var codes []string
+	add := func(code string) {
+		if !strings.HasPrefix(code, "$(string.") && !strings.HasPrefix(code, "$(presentation.") {
+			return
+		}
+		if _, ok := seen[code]; ok {
+			return
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+
+	for _, cat := range admx.Categories {
+		add(cat.DisplayCode)
+		add(cat.ExplainCode)
+	}
+	for _, product := range admx.Products {
+		add(product.DisplayCode)
+	}
+	for _, support := range admx.SupportedOnDefinitions {
+		add(support.DisplayCode)
+	}
+	for _, pol := range admx.Policies {
+		add(pol.DisplayCode)
+		add(pol.ExplainCode)
+		add(pol.PresentationID)
+		for _, elem := range pol.Elements {
+			if enum, ok := elem.(*EnumPolicyElement); ok {
+				for _, item := range enum.Items {
+					add(item.DisplayCode)
+				}
+			}
+		}
+	}
+	return codes
+}
@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamespaceConflictRule decides which file wins when two templates define
+// the same namespace
+type NamespaceConflictRule int
+
+const (
+	// HighestRevision keeps the file with the highest revision attribute;
+	// on a tie the earlier root wins
+	HighestRevision NamespaceConflictRule = iota
+	// FirstRootWins keeps the file found first
+	FirstRootWins
+	// ConflictError keeps the file found first and reports the others as
+	// DuplicateNamespace failures
+	ConflictError
+)
+
+func (r NamespaceConflictRule) String() string {
+	switch r {
+	case FirstRootWins:
+		return "first"
+	case ConflictError:
+		return "error"
+	default:
+		return "revision"
+	}
+}
+
+// ParseNamespaceConflictRule parses "revision", "first" or "error"
+func ParseNamespaceConflictRule(value string) (NamespaceConflictRule, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "revision", "":
+		return HighestRevision, nil
+	case "first":
+		return FirstRootWins, nil
+	case "error":
+		return ConflictError, nil
+	default:
+		return HighestRevision, fmt.Errorf("invalid namespace conflict rule: %s (revision, first or error)", value)
+	}
+}
+
+// NamespaceSource file chosen for a namespace
+type NamespaceSource struct {
+	Namespace       string
+	File            string
+	Root            string
+	Revision        Revision
+	SchemaVersion   string
+	MinAdmlRevision Revision
+	Overridden      []string
+}
+
+// SetNamespaceConflictRule sets the rule used by later loads
+func (b *AdmxBundle) SetNamespaceConflictRule(rule NamespaceConflictRule) {
+	b.conflictRule = rule
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func revisionTemplates(revision, admlRevision string) fstest.MapFS {
+	return fstest.MapFS{
+		"vendor.admx": {Data: []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<policyDefinitions revision="%s" schemaVersion="1.0">
+  <policyNamespaces><target prefix="v" namespace="Test.Vendor" /></policyNamespaces>
+  <resources minRequiredRevision="1.9" />
+  <policies />
+</policyDefinitions>`, revision))},
+		"en-US/vendor.adml": {Data: []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<policyDefinitionResources revision="%s" schemaVersion="1.0">
+  <displayName>Vendor</displayName>
+  <description>Vendor</description>
+  <resources><stringTable /></resources>
+</policyDefinitionResources>`, admlRevision))},
+	}
+}
+
+func TestHighestRevisionConflict(t *testing.T) {
+	b := NewAdmxBundle()
+	failures, err := b.loadRoots([]*templateRoot{
+		{fsys: revisionTemplates("1.10", "1.10"), name: "newer"},
+		{fsys: revisionTemplates("1.9", "1.9"), name: "older"},
+	}, nil)
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("load: %v %v", err, failures)
+	}
+	src := b.NamespaceSources["Test.Vendor"]
+	if src == nil || src.Root != "newer" || src.Revision != (Revision{Major: 1, Minor: 10}) {
+		t.Fatalf("chosen = %+v, want revision 1.10 from newer", src)
+	}
+}
+
+func TestCompareRevision(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.9", "1.10", -1},
+		{"1.10", "1.9", 1},
+		{"2.0", "1.99", 1},
+		{"1.0", "1", 0},
+	}
+	for _, tt := range tests {
+		a, errA := parseRevision(tt.a)
+		b, errB := parseRevision(tt.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("parseRevision: %v %v", errA, errB)
+		}
+		if got := compareRevision(a, b); got != tt.want {
+			t.Errorf("compareRevision(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "1.x", "v1", "1.-2"} {
+		if _, err := parseRevision(bad); err == nil {
+			t.Errorf("parseRevision(%q) succeeded", bad)
+		}
+	}
+}
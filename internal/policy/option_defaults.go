@@ -0,0 +1,77 @@
+package policy
+
+import "sort"
+
+// PresentationDefaults returns the values gpedit pre-fills when the policy
+// is enabled, keyed by element ID and typed like coerced options: the
+// defaultValue of numeric boxes (1 when absent) and text boxes, the defaultChecked state of check
+// boxes, the default text of combo boxes and the defaultItem of drop-down
+// lists. Numbers are clamped to the bounds of their element. Lists have no
+// defaults.
+func (b *AdmxBundle) PresentationDefaults(pol *PolicyPlusPolicy) map[string]interface{} {
+	defaults := make(map[string]interface{})
+	if pol.Presentation == nil {
+		return defaults
+	}
+
+	elements := make(map[string]PolicyElement, len(pol.RawPolicy.Elements))
+	for _, elem := range pol.RawPolicy.Elements {
+		elements[elem.GetID()] = elem
+	}
+
+	for _, pres := range pol.Presentation.Elements {
+		elem, ok := elements[pres.GetID()]
+		if !ok {
+			continue
+		}
+		switch pe := pres.(type) {
+		case *NumericBoxPresentationElement:
+			if dec, ok := elem.(*DecimalPolicyElement); ok {
+				value := pe.DefaultValue
+				if value < dec.Minimum {
+					value = dec.Minimum
+				}
+				if value > dec.Maximum {
+					value = dec.Maximum
+				}
+				defaults[pe.ID] = value
+			}
+		case *TextBoxPresentationElement:
+			if pe.DefaultValue != "" {
+				defaults[pe.ID] = b.resolveString(pe.DefaultValue, pol.RawPolicy.DefinedIn)
+			}
+		case *ComboBoxPresentationElement:
+			if pe.DefaultText != "" {
+				defaults[pe.ID] = b.resolveString(pe.DefaultText, pol.RawPolicy.DefinedIn)
+			}
+		case *CheckBoxPresentationElement:
+			defaults[pe.ID] = pe.DefaultState
+		case *DropDownPresentationElement:
+			if enum, ok := elem.(*EnumPolicyElement); ok && pe.DefaultItemID != nil && *pe.DefaultItemID < len(enum.Items) {
+				defaults[pe.ID] = *pe.DefaultItemID
+			}
+		}
+	}
+	return defaults
+}
+
+// WithDefaults returns options completed with defaults for the elements
+// that are missing or null, and the IDs of the defaults used, sorted.
+// options is not modified.
+func WithDefaults(options, defaults map[string]interface{}) (map[string]interface{}, []string) {
+	result := make(map[string]interface{}, len(options)+len(defaults))
+	for id, value := range options {
+		result[id] = value
+	}
+
+	used := []string{}
+	for id, value := range defaults {
+		if current, ok := result[id]; ok && current != nil {
+			continue
+		}
+		result[id] = value
+		used = append(used, id)
+	}
+	sort.Strings(used)
+	return result, used
+}
@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPresentationDefaults(t *testing.T) {
+	fsys := fstest.MapFS{
+		"defaults.admx": {Data: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<policyDefinitions revision="1.0" schemaVersion="1.0">
+  <policyNamespaces><target prefix="d" namespace="Test.Defaults" /></policyNamespaces>
+  <resources minRequiredRevision="1.0" />
+  <policies>
+    <policy name="P" class="Machine" displayName="$(string.P)" key="Software\Policies\Test" presentation="$(presentation.P)">
+      <elements>
+        <decimal id="Plain" valueName="Plain" maxValue="100" />
+        <decimal id="Given" valueName="Given" maxValue="100" />
+        <decimal id="Clamped" valueName="Clamped" minValue="10" maxValue="100" />
+        <boolean id="Flag" valueName="Flag" />
+        <enum id="Mode" valueName="Mode">
+          <item displayName="$(string.A)"><value><decimal value="0" /></value></item>
+          <item displayName="$(string.B)"><value><decimal value="1" /></value></item>
+        </enum>
+        <list id="Lst" key="Software\Policies\Test\List" />
+      </elements>
+    </policy>
+  </policies>
+</policyDefinitions>`)},
+		"en-US/defaults.adml": {Data: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<policyDefinitionResources revision="1.0" schemaVersion="1.0">
+  <displayName>Defaults</displayName>
+  <description>Defaults</description>
+  <resources>
+    <stringTable>
+      <string id="P">P</string>
+      <string id="A">A</string>
+      <string id="B">B</string>
+    </stringTable>
+    <presentationTable>
+      <presentation id="P">
+        <decimalTextBox refId="Plain">Plain</decimalTextBox>
+        <decimalTextBox refId="Given" defaultValue="42">Given</decimalTextBox>
+        <decimalTextBox refId="Clamped">Clamped</decimalTextBox>
+        <checkBox refId="Flag" defaultChecked="true">Flag</checkBox>
+        <dropdownList refId="Mode" defaultItem="1">Mode</dropdownList>
+        <listBox refId="Lst">List</listBox>
+      </presentation>
+    </presentationTable>
+  </resources>
+</policyDefinitionResources>`)},
+	}
+
+	b := NewAdmxBundle()
+	failures, err := b.LoadFS(fsys, ".", "en-US")
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("LoadFS: %v %v", err, failures)
+	}
+	pol := b.Policies["Test.Defaults:P"]
+	if pol == nil {
+		t.Fatal("policy not loaded")
+	}
+
+	want := map[string]interface{}{
+		"Plain":   uint32(1),
+		"Given":   uint32(42),
+		"Clamped": uint32(10),
+		"Flag":    true,
+		"Mode":    1,
+	}
+	if got := b.PresentationDefaults(pol); !reflect.DeepEqual(got, want) {
+		t.Errorf("PresentationDefaults = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"fmt"
+	"unicode/utf16"
+)
+
+// Constraint error codes
+const (
+	OptionRequired    = "required"
+	OptionOutOfRange  = "out_of_range"
+	OptionTooLong     = "too_long"
+	OptionInvalidItem = "invalid_item"
+)
+
+// ValidateOptions checks coerced options against the constraints of their
+// elements: required elements, decimal bounds, text length and enum item
+// indexes. All violations are reported.
+func ValidateOptions(schemas []*OptionSchema, options map[string]interface{}) error {
+	var errs OptionErrors
+	for _, schema := range schemas {
+		value, ok := options[schema.ID]
+		if !ok {
+			if schema.Required() {
+				errs = append(errs, &OptionError{ElementID: schema.ID, Code: OptionRequired, Message: "a value is required"})
+			}
+			continue
+		}
+		if err := schema.Validate(value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// PrepareOptions coerces and validates options in one step, so that a
+// policy is written either with every value or not at all. Errors of both
+// steps are reported together.
+func PrepareOptions(schemas []*OptionSchema, options map[string]interface{}) (map[string]interface{}, error) {
+	coerced, errs := coerceOptions(schemas, options)
+
+	failed := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		failed[e.ElementID] = true
+	}
+	remaining := make([]*OptionSchema, 0, len(schemas))
+	for _, schema := range schemas {
+		if !failed[schema.ID] {
+			remaining = append(remaining, schema)
+		}
+	}
+	if err := ValidateOptions(remaining, coerced); err != nil {
+		errs = append(errs, err.(OptionErrors)...)
+	}
+
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+	return coerced, nil
+}
+
+// Required reports whether the element must be given when the policy is
+// enabled
+func (s *OptionSchema) Required() bool {
+	switch e := s.Element.(type) {
+	case *DecimalPolicyElement:
+		return e.Required
+	case *TextPolicyElement:
+		return e.Required
+	case *EnumPolicyElement:
+		return e.Required
+	}
+	return false
+}
+
+// Validate checks a coerced value against the constraints of the element
+func (s *OptionSchema) Validate(value interface{}) *OptionError {
+	fail := func(code, format string, args ...interface{}) *OptionError {
+		return &OptionError{ElementID: s.ID, Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	switch e := s.Element.(type) {
+	case *DecimalPolicyElement:
+		n, ok := value.(uint32)
+		if ok && (n < e.Minimum || n > e.Maximum) {
+			return fail(OptionOutOfRange, "must be between %d and %d", e.Minimum, e.Maximum)
+		}
+	case *TextPolicyElement:
+		text, _ := value.(string)
+		if e.Required && text == "" {
+			return fail(OptionRequired, "a value is required")
+		}
+		if length := len(utf16.Encode([]rune(text))); e.MaxLength > 0 && length > e.MaxLength {
+			return fail(OptionTooLong, "must be at most %d characters, got %d", e.MaxLength, length)
+		}
+	case *EnumPolicyElement:
+		index, ok := value.(int)
+		if ok && (index < 0 || index >= len(e.Items)) {
+			return fail(OptionInvalidItem, "item index must be between 0 and %d", len(e.Items)-1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTextAdmx = `<?xml version="1.0" encoding="utf-8"?>
+<policyDefinitions revision="1.0" schemaVersion="1.0">
+  <policyNamespaces><target prefix="t" namespace="Test.Text" /></policyNamespaces>
+  <resources minRequiredRevision="1.0" />
+  <policies>
+    <policy name="P" class="Machine" displayName="$(string.P)" key="Software\Policies\Test">
+      <elements>
+        <text id="Default" valueName="Default" />
+        <text id="Short" valueName="Short" maxLength="5" />
+        <decimal id="Num" valueName="Num" />
+        <decimal id="Big" valueName="Big" minValue="10" maxValue="100000" />
+      </elements>
+    </policy>
+  </policies>
+</policyDefinitions>`
+
+func TestOptionLimits(t *testing.T) {
+	admx, err := parseAdmx([]byte(testTextAdmx), "test.admx")
+	if err != nil {
+		t.Fatalf("parseAdmx: %v", err)
+	}
+	schemas := OptionSchemas(admx.Policies[0])
+
+	tests := []struct {
+		name  string
+		id    string
+		value interface{}
+		want  string
+	}{
+		{"schema default allows 1023", "Default", strings.Repeat("x", 1023), ""},
+		{"schema default refuses 1024", "Default", strings.Repeat("x", 1024), OptionTooLong},
+		{"maxLength", "Short", "12345", ""},
+		{"over maxLength", "Short", "123456", OptionTooLong},
+		{"schema default allows 9999", "Num", uint32(9999), ""},
+		{"schema default refuses 10000", "Num", uint32(10000), OptionOutOfRange},
+		{"maxValue", "Big", uint32(100000), ""},
+		{"under minValue", "Big", uint32(9), OptionOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(schemas, map[string]interface{}{tt.id: tt.value})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ValidateOptions: %v", err)
+				}
+				return
+			}
+			errs, ok := err.(OptionErrors)
+			if !ok || len(errs) != 1 || errs[0].Code != tt.want {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidLimits(t *testing.T) {
+	for _, element := range []string{
+		`<decimal id="Num" valueName="Num" minValue="-1" />`,
+		`<decimal id="Num" valueName="Num" maxValue="lots" />`,
+		`<text id="Text" valueName="Text" maxLength="long" />`,
+	} {
+		admx := strings.Replace(testTextAdmx, `<text id="Default" valueName="Default" />`, element, 1)
+		if _, err := parseAdmx([]byte(admx), "test.admx"); err == nil {
+			t.Errorf("%s: parsed without error", element)
+		}
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"sort"
 	"strings"
 	"unicode/utf16"
-
-	"golang.org/x/sys/windows/registry"
 )
 
 const (
@@ -22,17 +20,39 @@ const (
 // ValueType represents Windows registry value type
 type ValueType uint32
 
-// Windows registry value types (taken from registry package)
+// Windows registry value types, numbered as in the registry API
 const (
-	NONE      ValueType = registry.NONE
-	SZ        ValueType = registry.SZ
-	EXPAND_SZ ValueType = registry.EXPAND_SZ
-	BINARY    ValueType = registry.BINARY
-	DWORD     ValueType = registry.DWORD
-	MULTI_SZ  ValueType = registry.MULTI_SZ
-	QWORD     ValueType = registry.QWORD
+	NONE      ValueType = 0
+	SZ        ValueType = 1
+	EXPAND_SZ ValueType = 2
+	BINARY    ValueType = 3
+	DWORD     ValueType = 4
+	MULTI_SZ  ValueType = 7
+	QWORD     ValueType = 11
 )
 
+// String returns the registry name of the type, such as REG_SZ
+func (t ValueType) String() string {
+	switch t {
+	case SZ:
+		return RegTypeString
+	case EXPAND_SZ:
+		return RegTypeExpandSz
+	case DWORD:
+		return RegTypeDWord
+	case QWORD:
+		return RegTypeQWord
+	case MULTI_SZ:
+		return RegTypeMultiSz
+	case BINARY:
+		return RegTypeBinary
+	case NONE:
+		return "REG_NONE"
+	default:
+		return fmt.Sprintf("REG_%d", uint32(t))
+	}
+}
+
 // PolFile POL file
 type PolFile struct {
 	entries          map[string]*polEntryData
@@ -433,6 +453,22 @@ func (p *PolFile) GetValueNames(key string) []string {
 	return names
 }
 
+// Entries returns every value of the file, markers such as **del. and
+// **delvals. included, sorted by key and value name
+func (p *PolFile) Entries() []RegistryEntry {
+	entries := make([]RegistryEntry, 0, len(p.entries))
+	for dictKey, entry := range p.entries {
+		parts := strings.SplitN(p.casePreservation[dictKey], "\\\\", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		data, _ := entry.asArbitrary()
+		entries = append(entries, RegistryEntry{Key: parts[0], ValueName: parts[1], Type: entry.Kind.String(), Kind: entry.Kind, Data: data})
+	}
+	sortRegistryEntries(entries)
+	return entries
+}
+
 // Data conversion functions
 
 func (e *polEntryData) asArbitrary() (interface{}, error) {
@@ -0,0 +1,416 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OptionKind kind of value a policy element takes
+type OptionKind int
+
+const (
+	OptionDecimal OptionKind = iota
+	OptionBoolean
+	OptionText
+	OptionEnum
+	OptionList
+	OptionKeyValueList
+	OptionMultiText
+)
+
+func (k OptionKind) String() string {
+	switch k {
+	case OptionDecimal:
+		return "decimal"
+	case OptionBoolean:
+		return "boolean"
+	case OptionText:
+		return "text"
+	case OptionEnum:
+		return "enum"
+	case OptionList:
+		return "list"
+	case OptionKeyValueList:
+		return "keyValueList"
+	case OptionMultiText:
+		return "multiText"
+	default:
+		return "unknown"
+	}
+}
+
+// OptionSchema the value one element of a policy accepts. Coerced values
+// have one Go type per kind: uint32 for decimal, bool for boolean, string
+// for text, the item index (int) for enum, []string for list and multiText
+// and map[string]string for keyValueList.
+type OptionSchema struct {
+	ID      string
+	Kind    OptionKind
+	Element PolicyElement
+	Items   []OptionItem
+}
+
+// OptionItem an enum item, matched by display name or string code
+type OptionItem struct {
+	Code string
+	Name string
+}
+
+// Option error codes
+const (
+	OptionUnknownElement = "unknown_element"
+	OptionInvalidType    = "invalid_type"
+	OptionUnknownItem    = "unknown_item"
+)
+
+// OptionError a value that does not fit its element
+type OptionError struct {
+	ElementID string
+	Code      string
+	Message   string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.ElementID, e.Message)
+}
+
+// OptionErrors all option errors of one request, sorted by element
+type OptionErrors []*OptionError
+
+func (e OptionErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "invalid options: " + strings.Join(messages, "; ")
+}
+
+// OptionSchemas describes the elements of a policy. Enum items are matched
+// by string code only; use AdmxBundle.OptionSchemas to match display names.
+func OptionSchemas(policy *AdmxPolicy) []*OptionSchema {
+	return buildOptionSchemas(policy, func(code string) string { return "" })
+}
+
+// OptionSchemas describes the elements of a policy, with enum item names in
+// the language of the bundle.
+func (b *AdmxBundle) OptionSchemas(pol *PolicyPlusPolicy) []*OptionSchema {
+	return buildOptionSchemas(pol.RawPolicy, func(code string) string {
+		return b.resolveString(code, pol.RawPolicy.DefinedIn)
+	})
+}
+
+func buildOptionSchemas(policy *AdmxPolicy, resolve func(code string) string) []*OptionSchema {
+	schemas := make([]*OptionSchema, 0, len(policy.Elements))
+	for _, element := range policy.Elements {
+		schema := &OptionSchema{ID: element.GetID(), Element: element}
+		switch e := element.(type) {
+		case *DecimalPolicyElement:
+			schema.Kind = OptionDecimal
+		case *BooleanPolicyElement:
+			schema.Kind = OptionBoolean
+		case *TextPolicyElement:
+			schema.Kind = OptionText
+		case *EnumPolicyElement:
+			schema.Kind = OptionEnum
+			for _, item := range e.Items {
+				schema.Items = append(schema.Items, OptionItem{Code: item.DisplayCode, Name: resolve(item.DisplayCode)})
+			}
+		case *ListPolicyElement:
+			schema.Kind = OptionList
+			if e.UserProvidesNames {
+				schema.Kind = OptionKeyValueList
+			}
+		case *MultiTextPolicyElement:
+			schema.Kind = OptionMultiText
+		default:
+			continue
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
+
+// CoerceOptions converts decoded option values, such as JSON numbers,
+// arrays and objects or form strings, to the types of their elements.
+// Options are keyed by element ID. Every value that cannot be converted is
+// reported; the result is only valid when the error is nil. Coercing
+// coerced options returns them unchanged.
+func CoerceOptions(schemas []*OptionSchema, options map[string]interface{}) (map[string]interface{}, error) {
+	result, errs := coerceOptions(schemas, options)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// coerceOptions returns the values that could be converted together with
+// the errors of the others
+func coerceOptions(schemas []*OptionSchema, options map[string]interface{}) (map[string]interface{}, OptionErrors) {
+	byID := make(map[string]*OptionSchema, len(schemas))
+	for _, schema := range schemas {
+		byID[schema.ID] = schema
+	}
+
+	result := make(map[string]interface{}, len(options))
+	var errs OptionErrors
+	for id, value := range options {
+		schema, ok := byID[id]
+		if !ok {
+			errs = append(errs, &OptionError{ElementID: id, Code: OptionUnknownElement, Message: "policy has no such element"})
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		coerced, err := schema.Coerce(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result[id] = coerced
+	}
+
+	errs.sort()
+	return result, errs
+}
+
+func (e OptionErrors) sort() {
+	sort.SliceStable(e, func(i, j int) bool { return e[i].ElementID < e[j].ElementID })
+}
+
+// Coerce converts one value to the type of the element
+func (s *OptionSchema) Coerce(value interface{}) (interface{}, *OptionError) {
+	fail := func(code, format string, args ...interface{}) (interface{}, *OptionError) {
+		return nil, &OptionError{ElementID: s.ID, Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	switch s.Kind {
+	case OptionDecimal:
+		n, ok := toWholeNumber(value)
+		if !ok || n < 0 || n > math.MaxUint32 {
+			return fail(OptionInvalidType, "expected a whole number from 0 to %d, got %s", uint32(math.MaxUint32), describeValue(value))
+		}
+		return uint32(n), nil
+
+	case OptionBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "1", "yes", "on":
+				return true, nil
+			case "false", "0", "no", "off":
+				return false, nil
+			}
+		default:
+			if n, ok := toWholeNumber(v); ok && (n == 0 || n == 1) {
+				return n == 1, nil
+			}
+		}
+		return fail(OptionInvalidType, "expected true or false, got %s", describeValue(value))
+
+	case OptionText:
+		text, ok := toText(value)
+		if !ok {
+			return fail(OptionInvalidType, "expected text, got %s", describeValue(value))
+		}
+		return text, nil
+
+	case OptionEnum:
+		if text, ok := value.(string); ok {
+			text = strings.TrimSpace(text)
+			for i, item := range s.Items {
+				if item.Name != "" && strings.EqualFold(item.Name, text) {
+					return i, nil
+				}
+			}
+			for i, item := range s.Items {
+				if strings.EqualFold(item.Code, text) {
+					return i, nil
+				}
+			}
+		}
+		n, ok := toWholeNumber(value)
+		if !ok {
+			if _, isText := value.(string); isText {
+				return fail(OptionUnknownItem, "%s is not an item; expected one of %s or an index", describeValue(value), s.itemNames())
+			}
+			return fail(OptionInvalidType, "expected an item name or index, got %s", describeValue(value))
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fail(OptionInvalidType, "item index %d is out of range", n)
+		}
+		return int(n), nil
+
+	case OptionList, OptionMultiText:
+		items, ok := toTextList(value)
+		if !ok {
+			return fail(OptionInvalidType, "expected a list of text, got %s", describeValue(value))
+		}
+		return items, nil
+
+	case OptionKeyValueList:
+		pairs, ok := toTextMap(value)
+		if !ok {
+			return fail(OptionInvalidType, "expected name/value pairs, got %s", describeValue(value))
+		}
+		return pairs, nil
+	}
+	return fail(OptionInvalidType, "unsupported element")
+}
+
+func (s *OptionSchema) itemNames() string {
+	names := make([]string, len(s.Items))
+	for i, item := range s.Items {
+		name := item.Name
+		if name == "" {
+			name = item.Code
+		}
+		names[i] = strconv.Quote(name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// toWholeNumber converts integers, integral floats and numeric text
+func toWholeNumber(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return toWholeNumber(uint64(v))
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case float32:
+		return toWholeNumber(float64(v))
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) || v < math.MinInt64 || v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// toText accepts text and formats numbers and booleans
+func toText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	if n, ok := toWholeNumber(value); ok {
+		return strconv.FormatInt(n, 10), true
+	}
+	return "", false
+}
+
+// toTextList accepts lists of text and text with one entry per line
+func toTextList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case string:
+		items := []string{}
+		for _, line := range strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, true
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, entry := range v {
+			text, ok := toText(entry)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, text)
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// toTextMap accepts objects and lists of {"name", "value"} objects
+func toTextMap(value interface{}) (map[string]string, bool) {
+	switch v := value.(type) {
+	case map[string]string:
+		return v, true
+	case map[string]interface{}:
+		pairs := make(map[string]string, len(v))
+		for name, entry := range v {
+			text, ok := toText(entry)
+			if !ok {
+				return nil, false
+			}
+			pairs[name] = text
+		}
+		return pairs, true
+	case []interface{}:
+		pairs := make(map[string]string, len(v))
+		for _, entry := range v {
+			obj, ok := entry.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			name, nameOK := obj["name"].(string)
+			if !nameOK {
+				name, nameOK = obj["key"].(string)
+			}
+			text, valueOK := toText(obj["value"])
+			if !nameOK || !valueOK {
+				return nil, false
+			}
+			pairs[name] = text
+		}
+		return pairs, true
+	}
+	return nil, false
+}
+
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
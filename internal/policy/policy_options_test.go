@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testOptionPolicy() *AdmxPolicy {
+	return &AdmxPolicy{
+		ID: "Options",
+		Elements: []PolicyElement{
+			&DecimalPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Num"}},
+			&BooleanPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Flag"}},
+			&TextPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Text"}},
+			&EnumPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Mode"}, Items: []*EnumPolicyElementItem{
+				{DisplayCode: "$(string.Off)"},
+				{DisplayCode: "$(string.On)"},
+			}},
+			&ListPolicyElement{BasePolicyElement: BasePolicyElement{ID: "List"}},
+			&ListPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Pairs"}, UserProvidesNames: true},
+			&MultiTextPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Lines"}},
+		},
+	}
+}
+
+func TestCoerceOptions(t *testing.T) {
+	schemas := OptionSchemas(testOptionPolicy())
+	tests := []struct {
+		name  string
+		id    string
+		value interface{}
+		want  interface{}
+	}{
+		{"decimal from JSON number", "Num", float64(42), uint32(42)},
+		{"decimal from text", "Num", " 7 ", uint32(7)},
+		{"decimal from json.Number", "Num", json.Number("4294967295"), uint32(4294967295)},
+		{"boolean", "Flag", true, true},
+		{"boolean from text", "Flag", "Off", false},
+		{"boolean from number", "Flag", float64(1), true},
+		{"text", "Text", "value", "value"},
+		{"text from number", "Text", float64(1.5), "1.5"},
+		{"text from boolean", "Text", false, "false"},
+		{"enum by index", "Mode", float64(1), 1},
+		{"enum by code", "Mode", "$(string.on)", 1},
+		{"list from array", "List", []interface{}{"a", float64(2)}, []string{"a", "2"}},
+		{"list from lines", "List", "a\r\n\n b \n", []string{"a", "b"}},
+		{"pairs from object", "Pairs", map[string]interface{}{"x": "1", "y": float64(2)}, map[string]string{"x": "1", "y": "2"}},
+		{"pairs from array", "Pairs", []interface{}{map[string]interface{}{"name": "x", "value": "1"}}, map[string]string{"x": "1"}},
+		{"multi-text", "Lines", []interface{}{"one", "two"}, []string{"one", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceOptions(schemas, map[string]interface{}{tt.id: tt.value})
+			if err != nil {
+				t.Fatalf("CoerceOptions: %v", err)
+			}
+			if !reflect.DeepEqual(got[tt.id], tt.want) {
+				t.Errorf("got %#v, want %#v", got[tt.id], tt.want)
+			}
+
+			// coerced options stay as they are
+			again, err := CoerceOptions(schemas, got)
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("coercing again = %#v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestCoerceOptionsErrors(t *testing.T) {
+	schemas := OptionSchemas(testOptionPolicy())
+	_, err := CoerceOptions(schemas, map[string]interface{}{
+		"Num":     float64(-1),
+		"Flag":    "maybe",
+		"Mode":    "Sometimes",
+		"List":    map[string]interface{}{},
+		"Pairs":   []interface{}{"x"},
+		"Missing": "x",
+		"Text":    nil,
+	})
+
+	var errs OptionErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want OptionErrors", err)
+	}
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.ElementID] = e.Code
+	}
+	want := map[string]string{
+		"Num":     OptionInvalidType,
+		"Flag":    OptionInvalidType,
+		"Mode":    OptionUnknownItem,
+		"List":    OptionInvalidType,
+		"Pairs":   OptionInvalidType,
+		"Missing": OptionUnknownElement,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("error codes = %v, want %v", got, want)
+	}
+	for i := 1; i < len(errs); i++ {
+		if errs[i-1].ElementID > errs[i].ElementID {
+			t.Errorf("errors are not sorted by element: %v", errs)
+		}
+	}
+}
@@ -30,14 +30,23 @@ func (ps PolicyState) String() string {
 	}
 }
 
-// GetPolPath returns the path to the pol file for a given section
-func GetPolPath(section AdmxPolicySection) (string, error) {
+// localGPOPath returns the folder of the local Group Policy object
+func localGPOPath() string {
 	systemRoot := os.Getenv("SystemRoot")
 	if systemRoot == "" {
 		systemRoot = "C:\\Windows"
 	}
+	return filepath.Join(systemRoot, "System32", "GroupPolicy")
+}
 
-	basePath := filepath.Join(systemRoot, "System32", "GroupPolicy")
+// GetGptIniPath returns the path to the gpt.ini of the local GPO
+func GetGptIniPath() string {
+	return filepath.Join(localGPOPath(), "gpt.ini")
+}
+
+// GetPolPath returns the path to the pol file for a given section
+func GetPolPath(section AdmxPolicySection) (string, error) {
+	basePath := localGPOPath()
 
 	switch section {
 	case User:
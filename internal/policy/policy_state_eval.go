@@ -0,0 +1,478 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StateMismatch a registry value that does not fit the state the other
+// values of the policy describe. Actual is empty when the value is missing.
+type StateMismatch struct {
+	Key       string
+	ValueName string
+	Expected  string
+	Actual    string
+}
+
+// StateEvaluation the state of a policy together with the values that make
+// it inconsistent. State is PolicyStateUnknown whenever Mismatches is not
+// empty.
+type StateEvaluation struct {
+	State      PolicyState
+	Options    map[string]interface{}
+	Mismatches []StateMismatch
+}
+
+// valueLookup reads one registry value, reporting whether it exists
+type valueLookup func(key, valueName string) (interface{}, bool)
+
+// nameLookup lists the value names of a key, without Registry.pol markers
+type nameLookup func(key string) []string
+
+func polFileLookup(pol *PolFile) valueLookup {
+	return func(key, valueName string) (interface{}, bool) {
+		data, _, err := pol.GetValue(key, valueName)
+		return data, err == nil
+	}
+}
+
+func polFileNames(pol *PolFile) nameLookup {
+	return pol.GetValueNames
+}
+
+// Outcomes of a state check
+const (
+	CheckEnabled  = "enabled"
+	CheckDisabled = "disabled"
+	CheckMismatch = "mismatch"
+	CheckNotSet   = "not set"
+	CheckMatched  = "matched"
+)
+
+// Names of the sources a policy state is read from
+const (
+	SourcePolFile  = "Registry.pol"
+	SourceRegistry = "Registry"
+)
+
+// StateCheck one registry value the evaluation looked at. Actual is empty
+// when the value is missing.
+type StateCheck struct {
+	Key       string
+	ValueName string
+	Expected  string
+	Actual    string
+	Outcome   string
+	Note      string
+}
+
+// StateTrace the checks of one evaluation and the rule that decided it
+type StateTrace struct {
+	Source     string
+	Path       string
+	State      PolicyState
+	Rule       string
+	Checks     []StateCheck
+	Mismatches []StateMismatch
+	Err        error
+}
+
+// StateExplanation how the state of a policy was decided: one trace per
+// source that was read, in the order they are consulted
+type StateExplanation struct {
+	State     PolicyState
+	DecidedBy string
+	Rule      string
+	Traces    []*StateTrace
+}
+
+// Disagree reports whether the sources that could be read give different
+// states
+func (x *StateExplanation) Disagree() bool {
+	var first *StateTrace
+	for _, trace := range x.Traces {
+		if trace.Err != nil {
+			continue
+		}
+		if first == nil {
+			first = trace
+		} else if trace.State != first.State {
+			return true
+		}
+	}
+	return false
+}
+
+// stateEvaluator collects the evidence for each state
+type stateEvaluator struct {
+	lookup     valueLookup
+	names      nameLookup
+	enabled    bool
+	disabled   bool
+	mismatches []StateMismatch
+	trace      bool
+	checks     []StateCheck
+	rule       string
+}
+
+// evaluateState compares the stored values of a policy with what enabling
+// and disabling it writes. The main value must hold the enabled or the
+// disabled value, and the enabled and disabled lists must be present
+// entirely or not at all. Element values without the main value, and
+// evidence for both states at once, are reported as well.
+func evaluateState(lookup valueLookup, names nameLookup, policy *AdmxPolicy) (PolicyState, []StateMismatch) {
+	e := &stateEvaluator{lookup: lookup, names: names}
+	state := e.run(policy)
+	return state, e.mismatches
+}
+
+// traceState evaluates like evaluateState and records every check
+func traceState(source string, lookup valueLookup, names nameLookup, policy *AdmxPolicy) *StateTrace {
+	e := &stateEvaluator{lookup: lookup, names: names, trace: true}
+	state := e.run(policy)
+	return &StateTrace{
+		Source:     source,
+		State:      state,
+		Rule:       e.rule,
+		Checks:     e.checks,
+		Mismatches: e.mismatches,
+	}
+}
+
+func (e *stateEvaluator) run(policy *AdmxPolicy) PolicyState {
+	onValue, offValue := mainValues(policy)
+
+	// an element stored in the main value decides its content itself
+	ownsMain := policy.RegistryValue != "" && !elementUsesValue(policy, policy.RegistryKey, policy.RegistryValue)
+	if ownsMain {
+		e.checkMain(policy.RegistryKey, policy.RegistryValue, onValue, offValue)
+	}
+	if policy.AffectedValues != nil {
+		if list := policy.AffectedValues.OnValueList; list != nil {
+			e.checkList(list, policy.RegistryKey, &e.enabled, "enabled list")
+		}
+		if list := policy.AffectedValues.OffValueList; list != nil {
+			e.checkList(list, policy.RegistryKey, &e.disabled, "disabled list")
+		}
+	}
+	hasSwitch := ownsMain || (policy.AffectedValues != nil &&
+		(policy.AffectedValues.OnValueList != nil || policy.AffectedValues.OffValueList != nil))
+	e.checkElements(policy, hasSwitch)
+
+	switch {
+	case len(e.mismatches) > 0:
+		e.rule = fmt.Sprintf("%d value(s) do not fit the state of the others", len(e.mismatches))
+		return PolicyStateUnknown
+	case e.enabled && e.disabled:
+		e.rule = "values of both the enabled and the disabled state are present"
+		return PolicyStateUnknown
+	case e.enabled && hasSwitch:
+		e.rule = "the enabled value or list is present"
+		return PolicyStateEnabled
+	case e.enabled:
+		e.rule = "the policy has no value of its own and element values are present"
+		return PolicyStateEnabled
+	case e.disabled:
+		e.rule = "the disabled value, list or a deletion marker is present"
+		return PolicyStateDisabled
+	}
+	e.rule = "no value of the policy is present"
+	return PolicyStateNotConfigured
+}
+
+// mainValues returns what enabling and disabling write to the main value,
+// DWORD 1 and DWORD 0 unless the policy defines them. A deleted main value
+// with a **del. marker counts as disabled as well.
+func mainValues(policy *AdmxPolicy) (*PolicyRegistryValue, *PolicyRegistryValue) {
+	on := &PolicyRegistryValue{RegistryType: Numeric, NumberValue: 1}
+	off := &PolicyRegistryValue{RegistryType: Numeric, NumberValue: 0}
+	if policy.AffectedValues != nil {
+		if v := policy.AffectedValues.OnValue; v != nil && v.RegistryType != Delete {
+			on = v
+		}
+		if v := policy.AffectedValues.OffValue; v != nil && v.RegistryType != Delete {
+			off = v
+		}
+	}
+	return on, off
+}
+
+func (e *stateEvaluator) checkMain(key, valueName string, on, off *PolicyRegistryValue) {
+	expected := fmt.Sprintf("%s (enabled) or %s (disabled)", describeRegistryValue(on), describeRegistryValue(off))
+	data, ok := e.lookup(key, valueName)
+	if !ok {
+		if _, deleted := e.lookup(key, "**del."+valueName); deleted {
+			e.disabled = true
+			e.record(key, valueName, expected, nil, false, CheckDisabled, "main value, deleted by a **del. marker")
+			return
+		}
+		e.record(key, valueName, expected, nil, false, CheckNotSet, "main value")
+		return
+	}
+
+	switch {
+	case registryValueMatches(on, data):
+		e.enabled = true
+		e.record(key, valueName, expected, data, true, CheckEnabled, "main value")
+	case registryValueMatches(off, data):
+		e.disabled = true
+		e.record(key, valueName, expected, data, true, CheckDisabled, "main value")
+	default:
+		e.mismatch(key, valueName, expected, data)
+		e.record(key, valueName, expected, data, true, CheckMismatch, "main value")
+	}
+}
+
+// checkList sets found when any entry of the list is present; entries that
+// are missing or hold another value are then mismatches
+func (e *stateEvaluator) checkList(list *PolicyRegistrySingleList, defaultKey string, found *bool, name string) {
+	listKey := defaultKey
+	if list.DefaultRegistryKey != "" {
+		listKey = list.DefaultRegistryKey
+	}
+
+	var missing []StateMismatch
+	first := len(e.checks)
+	matched := 0
+	for _, entry := range list.AffectedValues {
+		if entry.Value == nil || entry.Value.RegistryType == Delete {
+			continue
+		}
+		entryKey := listKey
+		if entry.RegistryKey != "" {
+			entryKey = entry.RegistryKey
+		}
+		expected := describeRegistryValue(entry.Value)
+		data, ok := e.lookup(entryKey, entry.RegistryValue)
+		if ok && registryValueMatches(entry.Value, data) {
+			matched++
+			e.record(entryKey, entry.RegistryValue, expected, data, true, CheckMatched, name)
+			continue
+		}
+		actual := ""
+		if ok {
+			actual = describeRegistryData(data)
+		}
+		missing = append(missing, StateMismatch{Key: entryKey, ValueName: entry.RegistryValue, Expected: expected, Actual: actual})
+		e.record(entryKey, entry.RegistryValue, expected, data, ok, CheckNotSet, name)
+	}
+
+	if matched > 0 {
+		*found = true
+		e.mismatches = append(e.mismatches, missing...)
+		for i := first; i < len(e.checks); i++ {
+			if e.checks[i].Outcome == CheckNotSet {
+				e.checks[i].Outcome = CheckMismatch
+				e.checks[i].Note = name + ", partly present"
+			}
+		}
+	}
+}
+
+// checkElements treats element values as evidence for the enabled state
+// when the policy has no value of its own. Otherwise they must not be set
+// while the policy is off, and required values must be set while it is on.
+func (e *stateEvaluator) checkElements(policy *AdmxPolicy, hasSwitch bool) {
+	for _, element := range policy.Elements {
+		if list, ok := element.(*ListPolicyElement); ok {
+			if !hasSwitch {
+				e.checkListElement(policy, list)
+			}
+			continue
+		}
+		base := element.GetBase()
+		if base.RegistryValue == "" {
+			continue
+		}
+		elemKey := policy.RegistryKey
+		if base.RegistryKey != "" {
+			elemKey = base.RegistryKey
+		}
+		note := "element " + base.ID
+		data, ok := e.lookup(elemKey, base.RegistryValue)
+
+		if !hasSwitch {
+			if ok {
+				e.enabled = true
+				e.record(elemKey, base.RegistryValue, "any value", data, true, CheckEnabled, note)
+			} else if _, deleted := e.lookup(elemKey, "**del."+base.RegistryValue); deleted {
+				e.disabled = true
+				e.record(elemKey, base.RegistryValue, "any value", nil, false, CheckDisabled, note+", deleted by a **del. marker")
+			} else {
+				e.record(elemKey, base.RegistryValue, "any value", nil, false, CheckNotSet, note)
+			}
+			continue
+		}
+
+		switch el := element.(type) {
+		case *DecimalPolicyElement, *TextPolicyElement:
+			switch {
+			case !ok && e.enabled && elementRequired(el):
+				e.mismatches = append(e.mismatches, StateMismatch{Key: elemKey, ValueName: base.RegistryValue, Expected: "a value (required while enabled)"})
+				e.record(elemKey, base.RegistryValue, "a value (required while enabled)", nil, false, CheckMismatch, note)
+			case ok && !e.enabled:
+				e.mismatch(elemKey, base.RegistryValue, "no value unless enabled", data)
+				e.record(elemKey, base.RegistryValue, "no value unless enabled", data, true, CheckMismatch, note)
+			default:
+				e.record(elemKey, base.RegistryValue, "", data, ok, presence(ok), note)
+			}
+		case *EnumPolicyElement:
+			switch {
+			case !ok && e.enabled && el.Required:
+				e.mismatches = append(e.mismatches, StateMismatch{Key: elemKey, ValueName: base.RegistryValue, Expected: "one of the items (required while enabled)"})
+				e.record(elemKey, base.RegistryValue, "one of the items (required while enabled)", nil, false, CheckMismatch, note)
+			case ok && !e.enabled:
+				e.mismatch(elemKey, base.RegistryValue, "no value unless enabled", data)
+				e.record(elemKey, base.RegistryValue, "no value unless enabled", data, true, CheckMismatch, note)
+			case ok && !enumItemMatches(el, data):
+				e.mismatch(elemKey, base.RegistryValue, "one of the items", data)
+				e.record(elemKey, base.RegistryValue, "one of the items", data, true, CheckMismatch, note)
+			default:
+				e.record(elemKey, base.RegistryValue, "", data, ok, presence(ok), note)
+			}
+		}
+	}
+}
+
+// checkListElement treats the entries of a list element as evidence for the
+// enabled state, and a key cleared by a **delvals. marker without entries
+// as evidence for the disabled state, for policies without a value of their
+// own
+func (e *stateEvaluator) checkListElement(policy *AdmxPolicy, list *ListPolicyElement) {
+	listKey := policy.RegistryKey
+	if list.RegistryKey != "" {
+		listKey = list.RegistryKey
+	}
+	note := "list element " + list.ID
+
+	entries := 0
+	for _, name := range e.names(listKey) {
+		if isListEntry(list, name) {
+			entries++
+		}
+	}
+	if entries > 0 {
+		e.enabled = true
+		e.record(listKey, "", "list entries", nil, false, CheckEnabled, fmt.Sprintf("%s, %d value(s)", note, entries))
+		return
+	}
+	if _, cleared := e.lookup(listKey, "**delvals."); cleared {
+		e.disabled = true
+		e.record(listKey, "", "list entries", nil, false, CheckDisabled, note+", cleared by a **delvals. marker")
+		return
+	}
+	e.record(listKey, "", "list entries", nil, false, CheckNotSet, note)
+}
+
+// isListEntry reports whether a value name is one a list element writes:
+// the prefix followed by a number when it has one, any name otherwise
+func isListEntry(list *ListPolicyElement, name string) bool {
+	if !list.HasPrefix || list.RegistryValue == "" {
+		return true
+	}
+	if len(name) <= len(list.RegistryValue) || !strings.EqualFold(name[:len(list.RegistryValue)], list.RegistryValue) {
+		return false
+	}
+	for _, c := range name[len(list.RegistryValue):] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *stateEvaluator) mismatch(key, valueName, expected string, data interface{}) {
+	e.mismatches = append(e.mismatches, StateMismatch{Key: key, ValueName: valueName, Expected: expected, Actual: describeRegistryData(data)})
+}
+
+func presence(found bool) string {
+	if found {
+		return CheckMatched
+	}
+	return CheckNotSet
+}
+
+// record adds a check to the trace, if one is kept
+func (e *stateEvaluator) record(key, valueName, expected string, data interface{}, found bool, outcome, note string) {
+	if !e.trace {
+		return
+	}
+	check := StateCheck{Key: key, ValueName: valueName, Expected: expected, Outcome: outcome, Note: note}
+	if found {
+		check.Actual = describeRegistryData(data)
+	}
+	e.checks = append(e.checks, check)
+}
+
+func elementUsesValue(policy *AdmxPolicy, key, valueName string) bool {
+	for _, element := range policy.Elements {
+		base := element.GetBase()
+		elemKey := policy.RegistryKey
+		if base.RegistryKey != "" {
+			elemKey = base.RegistryKey
+		}
+		if strings.EqualFold(elemKey, key) && strings.EqualFold(base.RegistryValue, valueName) {
+			return true
+		}
+	}
+	return false
+}
+
+func elementRequired(element PolicyElement) bool {
+	switch e := element.(type) {
+	case *DecimalPolicyElement:
+		return e.Required
+	case *TextPolicyElement:
+		return e.Required
+	}
+	return false
+}
+
+func enumItemMatches(element *EnumPolicyElement, data interface{}) bool {
+	for _, item := range element.Items {
+		if item.Value != nil && registryValueMatches(item.Value, data) {
+			return true
+		}
+	}
+	return false
+}
+
+func registryValueMatches(value *PolicyRegistryValue, data interface{}) bool {
+	switch value.RegistryType {
+	case Delete:
+		return false
+	case Numeric:
+		dw, ok := data.(uint32)
+		return ok && dw == value.NumberValue
+	default:
+		str, ok := data.(string)
+		return ok && str == value.StringValue
+	}
+}
+
+func describeRegistryValue(value *PolicyRegistryValue) string {
+	switch value.RegistryType {
+	case Delete:
+		return "deleted"
+	case Numeric:
+		return fmt.Sprintf("DWORD %d", value.NumberValue)
+	default:
+		return fmt.Sprintf("%q", value.StringValue)
+	}
+}
+
+func describeRegistryData(data interface{}) string {
+	switch v := data.(type) {
+	case uint32:
+		return fmt.Sprintf("DWORD %d", v)
+	case uint64:
+		return fmt.Sprintf("QWORD %d", v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		return fmt.Sprintf("multi-string [%s]", strings.Join(v, ", "))
+	case []byte:
+		return fmt.Sprintf("binary (%d bytes)", len(v))
+	}
+	return fmt.Sprintf("%v", data)
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+// testValues is a registry in memory, keyed by key and value name
+type testValues map[string]interface{}
+
+func (v testValues) lookup(key, valueName string) (interface{}, bool) {
+	data, ok := v[strings.ToLower(key+"\\"+valueName)]
+	return data, ok
+}
+
+func (v testValues) names(key string) []string {
+	prefix := strings.ToLower(key + "\\")
+	var names []string
+	for path := range v {
+		name := strings.TrimPrefix(path, prefix)
+		if name != path && !strings.Contains(name, "\\") && !strings.HasPrefix(name, "**") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func testStatePolicies() map[string]*AdmxPolicy {
+	return map[string]*AdmxPolicy{
+		"main": {
+			ID:            "Main",
+			RegistryKey:   `Software\Policies\Vendor`,
+			RegistryValue: "Main",
+			Elements: []PolicyElement{
+				&DecimalPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Num", RegistryValue: "Num"}, Required: true},
+			},
+		},
+		"lists": {
+			ID:          "Lists",
+			RegistryKey: `Software\Policies\Vendor`,
+			AffectedValues: &PolicyRegistryList{
+				OnValueList: &PolicyRegistrySingleList{AffectedValues: []*PolicyRegistryListEntry{
+					{RegistryValue: "A", Value: &PolicyRegistryValue{RegistryType: Numeric, NumberValue: 1}},
+					{RegistryValue: "B", Value: &PolicyRegistryValue{RegistryType: Text, StringValue: "on"}},
+				}},
+				OffValueList: &PolicyRegistrySingleList{AffectedValues: []*PolicyRegistryListEntry{
+					{RegistryValue: "A", Value: &PolicyRegistryValue{RegistryType: Numeric, NumberValue: 0}},
+				}},
+			},
+		},
+		"element": {
+			ID:          "Element",
+			RegistryKey: `Software\Policies\Vendor`,
+			Elements: []PolicyElement{
+				&TextPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Text", RegistryValue: "Text"}},
+			},
+		},
+		"list": {
+			ID:          "List",
+			RegistryKey: `Software\Policies\Vendor`,
+			Elements: []PolicyElement{
+				&ListPolicyElement{BasePolicyElement: BasePolicyElement{ID: "Sites", RegistryKey: `Software\Policies\Vendor\Sites`, RegistryValue: "Site"}, HasPrefix: true},
+			},
+		},
+	}
+}
+
+func TestEvaluateState(t *testing.T) {
+	const key = `software\policies\vendor\`
+	tests := []struct {
+		name       string
+		policy     string
+		values     testValues
+		want       PolicyState
+		mismatches int
+	}{
+		{"not configured", "main", testValues{}, PolicyStateNotConfigured, 0},
+		{"enabled", "main", testValues{key + "main": uint32(1), key + "num": uint32(5)}, PolicyStateEnabled, 0},
+		{"disabled", "main", testValues{key + "main": uint32(0)}, PolicyStateDisabled, 0},
+		{"deleted main value", "main", testValues{key + "**del.main": " "}, PolicyStateDisabled, 0},
+		{"wrong main value", "main", testValues{key + "main": uint32(7)}, PolicyStateUnknown, 1},
+		{"required element missing", "main", testValues{key + "main": uint32(1)}, PolicyStateUnknown, 1},
+		{"element while disabled", "main", testValues{key + "main": uint32(0), key + "num": uint32(5)}, PolicyStateUnknown, 1},
+		{"enabled list", "lists", testValues{key + "a": uint32(1), key + "b": "on"}, PolicyStateEnabled, 0},
+		{"half-present list", "lists", testValues{key + "a": uint32(1)}, PolicyStateUnknown, 1},
+		{"disabled list", "lists", testValues{key + "a": uint32(0)}, PolicyStateDisabled, 0},
+		{"element only, set", "element", testValues{key + "text": "x"}, PolicyStateEnabled, 0},
+		{"element only, deleted", "element", testValues{key + "**del.text": " "}, PolicyStateDisabled, 0},
+		{"element only, not set", "element", testValues{}, PolicyStateNotConfigured, 0},
+		{"list only, entries", "list", testValues{key + `sites\**delvals.`: " ", key + `sites\site1`: "a", key + `sites\site2`: "b"}, PolicyStateEnabled, 0},
+		{"list only, cleared", "list", testValues{key + `sites\**delvals.`: " "}, PolicyStateDisabled, 0},
+		{"list only, other values", "list", testValues{key + `sites\other`: "a"}, PolicyStateNotConfigured, 0},
+	}
+	policies := testStatePolicies()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, mismatches := evaluateState(tt.values.lookup, tt.values.names, policies[tt.policy])
+			if state != tt.want {
+				t.Errorf("state = %v, want %v", state, tt.want)
+			}
+			if len(mismatches) != tt.mismatches {
+				t.Errorf("mismatches = %v, want %d", mismatches, tt.mismatches)
+			}
+		})
+	}
+}
+
+func TestTraceStateListElement(t *testing.T) {
+	values := testValues{`software\policies\vendor\sites\site1`: "a"}
+	trace := traceState(SourcePolFile, values.lookup, values.names, testStatePolicies()["list"])
+	if trace.State != PolicyStateEnabled || len(trace.Checks) != 1 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if check := trace.Checks[0]; check.Outcome != CheckEnabled || !strings.Contains(check.Note, "1 value(s)") {
+		t.Errorf("check = %+v", check)
+	}
+}
@@ -6,134 +6,132 @@ import (
 
 // GetPolicyState reads the current policy from the .pol file or registry.
 func GetPolicyState(source PolicySource, policy *AdmxPolicy) (PolicyState, map[string]interface{}, error) {
-	if regSource, ok := source.(*RegistryPolicySource); ok {
-		var section AdmxPolicySection
-		if regSource.RootKey == registry.CURRENT_USER {
-			section = User
-		} else if regSource.RootKey == registry.LOCAL_MACHINE {
-			section = Machine
-		}
-
-		if polPath, err := GetPolPath(section); err == nil {
-			if pol, err := Load(polPath); err == nil {
-				state, options := getPolicyStateFromPolFile(pol, policy)
-				if state != PolicyStateNotConfigured {
-					return state, options, nil
-				}
-			}
-		}
-	}
-
-	if policy.RegistryValue != "" && !source.ContainsValue(policy.RegistryKey, policy.RegistryValue) {
-		return PolicyStateNotConfigured, nil, nil
+	eval, err := EvaluatePolicyState(source, policy)
+	if err != nil {
+		return PolicyStateNotConfigured, nil, err
 	}
+	return eval.State, eval.Options, nil
+}
 
-	if policy.AffectedValues != nil {
-		if isRegistryListPresent(source, policy.AffectedValues, policy.RegistryKey, policy.RegistryValue, true) {
-			options := readPolicyElements(source, policy)
-			return PolicyStateEnabled, options, nil
-		}
-		if isRegistryListPresent(source, policy.AffectedValues, policy.RegistryKey, policy.RegistryValue, false) {
-			return PolicyStateDisabled, nil, nil
-		}
-	} else if policy.RegistryValue != "" {
-		val, err := source.GetValue(policy.RegistryKey, policy.RegistryValue)
-		if err == nil {
-			if dw, ok := val.(uint32); ok && dw == 1 {
-				options := readPolicyElements(source, policy)
-				return PolicyStateEnabled, options, nil
+// EvaluatePolicyState reads the current policy from the .pol file or
+// registry together with the values that contradict each other. Options
+// are read for enabled and unknown policies.
+func EvaluatePolicyState(source PolicySource, policy *AdmxPolicy) (*StateEvaluation, error) {
+	if polPath, ok := sourcePolPath(source); ok {
+		if pol, err := Load(polPath); err == nil {
+			if eval := evaluatePolFile(pol, policy); eval.State != PolicyStateNotConfigured {
+				return eval, nil
 			}
 		}
 	}
 
-	return PolicyStateNotConfigured, nil, nil
-}
-
-// GetPolicyStateFromPolFilePublic is public wrapper for getPolicyStateFromPolFile
-func GetPolicyStateFromPolFilePublic(pol *PolFile, policy *AdmxPolicy) (PolicyState, map[string]interface{}) {
-	return getPolicyStateFromPolFile(pol, policy)
+	state, mismatches := evaluateState(sourceLookup(source), sourceNames(source), policy)
+	eval := &StateEvaluation{State: state, Mismatches: mismatches}
+	if state == PolicyStateEnabled || state == PolicyStateUnknown {
+		eval.Options = readPolicyElements(source, policy)
+	}
+	return eval, nil
 }
 
-func getPolicyStateFromPolFile(pol *PolFile, rawPolicy *AdmxPolicy) (PolicyState, map[string]interface{}) {
-	options := readPolicyElementsFromPolFile(pol, rawPolicy)
+// ExplainPolicyState evaluates a policy like EvaluatePolicyState, but reads
+// both the .pol file and the registry and records every check, so that the
+// verdicts can be compared.
+func ExplainPolicyState(source PolicySource, policy *AdmxPolicy) *StateExplanation {
+	x := &StateExplanation{}
 
-	if len(options) > 0 {
-		return PolicyStateEnabled, options
+	var polTrace *StateTrace
+	if polPath, ok := sourcePolPath(source); ok {
+		pol, err := Load(polPath)
+		if err != nil {
+			polTrace = &StateTrace{Source: SourcePolFile, State: PolicyStateNotConfigured, Err: err}
+		} else {
+			polTrace = traceState(SourcePolFile, polFileLookup(pol), polFileNames(pol), policy)
+		}
+		polTrace.Path = polPath
+		x.Traces = append(x.Traces, polTrace)
 	}
 
-	if rawPolicy.RegistryValue != "" {
-		if !pol.ContainsValue(rawPolicy.RegistryKey, rawPolicy.RegistryValue) {
-			return PolicyStateNotConfigured, nil
-		}
+	regTrace := traceState(SourceRegistry, sourceLookup(source), sourceNames(source), policy)
+	x.Traces = append(x.Traces, regTrace)
 
-		val, _, err := pol.GetValue(rawPolicy.RegistryKey, rawPolicy.RegistryValue)
-		if err == nil {
-			if dw, ok := val.(uint32); ok {
-				if dw == 1 {
-					return PolicyStateEnabled, options
-				}
-				if dw == 0 {
-					return PolicyStateDisabled, nil
-				}
-			}
-			return PolicyStateEnabled, options
+	switch {
+	case polTrace == nil:
+		x.State, x.DecidedBy = regTrace.State, SourceRegistry
+		x.Rule = "only the registry is read for this source"
+	case polTrace.State != PolicyStateNotConfigured:
+		x.State, x.DecidedBy = polTrace.State, SourcePolFile
+		x.Rule = "Registry.pol configures the policy, so it takes precedence over the registry"
+	default:
+		x.State, x.DecidedBy = regTrace.State, SourceRegistry
+		x.Rule = "Registry.pol does not configure the policy, so the registry decides"
+		if polTrace.Err != nil {
+			x.Rule = "Registry.pol could not be read, so the registry decides"
 		}
 	}
+	return x
+}
 
-	if rawPolicy.AffectedValues != nil {
-		if isPolFileListPresent(pol, rawPolicy.AffectedValues, rawPolicy.RegistryKey, rawPolicy.RegistryValue, true) {
-			return PolicyStateEnabled, options
-		}
-		if isPolFileListPresent(pol, rawPolicy.AffectedValues, rawPolicy.RegistryKey, rawPolicy.RegistryValue, false) {
-			return PolicyStateDisabled, nil
-		}
+// sourcePolPath returns the Registry.pol of the local GPO that belongs to a
+// registry source
+func sourcePolPath(source PolicySource) (string, bool) {
+	section, ok := sourceSection(source)
+	if !ok {
+		return "", false
 	}
+	polPath, err := GetPolPath(section)
+	return polPath, err == nil
+}
 
-	// Check for **del. markers in elements (disabled state detection)
-	// This method detects disabled state for policies without RegistryValue
-	if rawPolicy.Elements != nil {
-		hasAnyDeleteMarker := false
-		for _, element := range rawPolicy.Elements {
-			base := element.GetBase()
-			elemKey := rawPolicy.RegistryKey
-			if base.RegistryKey != "" {
-				elemKey = base.RegistryKey
-			}
-
-			// Check if **del. prefixed value exists
-			if pol.ContainsValue(elemKey, "**del."+base.RegistryValue) {
-				hasAnyDeleteMarker = true
-				break
-			}
-		}
+// sourceSection returns the section of a registry source of the current
+// user or the machine
+func sourceSection(source PolicySource) (AdmxPolicySection, bool) {
+	regSource, ok := source.(*RegistryPolicySource)
+	if !ok {
+		return Both, false
+	}
+	switch regSource.RootKey {
+	case registry.CURRENT_USER:
+		return User, true
+	case registry.LOCAL_MACHINE:
+		return Machine, true
+	}
+	return Both, false
+}
 
-		if hasAnyDeleteMarker {
-			return PolicyStateDisabled, nil
+func sourceLookup(source PolicySource) valueLookup {
+	return func(key, valueName string) (interface{}, bool) {
+		if !source.ContainsValue(key, valueName) {
+			return nil, false
 		}
+		data, err := source.GetValue(key, valueName)
+		return data, err == nil
 	}
+}
 
-	return PolicyStateNotConfigured, nil
+func sourceNames(source PolicySource) nameLookup {
+	return func(key string) []string {
+		names, _ := source.GetValueNames(key)
+		return names
+	}
 }
 
-func isPolFileListPresent(pol *PolFile, regList *PolicyRegistryList, defaultKey, defaultValue string, checkOn bool) bool {
-	var value *PolicyRegistryValue
-	var valueList *PolicyRegistrySingleList
+// GetPolicyStateFromPolFilePublic is public wrapper for getPolicyStateFromPolFile
+func GetPolicyStateFromPolFilePublic(pol *PolFile, policy *AdmxPolicy) (PolicyState, map[string]interface{}) {
+	return getPolicyStateFromPolFile(pol, policy)
+}
 
-	if checkOn {
-		value = regList.OnValue
-		valueList = regList.OnValueList
-	} else {
-		value = regList.OffValue
-		valueList = regList.OffValueList
-	}
+func getPolicyStateFromPolFile(pol *PolFile, rawPolicy *AdmxPolicy) (PolicyState, map[string]interface{}) {
+	eval := evaluatePolFile(pol, rawPolicy)
+	return eval.State, eval.Options
+}
 
-	if value != nil {
-		return isPolFileValuePresent(pol, value, defaultKey, defaultValue)
-	}
-	if valueList != nil {
-		return isPolFileListAllPresent(pol, valueList, defaultKey)
+func evaluatePolFile(pol *PolFile, rawPolicy *AdmxPolicy) *StateEvaluation {
+	state, mismatches := evaluateState(polFileLookup(pol), polFileNames(pol), rawPolicy)
+	eval := &StateEvaluation{State: state, Mismatches: mismatches}
+	if state == PolicyStateEnabled || state == PolicyStateUnknown {
+		eval.Options = readPolicyElementsFromPolFile(pol, rawPolicy)
 	}
-	return false
+	return eval
 }
 
 func isPolFileValuePresent(pol *PolFile, value *PolicyRegistryValue, key, valueName string) bool {
@@ -157,24 +155,6 @@ func isPolFileValuePresent(pol *PolFile, value *PolicyRegistryValue, key, valueN
 	return false
 }
 
-func isPolFileListAllPresent(pol *PolFile, list *PolicyRegistrySingleList, defaultKey string) bool {
-	listKey := defaultKey
-	if list.DefaultRegistryKey != "" {
-		listKey = list.DefaultRegistryKey
-	}
-
-	for _, entry := range list.AffectedValues {
-		entryKey := listKey
-		if entry.RegistryKey != "" {
-			entryKey = entry.RegistryKey
-		}
-		if !isPolFileValuePresent(pol, entry.Value, entryKey, entry.RegistryValue) {
-			return false
-		}
-	}
-	return true
-}
-
 func readPolicyElementsFromPolFile(pol *PolFile, rawPolicy *AdmxPolicy) map[string]interface{} {
 	options := make(map[string]interface{})
 	if rawPolicy.Elements == nil {
@@ -261,27 +241,6 @@ func readPolicyElementsFromPolFile(pol *PolFile, rawPolicy *AdmxPolicy) map[stri
 	return options
 }
 
-func isRegistryListPresent(source PolicySource, regList *PolicyRegistryList, defaultKey, defaultValue string, checkOn bool) bool {
-	var value *PolicyRegistryValue
-	var valueList *PolicyRegistrySingleList
-
-	if checkOn {
-		value = regList.OnValue
-		valueList = regList.OnValueList
-	} else {
-		value = regList.OffValue
-		valueList = regList.OffValueList
-	}
-
-	if value != nil {
-		return isValuePresent(source, value, defaultKey, defaultValue)
-	}
-	if valueList != nil {
-		return isListAllPresent(source, valueList, defaultKey)
-	}
-	return false
-}
-
 func isValuePresent(source PolicySource, value *PolicyRegistryValue, key, valueName string) bool {
 	if !source.ContainsValue(key, valueName) {
 		return false
@@ -306,24 +265,6 @@ func isValuePresent(source PolicySource, value *PolicyRegistryValue, key, valueN
 	return false
 }
 
-func isListAllPresent(source PolicySource, list *PolicyRegistrySingleList, defaultKey string) bool {
-	listKey := defaultKey
-	if list.DefaultRegistryKey != "" {
-		listKey = list.DefaultRegistryKey
-	}
-
-	for _, entry := range list.AffectedValues {
-		entryKey := listKey
-		if entry.RegistryKey != "" {
-			entryKey = entry.RegistryKey
-		}
-		if !isValuePresent(source, entry.Value, entryKey, entry.RegistryValue) {
-			return false
-		}
-	}
-	return true
-}
-
 func readPolicyElements(source PolicySource, policy *AdmxPolicy) map[string]interface{} {
 	options := make(map[string]interface{})
 	if policy.Elements == nil {
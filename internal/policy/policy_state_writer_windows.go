@@ -6,13 +6,21 @@ import (
 	"golang.org/x/sys/windows/registry"
 )
 
-// SetPolicyState updates both registry and .pol file.
+// SetPolicyState updates both registry and .pol file. Options are coerced
+// and validated with PrepareOptions first; an OptionErrors is returned, and
+// nothing is written, when one is invalid.
 func SetPolicyState(source PolicySource, policy *AdmxPolicy, state PolicyState, options map[string]interface{}) error {
 	if policy == nil {
 		return fmt.Errorf("policy is nil")
 	}
 
 	var err error
+	if state == PolicyStateEnabled {
+		if options, err = PrepareOptions(OptionSchemas(policy), options); err != nil {
+			return err
+		}
+	}
+
 	switch state {
 	case PolicyStateEnabled:
 		err = setPolicyEnabled(source, policy, options)
@@ -40,12 +48,21 @@ func SetPolicyState(source PolicySource, policy *AdmxPolicy, state PolicyState,
 
 		if err := updatePolFile(section, policy, state, options); err != nil {
 			fmt.Printf("⚠ Warning: .pol file could not be updated (%v), but registry was written\n", err)
+		} else if err := UpdateGptIni(GetGptIniPath(), section, gptExtensions(policy)); err != nil {
+			fmt.Printf("⚠ Warning: gpt.ini could not be updated (%v)\n", err)
 		}
 	}
 
 	return nil
 }
 
+// gptExtensions returns the client-side extensions to record for a policy.
+// Registry.pol is always applied by the registry extension, which other
+// extensions then read their settings from.
+func gptExtensions(policy *AdmxPolicy) []string {
+	return append([]string{RegistryExtensionGUID}, policy.ClientExtensions()...)
+}
+
 func updatePolFile(section AdmxPolicySection, policy *AdmxPolicy, state PolicyState, options map[string]interface{}) error {
 	polPath, err := GetPolPath(section)
 	if err != nil {
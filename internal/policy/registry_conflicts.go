@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// RegistryConflict a registry value that more than one policy writes or
+// deletes, so that setting one of them changes the state of the others.
+// ValueName is empty when a policy clears the key, which removes the values
+// the other policies write in it. CrossFile is set when the policies come
+// from different template files, such as an old and a new Edge template.
+type RegistryConflict struct {
+	Key       string
+	ValueName string
+	Matches   []*RegistryMatch
+	CrossFile bool
+}
+
+// Policies returns the distinct policies of the conflict
+func (c *RegistryConflict) Policies() []*PolicyPlusPolicy {
+	var policies []*PolicyPlusPolicy
+	for i, match := range c.Matches {
+		if i == 0 || match.Policy != c.Matches[i-1].Policy {
+			policies = append(policies, match.Policy)
+		}
+	}
+	return policies
+}
+
+// RegistryConflicts lists the registry values written by more than one
+// policy of the same section, sorted by key and value name
+func (b *AdmxBundle) RegistryConflicts() []*RegistryConflict {
+	return b.registryLookupIndex().conflicts
+}
+
+// ConflictingPolicies returns the other policies that write a registry
+// value pol writes, sorted by ID
+func (b *AdmxBundle) ConflictingPolicies(pol *PolicyPlusPolicy) []*PolicyPlusPolicy {
+	seen := make(map[*PolicyPlusPolicy]bool)
+	var result []*PolicyPlusPolicy
+	for _, conflict := range b.RegistryConflicts() {
+		policies := conflict.Policies()
+		involved := false
+		for _, other := range policies {
+			involved = involved || other == pol
+		}
+		if !involved {
+			continue
+		}
+		for _, other := range policies {
+			if other != pol && !seen[other] && sectionsOverlap(pol.RawPolicy.Section, other.RawPolicy.Section) {
+				seen[other] = true
+				result = append(result, other)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UniqueID < result[j].UniqueID
+	})
+	return result
+}
+
+func (index *registryIndex) findConflicts() []*RegistryConflict {
+	var conflicts []*RegistryConflict
+	for _, matches := range index.values {
+		if conflict := newRegistryConflict(matches[0].Key, matches[0].ValueName, matches); conflict != nil {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	// a cleared key conflicts with every value other policies write in it
+	for _, matches := range index.keys {
+		var clears []*RegistryMatch
+		for _, match := range matches {
+			if match.ValueName == "" {
+				clears = append(clears, match)
+			}
+		}
+		if len(clears) == 0 {
+			continue
+		}
+		var affected []*RegistryMatch
+		for _, match := range matches {
+			for _, clear := range clears {
+				if sectionsOverlap(match.Policy.RawPolicy.Section, clear.Policy.RawPolicy.Section) {
+					affected = append(affected, match)
+					break
+				}
+			}
+		}
+		if conflict := newRegistryConflict(clears[0].Key, "", affected); conflict != nil {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		ki, kj := strings.ToLower(conflicts[i].Key), strings.ToLower(conflicts[j].Key)
+		if ki != kj {
+			return ki < kj
+		}
+		return strings.ToLower(conflicts[i].ValueName) < strings.ToLower(conflicts[j].ValueName)
+	})
+	return conflicts
+}
+
+// newRegistryConflict keeps the matches whose policy shares a section with
+// another policy of the list; nil when there are none
+func newRegistryConflict(key, valueName string, matches []*RegistryMatch) *RegistryConflict {
+	var kept []*RegistryMatch
+	for _, match := range matches {
+		for _, other := range matches {
+			if other.Policy != match.Policy && sectionsOverlap(match.Policy.RawPolicy.Section, other.Policy.RawPolicy.Section) {
+				kept = append(kept, match)
+				break
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].Policy.UniqueID != kept[j].Policy.UniqueID {
+			return kept[i].Policy.UniqueID < kept[j].Policy.UniqueID
+		}
+		return kept[i].ElementID < kept[j].ElementID
+	})
+
+	conflict := &RegistryConflict{Key: key, ValueName: valueName, Matches: kept}
+	for _, match := range kept {
+		if match.Policy.RawPolicy.DefinedIn != kept[0].Policy.RawPolicy.DefinedIn {
+			conflict.CrossFile = true
+		}
+	}
+	return conflict
+}
+
+func sectionsOverlap(a, b AdmxPolicySection) bool {
+	return a == Both || b == Both || a == b
+}
@@ -0,0 +1,252 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Footprint actions
+const (
+	FootprintWrite  = "write"
+	FootprintDelete = "delete"
+	FootprintClear  = "clear"
+)
+
+// Registry value types of footprint entries
+const (
+	RegTypeDWord    = "REG_DWORD"
+	RegTypeString   = "REG_SZ"
+	RegTypeExpandSz = "REG_EXPAND_SZ"
+	RegTypeMultiSz  = "REG_MULTI_SZ"
+	RegTypeQWord    = "REG_QWORD"
+	RegTypeBinary   = "REG_BINARY"
+)
+
+// FootprintEntry one registry value a policy writes or deletes. ValueName
+// is empty when a whole key is cleared; names in braces, such as {name}
+// or Prefix{n}, stand for names taken from the options. Data is empty when
+// it comes from the options. Condition is empty when the entry is always
+// applied.
+type FootprintEntry struct {
+	Key       string
+	ValueName string
+	Action    string
+	Type      string
+	Data      string
+	Source    string
+	ElementID string
+	Condition string
+}
+
+// RegistryFootprint everything enabling and disabling a policy can touch
+type RegistryFootprint struct {
+	Enabled  []FootprintEntry
+	Disabled []FootprintEntry
+}
+
+// Keys returns the distinct keys of the footprint, sorted
+func (f *RegistryFootprint) Keys() []string {
+	seen := make(map[string]string)
+	for _, entry := range append(append([]FootprintEntry{}, f.Enabled...), f.Disabled...) {
+		lower := strings.ToLower(entry.Key)
+		if _, ok := seen[lower]; !ok {
+			seen[lower] = entry.Key
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for _, key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PolicyFootprint derives the static registry footprint of a policy from
+// its definition, following what setting it to Enabled or Disabled writes:
+// the main value, the enabled and disabled lists and the values of every
+// element, including the lists of boolean elements and enum items.
+func PolicyFootprint(policy *AdmxPolicy) *RegistryFootprint {
+	f := &footprintBuilder{}
+	f.enabled(policy)
+	f.disabled(policy)
+	return &RegistryFootprint{Enabled: f.on, Disabled: f.off}
+}
+
+type footprintBuilder struct {
+	on  []FootprintEntry
+	off []FootprintEntry
+}
+
+func (f *footprintBuilder) enabled(policy *AdmxPolicy) {
+	if policy.RegistryValue != "" && (policy.AffectedValues == nil || policy.AffectedValues.OnValue == nil) {
+		f.on = append(f.on, FootprintEntry{Key: policy.RegistryKey, ValueName: policy.RegistryValue, Action: FootprintWrite, Type: RegTypeDWord, Data: "1", Source: "policy"})
+	}
+	if policy.AffectedValues != nil {
+		f.on = appendRegistryList(f.on, policy.AffectedValues, true, policy.RegistryKey, policy.RegistryValue, "policy", "")
+	}
+
+	for _, element := range policy.Elements {
+		start := len(f.on)
+		f.enabledElement(policy, element)
+		tagElement(f.on[start:], element.GetID())
+	}
+}
+
+func (f *footprintBuilder) enabledElement(policy *AdmxPolicy, element PolicyElement) {
+	base := element.GetBase()
+	key := elementKey(policy, element)
+	source := "element " + base.ID
+	write := func(valueName, regType, data, condition string) {
+		f.on = append(f.on, FootprintEntry{Key: key, ValueName: valueName, Action: FootprintWrite, Type: regType, Data: data, Source: source, Condition: condition})
+	}
+
+	switch e := element.(type) {
+	case *DecimalPolicyElement:
+		if e.StoreAsText {
+			write(base.RegistryValue, RegTypeString, "", "")
+		} else {
+			write(base.RegistryValue, RegTypeDWord, "", "")
+		}
+	case *TextPolicyElement:
+		write(base.RegistryValue, textType(e.RegExpandSz), "", "")
+	case *MultiTextPolicyElement:
+		write(base.RegistryValue, RegTypeMultiSz, "", "")
+	case *BooleanPolicyElement:
+		if e.AffectedRegistry == nil {
+			return
+		}
+		if e.AffectedRegistry.OnValue == nil {
+			write(base.RegistryValue, RegTypeDWord, "1", "checked")
+		}
+		if e.AffectedRegistry.OffValue == nil {
+			f.on = append(f.on, FootprintEntry{Key: key, ValueName: base.RegistryValue, Action: FootprintDelete, Source: source, Condition: "unchecked"})
+		}
+		f.on = appendRegistryList(f.on, e.AffectedRegistry, true, key, base.RegistryValue, source, "checked")
+		f.on = appendRegistryList(f.on, e.AffectedRegistry, false, key, base.RegistryValue, source, "unchecked")
+	case *EnumPolicyElement:
+		for i, item := range e.Items {
+			condition := fmt.Sprintf("item %d", i)
+			if item.Value != nil {
+				f.on = append(f.on, registryValueEntry(key, base.RegistryValue, item.Value, source, condition))
+			}
+			if item.ValueList != nil {
+				f.on = appendSingleList(f.on, item.ValueList, key, source, condition)
+			}
+		}
+	case *ListPolicyElement:
+		if !e.NoPurgeOthers {
+			f.on = append(f.on, FootprintEntry{Key: key, Action: FootprintClear, Source: source})
+		}
+		valueName := "{item}"
+		switch {
+		case e.UserProvidesNames:
+			valueName = "{name}"
+		case e.HasPrefix:
+			valueName = base.RegistryValue + "{n}"
+		}
+		write(valueName, textType(e.RegExpandSz), "", "")
+	}
+}
+
+func (f *footprintBuilder) disabled(policy *AdmxPolicy) {
+	if policy.AffectedValues != nil && policy.AffectedValues.OffValue == nil && policy.RegistryValue != "" {
+		f.off = append(f.off, FootprintEntry{Key: policy.RegistryKey, ValueName: policy.RegistryValue, Action: FootprintDelete, Source: "policy"})
+	}
+	if policy.AffectedValues != nil {
+		f.off = appendRegistryList(f.off, policy.AffectedValues, false, policy.RegistryKey, policy.RegistryValue, "policy", "")
+	}
+
+	for _, element := range policy.Elements {
+		start := len(f.off)
+		f.disabledElement(policy, element)
+		tagElement(f.off[start:], element.GetID())
+	}
+}
+
+func (f *footprintBuilder) disabledElement(policy *AdmxPolicy, element PolicyElement) {
+	base := element.GetBase()
+	key := elementKey(policy, element)
+	source := "element " + base.ID
+
+	switch e := element.(type) {
+	case *ListPolicyElement:
+		f.off = append(f.off, FootprintEntry{Key: key, Action: FootprintClear, Source: source})
+	case *BooleanPolicyElement:
+		if e.AffectedRegistry != nil && (e.AffectedRegistry.OffValue != nil || e.AffectedRegistry.OffValueList != nil) {
+			f.off = appendRegistryList(f.off, e.AffectedRegistry, false, key, base.RegistryValue, source, "")
+			return
+		}
+		f.off = append(f.off, FootprintEntry{Key: key, ValueName: base.RegistryValue, Action: FootprintDelete, Source: source})
+	default:
+		f.off = append(f.off, FootprintEntry{Key: key, ValueName: base.RegistryValue, Action: FootprintDelete, Source: source})
+	}
+}
+
+func appendRegistryList(entries []FootprintEntry, list *PolicyRegistryList, on bool, key, valueName, source, condition string) []FootprintEntry {
+	value, valueList, name := list.OffValue, list.OffValueList, "disabled list"
+	if on {
+		value, valueList, name = list.OnValue, list.OnValueList, "enabled list"
+	}
+	if source != "policy" {
+		name = source
+	}
+	if value != nil {
+		entries = append(entries, registryValueEntry(key, valueName, value, source, condition))
+	}
+	if valueList != nil {
+		entries = appendSingleList(entries, valueList, key, name, condition)
+	}
+	return entries
+}
+
+func appendSingleList(entries []FootprintEntry, list *PolicyRegistrySingleList, key, source, condition string) []FootprintEntry {
+	listKey := key
+	if list.DefaultRegistryKey != "" {
+		listKey = list.DefaultRegistryKey
+	}
+	for _, entry := range list.AffectedValues {
+		if entry.Value == nil {
+			continue
+		}
+		entryKey := listKey
+		if entry.RegistryKey != "" {
+			entryKey = entry.RegistryKey
+		}
+		entries = append(entries, registryValueEntry(entryKey, entry.RegistryValue, entry.Value, source, condition))
+	}
+	return entries
+}
+
+func registryValueEntry(key, valueName string, value *PolicyRegistryValue, source, condition string) FootprintEntry {
+	entry := FootprintEntry{Key: key, ValueName: valueName, Action: FootprintWrite, Source: source, Condition: condition}
+	switch value.RegistryType {
+	case Delete:
+		entry.Action = FootprintDelete
+	case Numeric:
+		entry.Type, entry.Data = RegTypeDWord, fmt.Sprintf("%d", value.NumberValue)
+	default:
+		entry.Type, entry.Data = RegTypeString, value.StringValue
+	}
+	return entry
+}
+
+func tagElement(entries []FootprintEntry, id string) {
+	for i := range entries {
+		entries[i].ElementID = id
+	}
+}
+
+func elementKey(policy *AdmxPolicy, element PolicyElement) string {
+	if key := element.GetRegistryKey(); key != "" {
+		return key
+	}
+	return policy.RegistryKey
+}
+
+func textType(expandable bool) string {
+	if expandable {
+		return RegTypeExpandSz
+	}
+	return RegTypeString
+}
@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+// RegistryMatch a policy, or one of its elements, that writes or deletes a
+// registry value. Key and ValueName are as the template gives them; value
+// names in braces stand for names taken from the options. KeyOnly is set
+// when the path named the key rather than one of its values.
+type RegistryMatch struct {
+	Policy    *PolicyPlusPolicy
+	ElementID string
+	Source    string
+	Key       string
+	ValueName string
+	Enabled   bool
+	Disabled  bool
+	KeyOnly   bool
+}
+
+// registryIndex maps lowercased registry paths to the footprint entries of
+// the policies that touch them
+type registryIndex struct {
+	values    map[string][]*RegistryMatch
+	keys      map[string][]*RegistryMatch
+	conflicts []*RegistryConflict
+	unmanaged map[*PolicyPlusPolicy][]string
+}
+
+// registryHives maps hive prefixes to the section they belong to
+var registryHives = map[string]AdmxPolicySection{
+	"hklm":               Machine,
+	"hkey_local_machine": Machine,
+	"hkcu":               User,
+	"hkey_current_user":  User,
+}
+
+// ParseRegistryPath splits a registry path such as
+// HKLM\Software\Policies\Vendor\Value into its section and the key below
+// the hive. Paths copied from regedit ("Computer\HKEY_...") and PowerShell
+// ("HKLM:\...", "Registry::HKEY_...") are accepted. Without a hive the
+// section is Both.
+func ParseRegistryPath(path string) (AdmxPolicySection, string) {
+	path = strings.Trim(strings.ReplaceAll(strings.TrimSpace(path), "/", "\\"), "\\")
+	if i := strings.Index(path, "::"); i >= 0 {
+		path = path[i+2:]
+	}
+	parts := strings.SplitN(path, "\\", 2)
+	if strings.EqualFold(parts[0], "Computer") && len(parts) == 2 {
+		parts = strings.SplitN(parts[1], "\\", 2)
+	}
+	if section, ok := registryHives[strings.ToLower(strings.TrimSuffix(parts[0], ":"))]; ok {
+		if len(parts) == 1 {
+			return section, ""
+		}
+		return section, strings.Trim(parts[1], "\\")
+	}
+	return Both, path
+}
+
+// LookupRegistry finds the policies that write or delete a registry path
+// below the hive, which is either a value (its key followed by the value
+// name) or a key. Policies of other sections are left out unless section
+// is Both. Matches are sorted by policy ID.
+func (b *AdmxBundle) LookupRegistry(section AdmxPolicySection, path string) []*RegistryMatch {
+	index := b.registryLookupIndex()
+	path = strings.ToLower(strings.Trim(path, "\\"))
+	var candidates []*RegistryMatch
+	if i := strings.LastIndex(path, "\\"); i >= 0 {
+		key, valueName := path[:i], path[i+1:]
+		candidates = append(candidates, index.values[key+"\\"+valueName]...)
+		for _, match := range index.keys[key] {
+			if namePatternMatches(match.ValueName, valueName) {
+				candidates = append(candidates, match)
+			}
+		}
+	}
+	for _, match := range index.keys[path] {
+		keyOnly := *match
+		keyOnly.KeyOnly = true
+		candidates = append(candidates, &keyOnly)
+	}
+
+	// a value written by several entries of one element, such as a list
+	// that is cleared and refilled, is reported once
+	result := make([]*RegistryMatch, 0, len(candidates))
+	merged := make(map[string]*RegistryMatch)
+	for _, match := range candidates {
+		polSection := match.Policy.RawPolicy.Section
+		if section != Both && polSection != Both && polSection != section {
+			continue
+		}
+		id := match.Policy.UniqueID + "|" + match.ElementID
+		if match.KeyOnly {
+			id += "|" + strings.ToLower(match.ValueName)
+		}
+		if prev, ok := merged[id]; ok && prev.KeyOnly == match.KeyOnly {
+			prev.Enabled = prev.Enabled || match.Enabled
+			prev.Disabled = prev.Disabled || match.Disabled
+			if prev.ValueName == "" {
+				prev.ValueName = match.ValueName
+			}
+			continue
+		}
+		copied := *match
+		merged[id] = &copied
+		result = append(result, &copied)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Policy.UniqueID < result[j].Policy.UniqueID
+	})
+	return result
+}
+
+// registryLookupIndex returns the registry index, building it on first use
+func (b *AdmxBundle) registryLookupIndex() *registryIndex {
+	b.registryMu.Lock()
+	defer b.registryMu.Unlock()
+	if b.registryIndex == nil {
+		b.registryIndex = b.buildRegistryIndex()
+	}
+	return b.registryIndex
+}
+
+func (b *AdmxBundle) buildRegistryIndex() *registryIndex {
+	index := &registryIndex{
+		values:    make(map[string][]*RegistryMatch),
+		keys:      make(map[string][]*RegistryMatch),
+		unmanaged: make(map[*PolicyPlusPolicy][]string),
+	}
+	seen := make(map[string]*RegistryMatch)
+
+	add := func(pol *PolicyPlusPolicy, entry FootprintEntry, enabled bool) {
+		id := strings.ToLower(pol.UniqueID + "|" + entry.ElementID + "|" + entry.Key + "\\" + entry.ValueName)
+		match, ok := seen[id]
+		if !ok {
+			match = &RegistryMatch{Policy: pol, ElementID: entry.ElementID, Source: entry.Source, Key: entry.Key, ValueName: entry.ValueName}
+			seen[id] = match
+
+			// every entry is found through its key; literal value names
+			// through the full path as well
+			key := strings.ToLower(entry.Key)
+			index.keys[key] = append(index.keys[key], match)
+			if entry.ValueName != "" && !strings.Contains(entry.ValueName, "{") {
+				path := key + "\\" + strings.ToLower(entry.ValueName)
+				index.values[path] = append(index.values[path], match)
+			}
+		}
+		if enabled {
+			match.Enabled = true
+		} else {
+			match.Disabled = true
+		}
+	}
+
+	for _, pol := range b.Policies {
+		footprint := PolicyFootprint(pol.RawPolicy)
+		for _, entry := range footprint.Enabled {
+			add(pol, entry, true)
+		}
+		for _, entry := range footprint.Disabled {
+			add(pol, entry, false)
+		}
+		if keys := footprint.UnmanagedKeys(); len(keys) > 0 {
+			index.unmanaged[pol] = keys
+		}
+	}
+	index.conflicts = index.findConflicts()
+	return index
+}
+
+// namePatternMatches matches a value name against a footprint name: names
+// taken from the options match any value, Prefix{n} matches the prefix
+// followed by a number and a cleared key (no name) matches all of its
+// values. Literal names are matched through the value map instead.
+func namePatternMatches(pattern, valueName string) bool {
+	switch {
+	case pattern == "" || pattern == "{item}" || pattern == "{name}":
+		return true
+	case strings.HasSuffix(pattern, "{n}"):
+		prefix := strings.ToLower(strings.TrimSuffix(pattern, "{n}"))
+		rest, ok := strings.CutPrefix(valueName, prefix)
+		if !ok || rest == "" {
+			return false
+		}
+		for _, r := range rest {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// CategoryPath returns the display names of the categories of a policy,
+// from the root down
+func (p *PolicyPlusPolicy) CategoryPath() []string {
+	var path []string
+	for cat := p.Category; cat != nil; cat = cat.Parent {
+		path = append([]string{cat.DisplayName}, path...)
+	}
+	return path
+}
+
+// resetRegistryIndex drops the registry index after the bundle changed.
+func (b *AdmxBundle) resetRegistryIndex() {
+	b.registryMu.Lock()
+	b.registryIndex = nil
+	b.registryMu.Unlock()
+}
@@ -0,0 +1,252 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UnmanagedKeys returns the keys of the footprint outside the policy trees,
+// sorted. Values written there stay after the policy is removed.
+func (f *RegistryFootprint) UnmanagedKeys() []string {
+	var keys []string
+	for _, key := range f.Keys() {
+		if !IsPolicyKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// UnmanagedKeys returns the keys outside the policy trees a policy writes
+// in; none for policies that do not tattoo
+func (b *AdmxBundle) UnmanagedKeys(pol *PolicyPlusPolicy) []string {
+	return b.registryLookupIndex().unmanaged[pol]
+}
+
+// TattooingPolicies returns the policies that write outside the policy
+// trees, sorted by ID
+func (b *AdmxBundle) TattooingPolicies() []*PolicyPlusPolicy {
+	index := b.registryLookupIndex()
+	policies := make([]*PolicyPlusPolicy, 0, len(index.unmanaged))
+	for pol := range index.unmanaged {
+		policies = append(policies, pol)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].UniqueID < policies[j].UniqueID
+	})
+	return policies
+}
+
+// TattooValue a registry value outside the policy trees as it was before a
+// policy overwrote it. Exists is false when there was no value; the data
+// is in the field that fits Type. Kind is the registry type number, which
+// values of other types are written back with.
+type TattooValue struct {
+	Key       string   `json:"key"`
+	ValueName string   `json:"valueName"`
+	Exists    bool     `json:"exists"`
+	Type      string   `json:"type,omitempty"`
+	Kind      uint32   `json:"kind,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	Number    uint64   `json:"number,omitempty"`
+	Lines     []string `json:"lines,omitempty"`
+	Binary    []byte   `json:"binary,omitempty"`
+}
+
+// TattooBackup the values a tattooing policy overwrote, recorded when it
+// was first set. Keys are recorded whole, for lists and cleared keys.
+// Names holds the value names the policy writes in each of them, as the
+// footprint gives them (Prefix{n}, {item}); on restore, values that the
+// backup does not hold are deleted when they fit these names or are listed
+// in Registry.pol.
+type TattooBackup struct {
+	PolicyID string              `json:"policyId"`
+	Section  string              `json:"section"`
+	Recorded time.Time           `json:"recorded"`
+	Keys     []string            `json:"keys"`
+	Names    map[string][]string `json:"names,omitempty"`
+	Values   []TattooValue       `json:"values"`
+}
+
+// TattooStore keeps the backups of tattooing policies in a JSON file, one
+// per policy and section
+type TattooStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewTattooStore returns a store for the file at path; the file is created
+// on the first backup
+func NewTattooStore(path string) *TattooStore {
+	return &TattooStore{path: path}
+}
+
+// List returns the backups sorted by policy ID and section
+func (s *TattooStore) List() ([]*TattooBackup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Get returns the backup of a policy in a section, nil when there is none
+func (s *TattooStore) Get(section AdmxPolicySection, policyID string) (*TattooBackup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.PolicyID == policyID && backup.Section == tattooSection(section) {
+			return backup, nil
+		}
+	}
+	return nil, nil
+}
+
+// Put stores a backup, replacing the one of the same policy and section
+func (s *TattooStore) Put(backup *TattooBackup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := []*TattooBackup{backup}
+	for _, b := range backups {
+		if b.PolicyID != backup.PolicyID || b.Section != backup.Section {
+			kept = append(kept, b)
+		}
+	}
+	return s.write(kept)
+}
+
+// Delete removes the backup of a policy in a section
+func (s *TattooStore) Delete(section AdmxPolicySection, policyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := []*TattooBackup{}
+	for _, b := range backups {
+		if b.PolicyID != policyID || b.Section != tattooSection(section) {
+			kept = append(kept, b)
+		}
+	}
+	return s.write(kept)
+}
+
+// tattooSection names a section in the backups
+func tattooSection(section AdmxPolicySection) string {
+	if section == User {
+		return "User"
+	}
+	return "Machine"
+}
+
+func (s *TattooStore) read() ([]*TattooBackup, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []*TattooBackup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	backups := []*TattooBackup{}
+	if err := json.Unmarshal(data, &backups); err != nil {
+		return nil, fmt.Errorf("failed to read tattoo backups: %w", err)
+	}
+	return backups, nil
+}
+
+func (s *TattooStore) write(backups []*TattooBackup) error {
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].PolicyID != backups[j].PolicyID {
+			return backups[i].PolicyID < backups[j].PolicyID
+		}
+		return backups[i].Section < backups[j].Section
+	})
+	data, err := json.MarshalIndent(backups, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	// written next to the file and renamed, so a failed write keeps the
+	// previous backups
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// tattooTargets returns the values and the whole keys outside the policy
+// trees that a footprint writes: literal value names are recorded one by
+// one, keys with names taken from the options or that are cleared whole,
+// together with the names written in them
+func tattooTargets(footprint *RegistryFootprint) (values []FootprintEntry, keys []string, names map[string][]string) {
+	seenValues := make(map[string]bool)
+	seenKeys := make(map[string]string)
+	names = make(map[string][]string)
+	for _, entry := range append(append([]FootprintEntry{}, footprint.Enabled...), footprint.Disabled...) {
+		if IsPolicyKey(entry.Key) {
+			continue
+		}
+		lowerKey := strings.ToLower(entry.Key)
+		if entry.ValueName == "" || strings.Contains(entry.ValueName, "{") {
+			key, ok := seenKeys[lowerKey]
+			if !ok {
+				key = entry.Key
+				seenKeys[lowerKey] = key
+				keys = append(keys, key)
+			}
+			if entry.ValueName != "" {
+				names[key] = append(names[key], entry.ValueName)
+			}
+			continue
+		}
+		id := lowerKey + "\\" + strings.ToLower(entry.ValueName)
+		if !seenValues[id] {
+			seenValues[id] = true
+			values = append(values, entry)
+		}
+	}
+
+	// values in recorded keys are recorded with the key
+	kept := values[:0]
+	for _, entry := range values {
+		if _, ok := seenKeys[strings.ToLower(entry.Key)]; !ok {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, keys, names
+}
+
+// fitsFootprintName reports whether a value name fits one of the footprint
+// names of a key. Prefix{n} stands for the prefix followed by a number;
+// names taken from the options, such as {item} and {name}, fit none.
+func fitsFootprintName(names []string, name string) bool {
+	for _, pattern := range names {
+		prefix, ok := strings.CutSuffix(pattern, "{n}")
+		if !ok || len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+			continue
+		}
+		if strings.Trim(name[len(prefix):], "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
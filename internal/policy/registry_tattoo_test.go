@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTattooTargets(t *testing.T) {
+	const explorer = `Software\Microsoft\Windows\CurrentVersion\Explorer\Advanced`
+	const sites = `Software\Vendor\Sites`
+	footprint := &RegistryFootprint{
+		Enabled: []FootprintEntry{
+			{Key: `Software\Policies\Vendor`, ValueName: "Main", Action: FootprintWrite},
+			{Key: explorer, ValueName: "Hidden", Action: FootprintWrite},
+			{Key: sites, Action: FootprintClear},
+			{Key: sites, ValueName: "Site{n}", Action: FootprintWrite},
+			{Key: `software\vendor\sites`, ValueName: "Other", Action: FootprintWrite},
+		},
+		Disabled: []FootprintEntry{
+			{Key: explorer, ValueName: "hidden", Action: FootprintDelete},
+		},
+	}
+
+	values, keys, names := tattooTargets(footprint)
+	if len(values) != 1 || values[0].Key != explorer || values[0].ValueName != "Hidden" {
+		t.Errorf("values = %+v", values)
+	}
+	if !reflect.DeepEqual(keys, []string{sites}) {
+		t.Errorf("keys = %v", keys)
+	}
+	if !reflect.DeepEqual(names, map[string][]string{sites: {"Site{n}"}}) {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestFitsFootprintName(t *testing.T) {
+	names := []string{"Site{n}", "{item}"}
+	tests := map[string]bool{
+		"Site1":   true,
+		"site12":  true,
+		"Site":    false,
+		"Site1a":  false,
+		"Other":   false,
+		"1":       false,
+		"Sites10": false,
+	}
+	for name, want := range tests {
+		if got := fitsFootprintName(names, name); got != want {
+			t.Errorf("fitsFootprintName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
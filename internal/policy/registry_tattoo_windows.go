@@ -0,0 +1,232 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+var procRegSetValueExW = advapi32.NewProc("RegSetValueExW")
+
+// BackupTattooValues records the values outside the policy trees that
+// setting a policy overwrites, as they are in source now. Only registry
+// sources of the current user or the machine can be backed up.
+func BackupTattooValues(source PolicySource, policyID string, policy *AdmxPolicy) (*TattooBackup, error) {
+	root, section, err := tattooRoot(source)
+	if err != nil {
+		return nil, err
+	}
+
+	values, keys, names := tattooTargets(PolicyFootprint(policy))
+	backup := &TattooBackup{
+		PolicyID: policyID,
+		Section:  tattooSection(section),
+		Recorded: time.Now(),
+		Keys:     append([]string{}, keys...),
+		Names:    names,
+		Values:   []TattooValue{},
+	}
+	for _, entry := range values {
+		backup.Values = append(backup.Values, readTattooValue(root, entry.Key, entry.ValueName))
+	}
+	for _, key := range keys {
+		k, err := registry.OpenKey(root, key, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		names, _ := k.ReadValueNames(0)
+		k.Close()
+		for _, name := range names {
+			backup.Values = append(backup.Values, readTattooValue(root, key, name))
+		}
+	}
+	return backup, nil
+}
+
+// RestoreTattooValues puts back the values of a backup: values that existed
+// are written again, the others deleted. In whole keys, values that the
+// backup does not hold are deleted when the policy wrote them: when they fit
+// the recorded names or Registry.pol lists them. The policy's entries
+// outside the policy trees are then removed from Registry.pol, so that
+// Group Policy does not write them again.
+func RestoreTattooValues(source PolicySource, backup *TattooBackup) error {
+	root, _, err := tattooRoot(source)
+	if err != nil {
+		return err
+	}
+
+	saved := make(map[string]bool)
+	for _, value := range backup.Values {
+		if value.Exists {
+			saved[strings.ToLower(value.Key+"\\"+value.ValueName)] = true
+		}
+	}
+
+	return updateRegistryPol(source, func(pol *PolFile) error {
+		for _, key := range backup.Keys {
+			listed := make(map[string]bool)
+			for _, name := range pol.GetValueNames(key) {
+				listed[strings.ToLower(name)] = true
+			}
+			if err := deleteWrittenValues(root, key, func(name string) bool {
+				return !saved[strings.ToLower(key+"\\"+name)] &&
+					(listed[strings.ToLower(name)] || fitsFootprintName(backup.Names[key], name))
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, value := range backup.Values {
+			if err := writeTattooValue(root, value); err != nil {
+				return fmt.Errorf("%s\\%s could not be restored: %w", value.Key, value.ValueName, err)
+			}
+		}
+		notifyWindowsSettingChange()
+
+		for _, key := range backup.Keys {
+			for _, name := range pol.GetValueNames(key) {
+				pol.ForgetValue(key, name)
+			}
+			pol.ForgetValue(key, "**delvals.")
+		}
+		for _, value := range backup.Values {
+			pol.ForgetValue(value.Key, value.ValueName)
+		}
+		return nil
+	})
+}
+
+// deleteWrittenValues deletes the values of a key that written selects
+func deleteWrittenValues(root registry.Key, key string, written func(name string) bool) error {
+	k, err := registry.OpenKey(root, key, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s could not be opened: %w", key, err)
+	}
+	defer k.Close()
+
+	names, _ := k.ReadValueNames(0)
+	for _, name := range names {
+		if written(name) {
+			k.DeleteValue(name)
+		}
+	}
+	return nil
+}
+
+// TattooBackupSupported reports whether the values of a source can be
+// backed up: only the registry of the current user or the machine
+func TattooBackupSupported(source PolicySource) bool {
+	_, ok := sourceSection(source)
+	return ok
+}
+
+func tattooRoot(source PolicySource) (registry.Key, AdmxPolicySection, error) {
+	section, ok := sourceSection(source)
+	if !ok {
+		return 0, section, fmt.Errorf("tattoo backups need a registry source of the user or the machine")
+	}
+	return source.(*RegistryPolicySource).RootKey, section, nil
+}
+
+func readTattooValue(root registry.Key, key, valueName string) TattooValue {
+	value := TattooValue{Key: key, ValueName: valueName}
+	k, err := registry.OpenKey(root, key, registry.QUERY_VALUE)
+	if err != nil {
+		return value
+	}
+	defer k.Close()
+
+	entry, ok := readRegistryEntry(k, key, valueName)
+	if !ok {
+		return value
+	}
+	value.Exists, value.Type, value.Kind = true, entry.Type, uint32(entry.Kind)
+	switch data := entry.Data.(type) {
+	case string:
+		value.Text = data
+	case uint32:
+		value.Number = uint64(data)
+	case uint64:
+		value.Number = data
+	case []string:
+		value.Lines = data
+	case []byte:
+		value.Binary = data
+	}
+	return value
+}
+
+// writeTattooValue writes a recorded value back, or deletes it when it did
+// not exist. Types without a setter of their own are written as raw bytes
+// with their recorded type.
+func writeTattooValue(root registry.Key, value TattooValue) error {
+	if !value.Exists {
+		k, err := registry.OpenKey(root, value.Key, registry.SET_VALUE)
+		if err == registry.ErrNotExist {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer k.Close()
+		if err := k.DeleteValue(value.ValueName); err != nil && err != registry.ErrNotExist {
+			return err
+		}
+		return nil
+	}
+
+	k, _, err := registry.CreateKey(root, value.Key, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	switch value.Type {
+	case RegTypeString:
+		return k.SetStringValue(value.ValueName, value.Text)
+	case RegTypeExpandSz:
+		return k.SetExpandStringValue(value.ValueName, value.Text)
+	case RegTypeDWord:
+		return k.SetDWordValue(value.ValueName, uint32(value.Number))
+	case RegTypeQWord:
+		return k.SetQWordValue(value.ValueName, value.Number)
+	case RegTypeMultiSz:
+		return k.SetStringsValue(value.ValueName, value.Lines)
+	case RegTypeBinary:
+		return k.SetBinaryValue(value.ValueName, value.Binary)
+	default:
+		return setRawValue(k, value.ValueName, value.Kind, value.Binary)
+	}
+}
+
+// setRawValue writes data with any registry type; the registry package has
+// setters for the common types only
+func setRawValue(k registry.Key, name string, kind uint32, data []byte) error {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var buf *byte
+	if len(data) > 0 {
+		buf = &data[0]
+	}
+	ret, _, _ := procRegSetValueExW.Call(
+		uintptr(k),
+		uintptr(unsafe.Pointer(namePtr)),
+		0,
+		uintptr(kind),
+		uintptr(unsafe.Pointer(buf)),
+		uintptr(len(data)),
+	)
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Revision template revision as in the revision and minRequiredRevision
+// attributes. Major and minor are compared as numbers: 1.10 is newer than
+// 1.9.
+type Revision struct {
+	Major uint32
+	Minor uint32
+}
+
+// parseRevision parses "major.minor"; a revision without minor part has
+// minor 0
+func parseRevision(text string) (Revision, error) {
+	majorText, minorText, hasMinor := strings.Cut(strings.TrimSpace(text), ".")
+	major, err := strconv.ParseUint(majorText, 10, 32)
+	if err != nil {
+		return Revision{}, fmt.Errorf("invalid revision %q", text)
+	}
+	var minor uint64
+	if hasMinor {
+		minor, err = strconv.ParseUint(minorText, 10, 32)
+		if err != nil {
+			return Revision{}, fmt.Errorf("invalid revision %q", text)
+		}
+	}
+	return Revision{Major: uint32(major), Minor: uint32(minor)}, nil
+}
+
+// compareRevision returns -1, 0 or +1 as a is older than, the same as or
+// newer than b
+func compareRevision(a, b Revision) int {
+	if c := cmp.Compare(a.Major, b.Major); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Minor, b.Minor)
+}
+
+// IsZero reports whether the revision is absent
+func (r Revision) IsZero() bool {
+	return r == Revision{}
+}
+
+func (r Revision) String() string {
+	return fmt.Sprintf("%d.%d", r.Major, r.Minor)
+}
@@ -60,6 +60,8 @@ type AdmxCategory struct {
 	DisplayCode string
 	ExplainCode string
 	ParentID    string
+	SeeAlso     []string
+	Keywords    []string
 	DefinedIn   *AdmxFile
 }
 
@@ -77,6 +79,8 @@ type AdmxPolicy struct {
 	RegistryValue   string
 	AffectedValues  *PolicyRegistryList
 	Elements        []PolicyElement
+	SeeAlso         []string
+	Keywords        []string
 	DefinedIn       *AdmxFile
 }
 
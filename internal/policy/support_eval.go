@@ -0,0 +1,174 @@
+package policy
+
+import "strings"
+
+// SupportResult outcome of a support evaluation
+type SupportResult int
+
+const (
+	// SupportUnknown the definition has no product entries, or refers to
+	// products or definitions that are not loaded
+	SupportUnknown SupportResult = iota
+	Supported
+	NotSupported
+)
+
+func (r SupportResult) String() string {
+	switch r {
+	case Supported:
+		return "Supported"
+	case NotSupported:
+		return "Not Supported"
+	default:
+		return "Unknown"
+	}
+}
+
+// FindProduct looks up a product by unique ID ("namespace:id"), by bare ID or
+// by display name. Names are compared case-insensitively; the unique ID
+// wins over the other forms.
+func (b *AdmxBundle) FindProduct(ref string) (*PolicyPlusProduct, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, false
+	}
+	if product, ok := b.FlatProducts[ref]; ok {
+		return product, true
+	}
+
+	var byID, byName *PolicyPlusProduct
+	for _, product := range b.FlatProducts {
+		if byID == nil && strings.EqualFold(product.RawProduct.ID, ref) {
+			byID = product
+		}
+		if byName == nil && strings.EqualFold(product.DisplayName, ref) {
+			byName = product
+		}
+	}
+	if byID != nil {
+		return byID, true
+	}
+	return byName, byName != nil
+}
+
+// Evaluate tells whether the definition covers product. Nested support
+// definitions are followed; a product entry covers the products below it
+// and, since some version of it is meant, the products above it. A range
+// entry covers the children of its product whose version index lies in the
+// range.
+func (s *PolicyPlusSupport) Evaluate(product *PolicyPlusProduct) SupportResult {
+	return s.evaluate(product, make(map[*PolicyPlusSupport]bool))
+}
+
+func (s *PolicyPlusSupport) evaluate(product *PolicyPlusProduct, visiting map[*PolicyPlusSupport]bool) SupportResult {
+	if visiting[s] || len(s.Elements) == 0 {
+		return SupportUnknown
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	results := make([]SupportResult, 0, len(s.Elements))
+	for _, entry := range s.Elements {
+		switch {
+		case entry.SupportDefinition != nil:
+			results = append(results, entry.SupportDefinition.evaluate(product, visiting))
+		case entry.Product != nil:
+			results = append(results, entry.evaluateProduct(product))
+		default:
+			results = append(results, SupportUnknown)
+		}
+	}
+
+	if s.RawSupport != nil && s.RawSupport.Logic == AllOf {
+		return combineSupport(results, NotSupported, Supported)
+	}
+	return combineSupport(results, Supported, NotSupported)
+}
+
+// combineSupport returns decisive when any result is decisive, unknown when
+// any result is unknown and otherwise fallback.
+func combineSupport(results []SupportResult, decisive, fallback SupportResult) SupportResult {
+	unknown := false
+	for _, result := range results {
+		if result == decisive {
+			return decisive
+		}
+		if result == SupportUnknown {
+			unknown = true
+		}
+	}
+	if unknown {
+		return SupportUnknown
+	}
+	return fallback
+}
+
+func (e *PolicyPlusSupportEntry) evaluateProduct(target *PolicyPlusProduct) SupportResult {
+	entryProduct := e.Product
+	if !e.RawSupportEntry.IsRange {
+		if productWithin(target, entryProduct) || productWithin(entryProduct, target) {
+			return Supported
+		}
+		return NotSupported
+	}
+
+	// Target is a version of the product: check the version it belongs to
+	if target != entryProduct && productWithin(target, entryProduct) {
+		version := target
+		for version.Parent != entryProduct {
+			version = version.Parent
+		}
+		if e.versionInRange(version) {
+			return Supported
+		}
+		return NotSupported
+	}
+
+	// Target is the product itself or above it: any version in range will do
+	if productWithin(entryProduct, target) {
+		if len(entryProduct.Children) == 0 {
+			return Supported
+		}
+		for _, version := range entryProduct.Children {
+			if e.versionInRange(version) {
+				return Supported
+			}
+		}
+	}
+	return NotSupported
+}
+
+func (e *PolicyPlusSupportEntry) versionInRange(product *PolicyPlusProduct) bool {
+	version := product.RawProduct.Version
+	if e.RawSupportEntry.MinVersion != nil && version < *e.RawSupportEntry.MinVersion {
+		return false
+	}
+	if e.RawSupportEntry.MaxVersion != nil && version > *e.RawSupportEntry.MaxVersion {
+		return false
+	}
+	return true
+}
+
+// productWithin reports whether product is ancestor or lies below it.
+func productWithin(product, ancestor *PolicyPlusProduct) bool {
+	for p := product; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesTo reports whether the policy may apply to one of products. Only
+// policies whose support definition rules all of them out return false.
+func (p *PolicyPlusPolicy) AppliesTo(products ...*PolicyPlusProduct) bool {
+	if p.SupportedOn == nil || len(products) == 0 {
+		return true
+	}
+	for _, product := range products {
+		if p.SupportedOn.Evaluate(product) != NotSupported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// templateRoot a tree of template files. Files are read through fsys with
+// slash separated names; name is the folder or archive the tree came from
+// and prefixes the paths reported to callers.
+type templateRoot struct {
+	fsys      fs.FS
+	name      string
+	cacheable bool
+	closer    io.Closer
+}
+
+// isZipPath reports whether path names a zip archive
+func isZipPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// openTemplateRoot opens a folder or a zip archive of templates. The caller
+// closes the root.
+func openTemplateRoot(path string) (*templateRoot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() && isZipPath(path) {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open template pack: %w", err)
+		}
+		return &templateRoot{fsys: archive, name: path, cacheable: true, closer: archive}, nil
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is neither a folder nor a zip archive", path)
+	}
+	return &templateRoot{fsys: os.DirFS(path), name: path, cacheable: true}, nil
+}
+
+func (r *templateRoot) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// displayPath returns the path of a file of the root as shown to users
+func (r *templateRoot) displayPath(name string) string {
+	if r.name == "" {
+		return name
+	}
+	return filepath.Join(r.name, filepath.FromSlash(name))
+}
+
+// readTemplateFile reads a template file from fsys, or from the operating
+// system when fsys is nil
+func readTemplateFile(fsys fs.FS, name string) ([]byte, error) {
+	var file io.ReadCloser
+	var err error
+	if fsys == nil {
+		file, err = os.Open(name)
+	} else {
+		file, err = fsys.Open(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// findPath resolves the elements below dir one by one, ignoring case, so that
+// "en-us/Foo.ADML" finds "en-US/foo.adml" in archives and other case
+// sensitive file systems. An exact match is preferred.
+func findPath(fsys fs.FS, dir string, elems ...string) (string, bool) {
+	current := dir
+	for _, elem := range elems {
+		exact := path.Join(current, elem)
+		if _, err := fs.Stat(fsys, exact); err == nil {
+			current = exact
+			continue
+		}
+
+		entries, err := fs.ReadDir(fsys, current)
+		if err != nil {
+			return "", false
+		}
+		found := false
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), elem) {
+				current = path.Join(current, entry.Name())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,371 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// packManifestName file in each pack folder that describes the pack
+const packManifestName = "pack.json"
+
+// Limits on the unpacked templates of a pack. Uploads are limited before
+// they are unpacked, but a small archive can expand to any size.
+const (
+	maxPackFileSize  = 32 << 20
+	maxPackTotalSize = 256 << 20
+)
+
+// TemplatePack a set of custom templates installed into a PackStore
+type TemplatePack struct {
+	ID         string    `json:"id"`
+	Namespaces []string  `json:"namespaces"`
+	Files      []string  `json:"files"`
+	Locales    []string  `json:"locales"`
+	Installed  time.Time `json:"installed"`
+}
+
+// PackError an uploaded pack that cannot be installed, with the reasons
+type PackError struct {
+	Pack     string
+	Problems []string
+}
+
+func (e *PackError) Error() string {
+	return fmt.Sprintf("template pack '%s' is invalid: %s", e.Pack, strings.Join(e.Problems, "; "))
+}
+
+// PackReplaceError an uploaded pack that defines namespaces of templates
+// outside the store, which it would replace. Replaces maps each namespace
+// to the file of the template it replaces.
+type PackReplaceError struct {
+	Pack     string
+	Replaces map[string]string
+}
+
+func (e *PackReplaceError) Error() string {
+	namespaces := make([]string, 0, len(e.Replaces))
+	for ns := range e.Replaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return fmt.Sprintf("template pack '%s' would replace %s", e.Pack, strings.Join(namespaces, ", "))
+}
+
+// PackStore managed folder of custom template packs. Each pack lives in a
+// subfolder named after its ID, so the folder can be loaded as one template
+// root. Packs are staged in a hidden folder next to them, which template
+// scans skip.
+type PackStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewPackStore returns a store for dir; the folder is created on first
+// install.
+func NewPackStore(dir string) *PackStore {
+	return &PackStore{dir: dir}
+}
+
+// Dir returns the folder of the store, to be loaded as a template root
+func (s *PackStore) Dir() string {
+	return s.dir
+}
+
+// SanitizePackID turns a file or pack name into a pack ID: the extension
+// is dropped and characters other than letters, digits, '-', '_' and '.'
+// become '-'.
+func SanitizePackID(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, name)
+	return strings.Trim(id, "-.")
+}
+
+// List returns the installed packs sorted by ID
+func (s *PackStore) List() ([]*TemplatePack, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+func (s *PackStore) list() ([]*TemplatePack, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []*TemplatePack{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packs := []*TemplatePack{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		pack, err := s.readManifest(entry.Name())
+		if err != nil {
+			continue
+		}
+		packs = append(packs, pack)
+	}
+	sort.Slice(packs, func(i, j int) bool {
+		return strings.ToLower(packs[i].ID) < strings.ToLower(packs[j].ID)
+	})
+	return packs, nil
+}
+
+// Get returns an installed pack
+func (s *PackStore) Get(id string) (*TemplatePack, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readManifest(id)
+}
+
+func (s *PackStore) readManifest(id string) (*TemplatePack, error) {
+	if SanitizePackID(id) != id || id == "" {
+		return nil, fmt.Errorf("invalid pack ID '%s'", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id, packManifestName))
+	if err != nil {
+		return nil, err
+	}
+	pack := &TemplatePack{}
+	if err := json.Unmarshal(data, pack); err != nil {
+		return nil, fmt.Errorf("failed to read pack manifest: %w", err)
+	}
+	pack.ID = id
+	return pack, nil
+}
+
+// Contains reports whether a template file path lies in the folder of a pack
+func (s *PackStore) Contains(id, file string) bool {
+	rel, err := filepath.Rel(filepath.Join(s.dir, id), file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Holds reports whether a template file path lies in the folder of the store
+func (s *PackStore) Holds(file string) bool {
+	rel, err := filepath.Rel(s.dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Install validates the templates of src and installs them as pack id. The
+// .admx, .adml and .adm files are copied with their folder layout; other
+// files are ignored. Every template must load with its ADML, and the pack
+// must not define a namespace that another installed pack already defines.
+// protected maps the namespaces of templates outside the store to their
+// files; a pack that defines one of them is refused with a
+// PackReplaceError. An installed pack with the same ID is replaced.
+func (s *PackStore) Install(id string, src fs.FS, protected map[string]string, languageCodes ...string) (*TemplatePack, error) {
+	if SanitizePackID(id) != id || id == "" {
+		return nil, fmt.Errorf("invalid pack ID '%s'", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create template pack folder: %w", err)
+	}
+	staging, err := os.MkdirTemp(s.dir, ".staging-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage template pack: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	pack := &TemplatePack{ID: id, Namespaces: []string{}, Files: []string{}, Locales: []string{}}
+	if err := copyTemplateFiles(src, staging, pack); err != nil {
+		return nil, err
+	}
+	if err := s.validate(staging, pack, languageCodes); err != nil {
+		return nil, err
+	}
+	replaced := &PackReplaceError{Pack: pack.ID, Replaces: map[string]string{}}
+	for _, ns := range pack.Namespaces {
+		if file, ok := protected[ns]; ok {
+			replaced.Replaces[ns] = file
+		}
+	}
+	if len(replaced.Replaces) > 0 {
+		return nil, replaced
+	}
+
+	pack.Installed = time.Now().UTC()
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(staging, packManifestName), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write pack manifest: %w", err)
+	}
+
+	target := filepath.Join(s.dir, id)
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to replace template pack: %w", err)
+	}
+	if err := os.Rename(staging, target); err != nil {
+		return nil, fmt.Errorf("failed to install template pack: %w", err)
+	}
+	return pack, nil
+}
+
+// copyTemplateFiles copies the template files of src below dir and records
+// them in pack. Files or packs larger than the limits are refused with a
+// PackError.
+func copyTemplateFiles(src fs.FS, dir string, pack *TemplatePack) error {
+	locales := map[string]bool{}
+	var total int64
+	err := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		lower := strings.ToLower(name)
+		isAdml := strings.HasSuffix(lower, ".adml")
+		if !isAdml && !strings.HasSuffix(lower, ".admx") && !strings.HasSuffix(lower, ".adm") {
+			return nil
+		}
+
+		limit := min(maxPackFileSize, maxPackTotalSize-total)
+		data, err := readPackFile(src, name, limit)
+		if errors.Is(err, errPackFileTooLarge) {
+			problem := fmt.Sprintf("%s is larger than %d MB unpacked", name, maxPackFileSize>>20)
+			if limit < maxPackFileSize {
+				problem = fmt.Sprintf("the templates are larger than %d MB unpacked", maxPackTotalSize>>20)
+			}
+			return &PackError{Pack: pack.ID, Problems: []string{problem}}
+		}
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+
+		pack.Files = append(pack.Files, name)
+		if isAdml && path.Dir(name) != "." {
+			locales[path.Base(path.Dir(name))] = true
+		}
+		return nil
+	})
+	var invalid *PackError
+	if errors.As(err, &invalid) {
+		return invalid
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy template pack: %w", err)
+	}
+
+	for locale := range locales {
+		pack.Locales = append(pack.Locales, locale)
+	}
+	sort.Strings(pack.Locales)
+	sort.Strings(pack.Files)
+	return nil
+}
+
+// errPackFileTooLarge a template of a pack is larger than the limit
+var errPackFileTooLarge = errors.New("template file too large")
+
+// readPackFile reads a template of a pack, at most limit bytes
+func readPackFile(src fs.FS, name string, limit int64) ([]byte, error) {
+	file, err := src.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to open file: %w", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read file: %w", name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errPackFileTooLarge
+	}
+	return data, nil
+}
+
+// validate loads the staged pack on its own and checks it against the other
+// installed packs
+func (s *PackStore) validate(staging string, pack *TemplatePack, languageCodes []string) error {
+	invalid := &PackError{Pack: pack.ID}
+
+	bundle := NewAdmxBundle()
+	failures, err := bundle.LoadFolder(staging, languageCodes...)
+	if err != nil {
+		return err
+	}
+	for _, fail := range failures {
+		if rel, err := filepath.Rel(staging, fail.AdmxPath); err == nil {
+			fail.AdmxPath = filepath.ToSlash(rel)
+		}
+		invalid.Problems = append(invalid.Problems, fail.Error())
+	}
+	for ns := range bundle.NamespaceSources {
+		pack.Namespaces = append(pack.Namespaces, ns)
+	}
+	sort.Strings(pack.Namespaces)
+	if len(pack.Namespaces) == 0 && len(failures) == 0 {
+		invalid.Problems = append(invalid.Problems, "no .admx or .adm templates found")
+	}
+
+	installed, err := s.list()
+	if err != nil {
+		return err
+	}
+	for _, other := range installed {
+		if other.ID == pack.ID {
+			continue
+		}
+		for _, ns := range other.Namespaces {
+			if _, ok := bundle.NamespaceSources[ns]; ok {
+				invalid.Problems = append(invalid.Problems,
+					fmt.Sprintf("namespace %s is already installed by pack '%s'", ns, other.ID))
+			}
+		}
+	}
+
+	if len(invalid.Problems) > 0 {
+		return invalid
+	}
+	return nil
+}
+
+// Remove deletes an installed pack
+func (s *PackStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.readManifest(id); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(s.dir, id)); err != nil {
+		return fmt.Errorf("failed to remove template pack: %w", err)
+	}
+	return nil
+}
@@ -8,8 +8,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"gopolicy/internal/handlers"
 	"gopolicy/internal/policy"
@@ -22,31 +24,113 @@ var staticFiles embed.FS
 var logoFile []byte
 
 func main() {
+	// Parse command line flags
+	portFlag := flag.Int("p", 8080, "Port number to run the server on")
+	convertAdmFlag := flag.String("convert-adm", "", "Convert a legacy .adm template to ADMX/ADML and exit")
+	convertOutFlag := flag.String("convert-out", "", "Output folder for -convert-adm (default: folder of the .adm file)")
+	convertLocaleFlag := flag.String("convert-locale", "en-US", "ADML locale folder for -convert-adm")
+	cacheDirFlag := flag.String("cache-dir", defaultCacheDir(), "Folder for the parsed template cache")
+	noCacheFlag := flag.Bool("no-cache", false, "Always parse templates instead of using the cache")
+	templatesFlag := flag.String("templates", "", "Ordered list of template folders separated by ';' (default: %SystemRoot%\\PolicyDefinitions)")
+	packFlag := flag.String("pack", "", "Zipped template packs separated by ';', loaded after the template folders")
+	customTemplatesFlag := flag.String("custom-templates", defaultCustomTemplatesDir(), "Folder for template packs uploaded through the API, loaded last (empty disables uploads)")
+	conflictFlag := flag.String("conflict", "revision", "Rule for namespaces defined in several folders: revision, first or error")
+	watchFlag := flag.Bool("watch", false, "Reload templates automatically when a template folder changes")
+	watchIntervalFlag := flag.Duration("watch-interval", 5*time.Second, "Polling interval for -watch")
+	lookupFlag := flag.String("lookup", "", "Print the policies that write a registry key or value (e.g. HKLM\\Software\\Policies\\...) and exit")
+	policyKeysFlag := flag.Bool("scan-policy-keys", false, "Also list values below Software\\Policies in the registry that no template describes as Extra Registry Settings")
+	tattooBackupFlag := flag.String("tattoo-backup", defaultTattooBackupFile(), "File for the values that policies writing outside the Policies keys overwrite (empty disables backups)")
+	flag.Parse()
+
+	if *convertAdmFlag != "" {
+		outDir := *convertOutFlag
+		if outDir == "" {
+			outDir = filepath.Dir(*convertAdmFlag)
+		}
+		admxPath, admlPath, err := policy.ConvertAdmFile(*convertAdmFlag, outDir, *convertLocaleFlag)
+		if err != nil {
+			log.Fatalf("ADM conversion failed: %v", err)
+		}
+		fmt.Printf("Written %s\nWritten %s\n", admxPath, admlPath)
+		return
+	}
+
 	fmt.Println("Policy Plus - Go Edition")
 	fmt.Println("Local Group Policy Editor for all Windows editions")
 	fmt.Println("========================================")
 
-	// Create main workspace
-	workspace := policy.NewAdmxBundle()
+	conflictRule, err := policy.ParseNamespaceConflictRule(*conflictFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Load default ADMX folder
-	admxPath := os.Getenv("SystemRoot")
-	if admxPath == "" {
-		admxPath = "C:\\Windows"
+	// Template roots, in priority order
+	templateRoots := filepath.SplitList(*templatesFlag)
+	if len(templateRoots) == 0 {
+		admxPath := os.Getenv("SystemRoot")
+		if admxPath == "" {
+			admxPath = "C:\\Windows"
+		}
+		templateRoots = []string{admxPath + "\\PolicyDefinitions"}
+	}
+	templateRoots = append(templateRoots, filepath.SplitList(*packFlag)...)
+	var packStore *policy.PackStore
+	if *customTemplatesFlag != "" {
+		if err := os.MkdirAll(*customTemplatesFlag, 0755); err != nil {
+			log.Printf("Custom template folder could not be created: %v\n", err)
+		} else {
+			packStore = policy.NewPackStore(*customTemplatesFlag)
+			templateRoots = append(templateRoots, packStore.Dir())
+		}
 	}
-	admxPath += "\\PolicyDefinitions"
+	locales := detectLocales()
 
-	fmt.Printf("Loading ADMX files: %s\n", admxPath)
-	if _, err := os.Stat(admxPath); err == nil {
-		locales := detectLocales()
-		fmt.Printf("Detected locales: %v\n", locales)
-		failures, err := workspace.LoadFolder(admxPath, locales...)
+	// loadWorkspace builds a fresh bundle; it is used at startup and on reload
+	loadWorkspace := func() (*policy.AdmxBundle, []*policy.AdmxLoadFailure, error) {
+		workspace := policy.NewAdmxBundle()
+		workspace.SetNamespaceConflictRule(conflictRule)
+		if !*noCacheFlag {
+			workspace.EnableCache(*cacheDirFlag)
+		}
+		var roots []string
+		for _, root := range templateRoots {
+			if _, err := os.Stat(root); err != nil {
+				log.Printf("Template folder or pack not found: %s\n", root)
+				continue
+			}
+			roots = append(roots, root)
+		}
+		if len(roots) == 0 {
+			return workspace, nil, nil
+		}
+		failures, err := workspace.LoadFolders(roots, locales...)
 		if err != nil {
-			log.Printf("ADMX loading error: %v\n", err)
+			return nil, failures, err
+		}
+		fmt.Printf("Loaded %s\n", workspace.LastLoadStats())
+		for _, fail := range failures {
+			log.Printf("%v\n", fail)
 		}
-		if len(failures) > 0 {
-			log.Printf("%d files failed to load\n", len(failures))
+		for _, issue := range workspace.AdmlRevisionIssues() {
+			log.Printf("ADML %s has revision %s but %s requires %s\n",
+				issue.AdmlPath, issue.Revision, issue.AdmxPath, issue.Required)
 		}
+		return workspace, failures, nil
+	}
+
+	fmt.Printf("Loading ADMX files: %s\n", strings.Join(templateRoots, ", "))
+	fmt.Printf("Detected locales: %v\n", locales)
+	workspace, failures, err := loadWorkspace()
+	if err != nil {
+		log.Printf("ADMX loading error: %v\n", err)
+		workspace = policy.NewAdmxBundle()
+	}
+	if len(failures) > 0 {
+		log.Printf("%d files failed to load\n", len(failures))
+	}
+
+	if *lookupFlag != "" {
+		os.Exit(printRegistryLookup(workspace, *lookupFlag))
 	}
 
 	// HTTP handlers
@@ -67,6 +151,17 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create handler: %v", err)
 	}
+	handler.SetBundleLoader(loadWorkspace)
+	handler.SetPolicyKeyScan(*policyKeysFlag)
+	if packStore != nil {
+		handler.SetPackStore(packStore)
+	}
+	if *tattooBackupFlag != "" {
+		handler.SetTattooStore(policy.NewTattooStore(*tattooBackupFlag))
+	}
+	if *watchFlag {
+		go handler.WatchTemplates(templateRoots, *watchIntervalFlag, nil)
+	}
 	mux.HandleFunc("/", handler.HandleIndex)
 	mux.HandleFunc("/api/categories", handler.HandleCategories)
 	mux.HandleFunc("/api/policies", handler.HandlePolicies)
@@ -75,11 +170,21 @@ func main() {
 	mux.HandleFunc("/api/sources", handler.HandleSources)
 	mux.HandleFunc("/api/save", handler.HandleSave)
 	mux.HandleFunc("/api/search", handler.HandleSearch)
+	mux.HandleFunc("/api/footprint", handler.HandleFootprint)
+	mux.HandleFunc("/api/lookup", handler.HandleRegistryLookup)
+	mux.HandleFunc("/api/extra-registry", handler.HandleExtraRegistry)
+	mux.HandleFunc("/api/tattoos", handler.HandleTattoos)
 	mux.HandleFunc("/api/refresh-explorer", handler.HandleRefreshExplorer)
-
-	// Parse command line flags
-	portFlag := flag.Int("p", 8080, "Port number to run the server on")
-	flag.Parse()
+	mux.HandleFunc("/api/reload", handler.HandleReload)
+	mux.HandleFunc("/api/namespaces", handler.HandleNamespaces)
+	mux.HandleFunc("/api/templates/diagnostics", handler.HandleTemplateDiagnostics)
+	mux.HandleFunc("/api/templates/conflicts", handler.HandleRegistryConflicts)
+	mux.HandleFunc("/api/packs", handler.HandlePacks)
+	mux.HandleFunc("/api/packs/", handler.HandlePacks)
+	mux.HandleFunc("/api/products", handler.HandleProducts)
+	mux.HandleFunc("/api/extensions", handler.HandleClientExtensions)
+	mux.HandleFunc("/api/locales", handler.HandleLocales)
+	mux.HandleFunc("/api/locales/coverage", handler.HandleLocalizationCoverage)
 
 	port := fmt.Sprintf(":%d", *portFlag)
 	fmt.Printf("\nStarting web interface: http://localhost%s\n", port)
@@ -91,6 +196,66 @@ func main() {
 	}
 }
 
+// printRegistryLookup prints the policies that write a registry path and
+// returns the exit code: 1 when there are none
+func printRegistryLookup(workspace *policy.AdmxBundle, path string) int {
+	section, key := policy.ParseRegistryPath(path)
+	matches := workspace.LookupRegistry(section, key)
+	if key == "" || len(matches) == 0 {
+		fmt.Printf("\nNo policy writes %s\n", path)
+		return 1
+	}
+
+	fmt.Printf("\n%d policies write %s\n", len(matches), path)
+	for _, match := range matches {
+		pol := match.Policy
+		fmt.Printf("\n%s\n  %s\n", pol.UniqueID, pol.DisplayName)
+		if categories := pol.CategoryPath(); len(categories) > 0 {
+			fmt.Printf("  Category: %s\n", strings.Join(categories, " > "))
+		}
+		target := match.Key
+		if match.ValueName != "" {
+			target += "\\" + match.ValueName
+		}
+		var states []string
+		if match.Enabled {
+			states = append(states, "Enabled")
+		}
+		if match.Disabled {
+			states = append(states, "Disabled")
+		}
+		fmt.Printf("  Changes: %s (%s) when %s\n", target, match.Source, strings.Join(states, " or "))
+		if !policy.IsPolicyKey(match.Key) {
+			fmt.Println("  Outside the Policies keys: the value stays after the policy is removed")
+		}
+	}
+	return 0
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "GoPolicy")
+}
+
+func defaultCustomTemplatesDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "GoPolicy", "Templates")
+}
+
+func defaultTattooBackupFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "GoPolicy", "tattoo-backup.json")
+}
+
 func detectLocales() []string {
 	localeSet := map[string]struct{}{}
 	addLocale := func(loc string) {
@@ -104,8 +269,7 @@ func detectLocales() []string {
 	addLocale(os.Getenv("PreferredLanguage"))
 	addLocale(os.Getenv("UILanguage"))
 
-	// backup locales (Turkish and English)
-	addLocale("tr-TR")
+	// backup locale; other installed locales are available per request
 	addLocale("en-US")
 
 	var locales []string
@@ -4,16 +4,151 @@ let currentPolicy = null;
 let categoriesData = { user: [], computer: [] };
 let searchDebounceTimer = null;
 let currentSearchResults = null;
+let currentLanguage = localStorage.getItem('language') || '';
+let currentProduct = localStorage.getItem('applicableTo') || '';
 
 // On page load
 document.addEventListener('DOMContentLoaded', () => {
+    loadLocales();
+    loadProducts();
     loadCategories();
+    loadDiagnostics();
 });
 
+let templateDiagnostics = null;
+
+// Show a warning badge when templates failed to load
+async function loadDiagnostics() {
+    const badge = document.getElementById('diagnostics-badge');
+    if (!badge) return;
+    try {
+        const response = await fetch('/api/templates/diagnostics');
+        templateDiagnostics = await response.json();
+        const count = templateDiagnostics.failureCount || 0;
+        badge.textContent = `⚠️ ${count} template${count === 1 ? '' : 's'} failed`;
+        badge.style.display = count > 0 ? '' : 'none';
+    } catch (error) {
+        console.error('Failed to load template diagnostics:', error);
+    }
+}
+
+// List the template load failures in the info panel
+function showDiagnostics() {
+    if (!templateDiagnostics) return;
+    const failures = templateDiagnostics.failures || [];
+    const infoPanel = document.getElementById('policy-info');
+    infoPanel.innerHTML = `
+        <h3>Template Load Failures</h3>
+        <p>${failures.length} of ${templateDiagnostics.stats.files} template files could not be loaded.</p>
+        <ul class="diagnostics-list">
+            ${failures.map(fail => `
+                <li>
+                    <strong>${escapeHtml(fail.type)}</strong>
+                    <code>${escapeHtml(fail.file)}</code>
+                    ${fail.info ? `<div class="diagnostics-info">${escapeHtml(fail.info)}</div>` : ''}
+                </li>
+            `).join('')}
+        </ul>
+    `;
+}
+
+// Add the selected language to an API URL
+function withLanguage(url) {
+    if (!currentLanguage) return url;
+    const separator = url.includes('?') ? '&' : '?';
+    return `${url}${separator}lang=${encodeURIComponent(currentLanguage)}`;
+}
+
+// Add the selected target product to an API URL
+function withApplicability(url) {
+    if (!currentProduct) return url;
+    const separator = url.includes('?') ? '&' : '?';
+    return `${url}${separator}applicableTo=${encodeURIComponent(currentProduct)}`;
+}
+
+// Load the product tree into the "applies to" selector
+async function loadProducts() {
+    const select = document.getElementById('product-select');
+    if (!select) return;
+    try {
+        const response = await fetch(withLanguage('/api/products'));
+        const products = await response.json();
+        select.innerHTML = '<option value="">All products</option>';
+        const addOptions = (nodes, depth) => {
+            (nodes || []).forEach(node => {
+                const option = document.createElement('option');
+                option.value = node.id;
+                option.textContent = `${'\u00a0\u00a0'.repeat(depth)}${node.name}`;
+                select.appendChild(option);
+                addOptions(node.children, depth + 1);
+            });
+        };
+        addOptions(products, 0);
+        if (currentProduct && !select.querySelector(`option[value="${CSS.escape(currentProduct)}"]`)) {
+            currentProduct = '';
+            localStorage.removeItem('applicableTo');
+        }
+        select.value = currentProduct;
+        select.parentElement.style.display = products && products.length > 0 ? '' : 'none';
+    } catch (error) {
+        console.error('Failed to load products:', error);
+    }
+}
+
+// Show only policies that may apply to the selected product
+function changeProduct(product) {
+    currentProduct = product;
+    if (product) {
+        localStorage.setItem('applicableTo', product);
+    } else {
+        localStorage.removeItem('applicableTo');
+    }
+    loadCategories();
+    if (currentCategory) {
+        loadPolicies(currentCategory);
+    }
+}
+
+// Load available ADML locales into the language selector
+async function loadLocales() {
+    const select = document.getElementById('language-select');
+    if (!select) return;
+    try {
+        const response = await fetch(withLanguage('/api/locales'));
+        const data = await response.json();
+        const locales = data.locales || [];
+        select.innerHTML = '<option value="">Browser default</option>';
+        locales.forEach(locale => {
+            const option = document.createElement('option');
+            option.value = locale.code;
+            option.textContent = locale.code;
+            select.appendChild(option);
+        });
+        select.value = currentLanguage;
+        select.parentElement.style.display = locales.length > 1 ? '' : 'none';
+    } catch (error) {
+        console.error('Failed to load locales:', error);
+    }
+}
+
+// Switch the language of names and descriptions
+function changeLanguage(language) {
+    currentLanguage = language;
+    if (language) {
+        localStorage.setItem('language', language);
+    } else {
+        localStorage.removeItem('language');
+    }
+    loadCategories();
+    if (currentCategory) {
+        loadPolicies(currentCategory);
+    }
+}
+
 // Load categories
 async function loadCategories() {
     try {
-        const response = await fetch('/api/categories');
+        const response = await fetch(withApplicability(withLanguage('/api/categories')));
         const data = await response.json();
         categoriesData = {
             user: data.user || [],
@@ -168,11 +303,20 @@ async function selectCategory(categoryId, event) {
     
     // Show category information
     const category = findCategoryInAll(categoryId);
+    if (category && category.virtual) {
+        document.getElementById('policy-info').innerHTML = `
+            <h3>${escapeHtml(category.name)}</h3>
+            <div class="category-description"><p>${escapeHtml(category.description)}. They can be edited or deleted as raw values.</p></div>
+            <p><strong>${category.policyCount} values</strong> found.</p>
+        `;
+        await loadExtraRegistry(category.section);
+        return;
+    }
     if (category) {
         const infoPanel = document.getElementById('policy-info');
         infoPanel.innerHTML = `
-            <h3>${category.name}</h3>
-            <p>${category.description || 'No description'}</p>
+            <h3>${escapeHtml(category.name)}</h3>
+            <div class="category-description">${category.descriptionHtml || '<p>No description</p>'}</div>
             <p><strong>${category.policyCount} policies</strong> found.</p>
         `;
     }
@@ -205,7 +349,7 @@ function findCategoryInAll(id) {
 // Load policies
 async function loadPolicies(categoryId) {
     try {
-        const response = await fetch(`/api/policies?category=${encodeURIComponent(categoryId)}`);
+        const response = await fetch(withApplicability(withLanguage(`/api/policies?category=${encodeURIComponent(categoryId)}`)));
         const policies = await response.json();
         renderPolicies(policies);
     } catch (error) {
@@ -241,11 +385,12 @@ function renderPolicies(policies) {
         const stateClass = policy.state.toLowerCase().replace(' ', '-');
         
         div.innerHTML = `
-            <h4>${policy.name}</h4>
-            <p>${policy.description || 'No description'}</p>
+            <h4>${escapeHtml(policy.name)}</h4>
+            <p>${escapeHtml(policy.description || 'No description')}</p>
             <div style="display: flex; align-items: center; gap: 12px; margin-top: 8px;">
                 <span class="policy-state ${stateClass}">${policy.state}</span>
                 <small style="color: var(--text-light); font-size: 0.8125rem;">${policy.section}</small>
+                ${renderTattooBadge(policy)}
             </div>
         `;
         
@@ -253,10 +398,121 @@ function renderPolicies(policies) {
     });
 }
 
+// Extra Registry Settings: raw values that no loaded template describes
+let extraRegistryValues = [];
+
+function extraRegistrySection(section) {
+    return section === 'User' ? 'user' : 'machine';
+}
+
+async function loadExtraRegistry(section) {
+    try {
+        const response = await fetch(`/api/extra-registry?section=${extraRegistrySection(section)}`);
+        const data = await response.json();
+        if (!response.ok) {
+            showError(data.error || 'Failed to load extra registry settings');
+            return;
+        }
+        extraRegistryValues = data.values;
+        renderExtraRegistry();
+    } catch (error) {
+        console.error('Failed to load extra registry settings:', error);
+        showError('Failed to load extra registry settings');
+    }
+}
+
+function formatRegistryData(value) {
+    if (value.deleted) {
+        return 'deleted by Group Policy';
+    }
+    if (Array.isArray(value.data)) {
+        return value.data.join('\n');
+    }
+    return value.data === null || value.data === undefined ? '' : String(value.data);
+}
+
+function renderExtraRegistry() {
+    const list = document.getElementById('policies');
+    if (extraRegistryValues.length === 0) {
+        list.innerHTML = '<p>No extra registry settings.</p>';
+        return;
+    }
+
+    list.innerHTML = extraRegistryValues.map((v, i) => {
+        const s = v.suggestion;
+        const suggestion = s ? `<p class="diagnostics-info">Probably described by ${escapeHtml(s.template || s.namespace)}
+            (${escapeHtml(s.namespace)}${s.loaded ? ', loaded' : ', missing'}): ${escapeHtml(s.reason)}</p>` : '';
+        const edit = v.editable ? `<button onclick="editExtraRegistry(${i})">Edit</button>` : '';
+        return `<div class="policy-item extra-registry-item" id="extra-registry-${i}">
+            <h4><code>${escapeHtml(v.key)}\\${escapeHtml(v.valueName)}</code></h4>
+            <p>${escapeHtml(v.type)} <code class="extra-registry-data">${escapeHtml(formatRegistryData(v))}</code></p>
+            ${suggestion}
+            <div class="extra-registry-actions">
+                <small>${escapeHtml(v.section)} · ${escapeHtml(v.source)}</small>
+                ${edit}
+                <button class="danger" onclick="deleteExtraRegistry(${i})">Delete</button>
+            </div>
+        </div>`;
+    }).join('');
+}
+
+function editExtraRegistry(index) {
+    const v = extraRegistryValues[index];
+    const item = document.getElementById(`extra-registry-${index}`);
+    const hint = v.type === 'REG_MULTI_SZ' ? 'One entry per line' : (v.type === 'REG_DWORD' ? 'Decimal or 0x hexadecimal' : '');
+    item.querySelector('.extra-registry-actions').innerHTML = `
+        <textarea class="extra-registry-editor" rows="${v.type === 'REG_MULTI_SZ' ? 4 : 1}" placeholder="${hint}">${escapeHtml(formatRegistryData(v))}</textarea>
+        <button onclick="saveExtraRegistry(${index})">Save</button>
+        <button onclick="renderExtraRegistry()">Cancel</button>
+    `;
+}
+
+async function saveExtraRegistry(index) {
+    const v = extraRegistryValues[index];
+    const data = document.querySelector(`#extra-registry-${index} .extra-registry-editor`).value;
+    await changeExtraRegistry('POST', '/api/extra-registry', {
+        section: extraRegistrySection(v.section),
+        key: v.key,
+        valueName: v.valueName,
+        type: v.type,
+        data: data
+    }, v.section);
+}
+
+async function deleteExtraRegistry(index) {
+    const v = extraRegistryValues[index];
+    if (!confirm(`Delete ${v.key}\\${v.valueName} from ${v.section} policy?`)) {
+        return;
+    }
+    const params = new URLSearchParams({section: extraRegistrySection(v.section), key: v.key, valueName: v.valueName});
+    await changeExtraRegistry('DELETE', `/api/extra-registry?${params}`, null, v.section);
+}
+
+async function changeExtraRegistry(method, url, body, section) {
+    try {
+        const options = {method: method};
+        if (body) {
+            options.headers = {'Content-Type': 'application/json'};
+            options.body = JSON.stringify(body);
+        }
+        const response = await fetch(url, options);
+        const result = await response.json();
+        if (!response.ok) {
+            showError(result.error || 'Registry value could not be changed');
+            return;
+        }
+        showSuccess(result.message);
+        await loadExtraRegistry(section);
+    } catch (error) {
+        console.error('Failed to change registry value:', error);
+        showError('Failed to change registry value: ' + error.message);
+    }
+}
+
 // Open policy editor in right panel
 async function openPolicyEditor(policyId) {
     try {
-        const response = await fetch(`/api/policy/${encodeURIComponent(policyId)}`);
+        const response = await fetch(withApplicability(withLanguage(`/api/policy/${encodeURIComponent(policyId)}`)));
         const policy = await response.json();
         currentPolicy = policy;
         resetApplyButton();
@@ -279,10 +535,14 @@ async function openPolicyEditor(policyId) {
         let stateBadgeClass = 'not-configured';
         if (currentState === 'Enabled') stateBadgeClass = 'enabled';
         else if (currentState === 'Disabled') stateBadgeClass = 'disabled';
+        else if (currentState === 'Unknown') stateBadgeClass = 'unknown';
         
         // Create panel content
         let html = `
-            <p class="policy-description">${policy.description || 'No description available'}</p>
+            <div class="policy-description">${policy.descriptionHtml || '<p>No description available</p>'}</div>
+            ${renderPolicyReferences(policy)}
+            ${renderStateMismatches(policy)}
+            ${renderTattooWarning(policy)}
             
             <div class="form-group">
                 <label>
@@ -310,11 +570,21 @@ async function openPolicyEditor(policyId) {
             <div id="policy-elements">
         `;
         
-        // Add policy elements
+        // Add policy elements in presentation order, with the labels between them
         if (policy.elements && policy.elements.length > 0) {
             html += '<h4 style="margin-top: 24px; color: var(--text-primary); font-size: 1rem; font-weight: 600; margin-bottom: 16px;">Settings:</h4>';
-            policy.elements.forEach(elem => {
-                html += renderPolicyElement(elem);
+            const layout = policy.layout && policy.layout.length > 0
+                ? policy.layout
+                : policy.elements.map(elem => ({ type: 'element', elementId: elem.id }));
+            layout.forEach(item => {
+                if (item.type === 'label') {
+                    html += `<p class="presentation-label">${escapeHtml(item.text || '')}</p>`;
+                    return;
+                }
+                const elem = policy.elements.find(e => e.id === item.elementId);
+                if (elem) {
+                    html += renderPolicyElement(elem);
+                }
             });
         }
         
@@ -322,6 +592,7 @@ async function openPolicyEditor(policyId) {
             </div>
             <div class="registry-info">
                 <small><strong>Registry:</strong> ${policy.registryKey}</small>
+                ${renderFootprint(policy.footprint)}
             </div>
             <div class="panel-actions">
                 <button id="apply-policy-button" onclick="applyPolicy()">Apply</button>
@@ -408,7 +679,18 @@ function renderPolicyElement(elem) {
             if (hasValue) {
                 textAttrs += ` value="${escapeHtml(String(elem.defaultValue))}"`;
             }
+            if (elem.suggestions && elem.suggestions.length > 0) {
+                textAttrs += ` list="suggestions-${elem.id}"`;
+            }
             html += `<input type="text" class="form-control" id="elem-${elem.id}" data-element-id="${elem.id}" data-element-type="text" placeholder="${escapeHtml(elem.label || '')}"${textAttrs}>`;
+            if (elem.suggestions && elem.suggestions.length > 0) {
+                const suggestions = elem.noSort ? elem.suggestions : [...elem.suggestions].sort();
+                html += `<datalist id="suggestions-${elem.id}">`;
+                suggestions.forEach(suggestion => {
+                    html += `<option value="${escapeHtml(suggestion)}">`;
+                });
+                html += '</datalist>';
+            }
             if (hasValue) {
                 html += `<small style="display: block; color: var(--success-color); margin-top: 6px; font-weight: 500; font-size: 0.8125rem;">✓ Saved value loaded</small>`;
             }
@@ -429,6 +711,9 @@ function renderPolicyElement(elem) {
             if (hasNumValue) {
                 numAttrs += ` value="${elem.defaultValue}"`;
             }
+            if (elem.spinner && elem.spinnerStep) {
+                numAttrs += ` step="${elem.spinnerStep}"`;
+            }
             if (elem.required) {
                 numAttrs += ` required`;
             }
@@ -459,9 +744,15 @@ function renderPolicyElement(elem) {
             }
             let selectedOptionName = null;
             if (elem.options && elem.options.length > 0) {
-                elem.options.forEach(opt => {
-                    const selected = (elem.defaultValue !== undefined && elem.defaultValue === opt.index) ? ' selected' : '';
-                    if (selected) selectedOptionName = opt.displayName;
+                const hasSaved = elem.defaultValue !== undefined && elem.defaultValue !== null;
+                const options = !elem.noSort && elem.control === 'dropdownList'
+                    ? [...elem.options].sort((a, b) => a.displayName.localeCompare(b.displayName))
+                    : elem.options;
+                options.forEach(opt => {
+                    const isSaved = hasSaved && elem.defaultValue === opt.index;
+                    const isDefault = !hasSaved && elem.defaultItem === opt.index;
+                    const selected = (isSaved || isDefault) ? ' selected' : '';
+                    if (isSaved) selectedOptionName = opt.displayName;
                     html += `<option value="${opt.index}"${selected}>${escapeHtml(opt.displayName)}</option>`;
                 });
             } else {
@@ -613,6 +904,96 @@ function addListItem(wrapperId, isKeyValue) {
     }
 }
 
+// Render support information, client-side extensions, seeAlso links and
+// keywords of a policy
+function renderPolicyReferences(policy) {
+    let html = '';
+    if (policy.supportedOn) {
+        html += `<p class="policy-supported-on"><strong>Supported on:</strong> ${escapeHtml(policy.supportedOn)}</p>`;
+    }
+    if (policy.applicable === false) {
+        html += '<p class="policy-not-applicable">This policy does not apply to the selected product.</p>';
+    }
+    if (policy.clientExtensions && policy.clientExtensions.length > 0) {
+        const names = policy.clientExtensions.map(cse =>
+            `<span title="${escapeHtml(cse.guid)}">${escapeHtml(cse.name || cse.guid)}</span>`);
+        html += `<p class="policy-supported-on"><strong>Processed by:</strong> ${names.join(', ')}</p>`;
+    }
+    if (policy.seeAlso && policy.seeAlso.length > 0) {
+        const items = policy.seeAlso.map(ref => ref.url
+            ? `<li><a href="${escapeHtml(ref.url).replace(/"/g, '&quot;')}" target="_blank" rel="noopener noreferrer">${escapeHtml(ref.text)}</a></li>`
+            : `<li>${escapeHtml(ref.text)}</li>`);
+        html += `<div class="policy-see-also"><strong>See also</strong><ul>${items.join('')}</ul></div>`;
+    }
+    if (policy.keywords && policy.keywords.length > 0) {
+        const tags = policy.keywords.map(keyword => `<span class="policy-keyword">${escapeHtml(keyword)}</span>`);
+        html += `<div class="policy-keywords">${tags.join('')}</div>`;
+    }
+    return html;
+}
+
+// List what enabling and disabling the policy writes to the registry
+function renderFootprint(footprint) {
+    if (!footprint || (footprint.enabled.length === 0 && footprint.disabled.length === 0)) {
+        return '';
+    }
+    const rows = (entries) => entries.map(e => {
+        const target = e.valueName ? `${e.key}\\${e.valueName}` : `${e.key} (all values)`;
+        const data = [e.type, e.data].filter(Boolean).join(' ');
+        const condition = e.condition ? ` when ${e.condition}` : '';
+        return `<li><code>${escapeHtml(target)}</code> ${escapeHtml(e.action)} ${escapeHtml(data)}
+            <span class="diagnostics-info">${escapeHtml(e.source + condition)}</span></li>`;
+    }).join('');
+    return `<details class="policy-footprint">
+        <summary>Registry footprint (${footprint.keys.length} key${footprint.keys.length === 1 ? '' : 's'})</summary>
+        <strong>Enabled</strong><ul class="diagnostics-list">${rows(footprint.enabled)}</ul>
+        <strong>Disabled</strong><ul class="diagnostics-list">${rows(footprint.disabled)}</ul>
+    </details>`;
+}
+
+// List the registry values that make the policy state Unknown
+function renderStateMismatches(policy) {
+    if (!policy.mismatches || policy.mismatches.length === 0) {
+        return '';
+    }
+    const items = policy.mismatches.map(m => `
+        <li>
+            <code>${escapeHtml(m.key)}\\${escapeHtml(m.valueName)}</code>
+            <div class="diagnostics-info">Expected ${escapeHtml(m.expected)}, found ${escapeHtml(m.actual || 'no value')}</div>
+        </li>`);
+    return `<div class="state-mismatches">
+        <strong>Partially configured:</strong> these registry values do not match one state.
+        Set the policy again to repair it.
+        <ul class="diagnostics-list">${items.join('')}</ul>
+    </div>`;
+}
+
+// Mark policies that write outside the Policies keys
+function renderTattooBadge(policy) {
+    if (!policy.tattooing) {
+        return '';
+    }
+    return '<span class="policy-tattoo" title="Writes outside the Policies keys: its values stay after the policy is removed">Tattoos</span>';
+}
+
+// Explain what a tattooing policy leaves behind and whether a backup exists
+function renderTattooWarning(policy) {
+    if (!policy.tattooing) {
+        return '';
+    }
+    const keys = policy.footprint.unmanagedKeys.map(key => `<li><code>${escapeHtml(key)}</code></li>`);
+    const backups = (policy.tattooBackups || []).map(b =>
+        `${escapeHtml(b.section)}: ${b.values} value${b.values === 1 ? '' : 's'}, recorded ${new Date(b.recorded).toLocaleString()}`);
+    const backupText = backups.length > 0
+        ? `Backed up: ${backups.join('; ')}. Setting the policy to Not Configured restores them.`
+        : 'The values it overwrites are backed up when it is first set.';
+    return `<div class="tattoo-warning">
+        <strong>Writes outside the Policies keys:</strong> Group Policy does not remove these values when the policy is removed.
+        ${backupText}
+        <ul class="diagnostics-list">${keys.join('')}</ul>
+    </div>`;
+}
+
 // Escape HTML
 function escapeHtml(text) {
     const div = document.createElement('div');
@@ -626,6 +1007,7 @@ async function applyPolicy() {
     setApplyButtonLoading(true);
     
     const state = document.querySelector('input[name="policy-state"]:checked').value;
+    clearFieldErrors();
     
     // Collect element values
     const options = {};
@@ -745,17 +1127,33 @@ async function applyPolicy() {
         const rawText = await response.text();
         if (response.ok) {
             let resultMessage = 'Policy applied successfully!';
+            let affected = [];
             if (rawText) {
                 try {
                     const result = JSON.parse(rawText);
                     if (result && (result.message || result.success)) {
                         resultMessage = result.message || resultMessage;
                     }
+                    if (result && Array.isArray(result.defaultsApplied) && result.defaultsApplied.length > 0) {
+                        resultMessage += ` (defaults used for ${result.defaultsApplied.map(d => d.elementId).join(', ')})`;
+                    }
+                    if (result && Array.isArray(result.affectedPolicies)) {
+                        affected = result.affectedPolicies;
+                    }
+                    if (result && result.tattoo && result.tattoo.backup === 'restored') {
+                        resultMessage += ` (${result.tattoo.restored} overwritten value${result.tattoo.restored === 1 ? '' : 's'} restored)`;
+                    } else if (result && result.tattoo && result.tattoo.backup === 'recorded') {
+                        resultMessage += ' (overwritten values backed up)';
+                    }
                 } catch (parseErr) {
                     console.warn('Success response JSON parse error:', parseErr);
                 }
             }
             showSuccess(resultMessage);
+            if (affected.length > 0) {
+                const changes = affected.map(p => `${escapeHtml(p.policyName || p.policyId)} (${p.before} → ${p.after})`);
+                showNotification(`Also changed, as they share registry values: ${changes.join(', ')}`, 'warning');
+            }
             closePolicyPanel();
             // Refresh policy list
             if (currentCategory) {
@@ -767,6 +1165,10 @@ async function applyPolicy() {
                 try {
                     const errorData = JSON.parse(rawText);
                     errorMessage = errorData.error || errorData.message || errorMessage;
+                    if (Array.isArray(errorData.errors) && errorData.errors.length > 0) {
+                        showFieldErrors(errorData.errors);
+                        errorMessage += ': ' + errorData.errors.map(e => `${e.elementId}: ${e.message}`).join('; ');
+                    }
                 } catch {
                     errorMessage = rawText;
                 }
@@ -781,6 +1183,24 @@ async function applyPolicy() {
     }
 }
 
+// Attach option errors returned by the server to their fields
+function showFieldErrors(errors) {
+    errors.forEach(error => {
+        const field = document.querySelector(`#policy-detail-body [data-element-id="${CSS.escape(error.elementId)}"]`);
+        if (!field) return;
+        field.classList.add('field-error');
+        const message = document.createElement('div');
+        message.className = 'field-error-message';
+        message.textContent = error.message;
+        field.insertAdjacentElement('afterend', message);
+    });
+}
+
+function clearFieldErrors() {
+    document.querySelectorAll('#policy-detail-body .field-error').forEach(field => field.classList.remove('field-error'));
+    document.querySelectorAll('#policy-detail-body .field-error-message').forEach(message => message.remove());
+}
+
 // Close modal (kept for compatibility, redirects to closePolicyPanel)
 function closeModal() {
     closePolicyPanel();
@@ -806,6 +1226,31 @@ async function refreshExplorer() {
     }
 }
 
+// Reload ADMX templates
+async function reloadTemplates() {
+    try {
+        const response = await fetch('/api/reload', {
+            method: 'POST'
+        });
+
+        if (response.ok) {
+            const result = await response.json();
+            showSuccess(result.message || 'Templates reloaded');
+            loadDiagnostics();
+            await loadCategories();
+            if (currentCategory) {
+                loadPolicies(currentCategory);
+            }
+        } else {
+            const errorData = await response.json().catch(() => ({}));
+            showError(errorData.error || errorData.message || 'Failed to reload templates');
+        }
+    } catch (error) {
+        console.error('Reload templates error:', error);
+        showError('Failed to reload templates: ' + error.message);
+    }
+}
+
 function setApplyButtonLoading(isLoading) {
     const button = document.getElementById('apply-policy-button');
     if (!button) return;
@@ -895,7 +1340,7 @@ async function performSearch(query, section) {
     if (!query) return;
     
     try {
-        const response = await fetch(`/api/search?q=${encodeURIComponent(query)}&section=${encodeURIComponent(section)}`);
+        const response = await fetch(withApplicability(withLanguage(`/api/search?q=${encodeURIComponent(query)}&section=${encodeURIComponent(section)}`)));
         if (!response.ok) {
             throw new Error('Search failed');
         }
@@ -976,6 +1421,7 @@ function displaySearchResults(data, section) {
                 <div style="display: flex; align-items: center; gap: 12px;">
                     <span class="policy-state ${stateClass}">${policy.state}</span>
                     <small style="color: var(--text-light); font-size: 0.8125rem;">${policy.section}</small>
+                    ${renderTattooBadge(policy)}
                 </div>
                 <small style="color: var(--text-secondary); font-size: 0.8125rem;">
                     ${escapeHtml(policy.categoryName || 'No category')}